@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	av1 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	conditionReady              = "Ready"
+	conditionCredentialsIssued  = "CredentialsIssued"
+	conditionSecretSynced       = "SecretSynced"
+	conditionDegraded           = "Degraded"
+	conditionSessionTagsIgnored = "SessionTagsIgnored"
+
+	reasonCredentialsAvailable   = "CredentialsAvailable"
+	reasonCredentialsUnavailable = "CredentialsUnavailable"
+	reasonCredentialsExpired     = "CredentialsExpired"
+	reasonCredentialsOutdated    = "CredentialsOutdated"
+	reasonIssued                 = "Issued"
+	reasonSynced                 = "Synced"
+	reasonCreateSecretFailed     = "CreateSecretFailed"
+	reasonUpdateSecretFailed     = "UpdateSecretFailed"
+	reasonRecreateSecretFailed   = "RecreateSecretFailed"
+	reasonInvalidSecretTemplate  = "InvalidSecretTemplate"
+	reasonAsExpected             = "AsExpected"
+	reasonSpecTagsApplied        = "SpecTagsApplied"
+	reasonSpecTagsIgnored        = "SpecTagsIgnored"
+)
+
+// setCondition sets a condition of the AWSIAMRole observed for its current
+// generation. It returns true if the condition changed.
+func setCondition(awsIAMRole *av1.AWSIAMRole, conditionType string, status metav1.ConditionStatus, reason, message string) bool {
+	return meta.SetStatusCondition(&awsIAMRole.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: awsIAMRole.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// sessionTagsIgnoredCondition returns the SessionTagsIgnored condition
+// listing the keys of the session tags of the spec which are ignored because
+// they aren't allowed or are set by the controller.
+func sessionTagsIgnoredCondition(generation int64, ignored []string) metav1.Condition {
+	if len(ignored) == 0 {
+		return metav1.Condition{
+			Type:               conditionSessionTagsIgnored,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: generation,
+			Reason:             reasonSpecTagsApplied,
+			Message:            "All session tags of the spec are applied",
+		}
+	}
+
+	return metav1.Condition{
+		Type:               conditionSessionTagsIgnored,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             reasonSpecTagsIgnored,
+		Message:            fmt.Sprintf("Ignored session tags of the spec which are not allowed or set by the controller: %s", strings.Join(ignored, ", ")),
+	}
+}
+
+// setCredentialsIssuedCondition reports credentials issued for the role in
+// the CredentialsIssued condition of the AWSIAMRole.
+func setCredentialsIssuedCondition(awsIAMRole *av1.AWSIAMRole, roleARN string, expiration time.Time) {
+	setCondition(awsIAMRole, conditionCredentialsIssued, metav1.ConditionTrue, reasonIssued,
+		fmt.Sprintf("Issued credentials for role '%s' expiring at %s", roleARN, expiration.UTC().Format(time.RFC3339)),
+	)
+}
+
+// setSecretSyncedCondition reports credentials stored in the secret of the
+// AWSIAMRole in the SecretSynced condition.
+func setSecretSyncedCondition(awsIAMRole *av1.AWSIAMRole) {
+	setCondition(awsIAMRole, conditionSecretSynced, metav1.ConditionTrue, reasonSynced,
+		fmt.Sprintf("Stored the credentials in secret %s/%s", awsIAMRole.Namespace, secretName(awsIAMRole)),
+	)
+}
+
+// setReadyConditions derives the Ready and Degraded conditions of the
+// AWSIAMRole. It's Ready while the secret holds unexpired credentials issued
+// for the current generation and Degraded while the CredentialsIssued or the
+// SecretSynced condition is False. It returns true if a condition changed.
+func setReadyConditions(awsIAMRole *av1.AWSIAMRole, now time.Time) bool {
+	status := &awsIAMRole.Status
+
+	ready := metav1.ConditionFalse
+	var reason, message string
+	switch {
+	case status.Expiration == nil:
+		reason = reasonCredentialsUnavailable
+		message = fmt.Sprintf("No credentials were stored in secret %s/%s yet", awsIAMRole.Namespace, secretName(awsIAMRole))
+	case !now.Before(status.Expiration.Time):
+		reason = reasonCredentialsExpired
+		message = fmt.Sprintf("Credentials in secret %s/%s expired at %s", awsIAMRole.Namespace, secretName(awsIAMRole), status.Expiration.UTC().Format(time.RFC3339))
+	case status.ObservedGeneration == nil || *status.ObservedGeneration != awsIAMRole.Generation:
+		reason = reasonCredentialsOutdated
+		message = fmt.Sprintf("Credentials in secret %s/%s were issued for a previous generation of the AWSIAMRole", awsIAMRole.Namespace, secretName(awsIAMRole))
+	default:
+		ready = metav1.ConditionTrue
+		reason = reasonCredentialsAvailable
+		message = fmt.Sprintf("Credentials for role '%s' are available in secret %s/%s", status.RoleARN, awsIAMRole.Namespace, secretName(awsIAMRole))
+	}
+	changed := setCondition(awsIAMRole, conditionReady, ready, reason, message)
+
+	degraded := metav1.ConditionFalse
+	reason = reasonAsExpected
+	message = "Credentials are issued and synced to the secret"
+	for _, conditionType := range []string{conditionCredentialsIssued, conditionSecretSynced} {
+		condition := meta.FindStatusCondition(status.Conditions, conditionType)
+		if condition != nil && condition.Status == metav1.ConditionFalse {
+			degraded = metav1.ConditionTrue
+			reason = condition.Reason
+			message = condition.Message
+			break
+		}
+	}
+	if setCondition(awsIAMRole, conditionDegraded, degraded, reason, message) {
+		changed = true
+	}
+
+	return changed
+}
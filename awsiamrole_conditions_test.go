@@ -0,0 +1,117 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	av1 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetReadyConditions(tt *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	expiration := metav1.NewTime(now.Add(time.Hour))
+	expired := metav1.NewTime(now.Add(-time.Minute))
+	currentGeneration := int64(2)
+	previousGeneration := int64(1)
+
+	for _, tc := range []struct {
+		msg            string
+		status         av1.AWSIAMRoleStatus
+		ready          metav1.ConditionStatus
+		readyReason    string
+		degraded       metav1.ConditionStatus
+		degradedReason string
+	}{
+		{
+			msg:            "no credentials",
+			ready:          metav1.ConditionFalse,
+			readyReason:    reasonCredentialsUnavailable,
+			degraded:       metav1.ConditionFalse,
+			degradedReason: reasonAsExpected,
+		},
+		{
+			msg: "credentials available",
+			status: av1.AWSIAMRoleStatus{
+				ObservedGeneration: &currentGeneration,
+				Expiration:         &expiration,
+				Conditions: []metav1.Condition{
+					{Type: conditionCredentialsIssued, Status: metav1.ConditionTrue, Reason: reasonIssued},
+					{Type: conditionSecretSynced, Status: metav1.ConditionTrue, Reason: reasonSynced},
+				},
+			},
+			ready:          metav1.ConditionTrue,
+			readyReason:    reasonCredentialsAvailable,
+			degraded:       metav1.ConditionFalse,
+			degradedReason: reasonAsExpected,
+		},
+		{
+			msg: "credentials available but refresh failing",
+			status: av1.AWSIAMRoleStatus{
+				ObservedGeneration: &currentGeneration,
+				Expiration:         &expiration,
+				Conditions: []metav1.Condition{
+					{Type: conditionCredentialsIssued, Status: metav1.ConditionFalse, Reason: failureReasonThrottled},
+					{Type: conditionSecretSynced, Status: metav1.ConditionTrue, Reason: reasonSynced},
+				},
+			},
+			ready:          metav1.ConditionTrue,
+			readyReason:    reasonCredentialsAvailable,
+			degraded:       metav1.ConditionTrue,
+			degradedReason: failureReasonThrottled,
+		},
+		{
+			msg: "credentials expired",
+			status: av1.AWSIAMRoleStatus{
+				ObservedGeneration: &currentGeneration,
+				Expiration:         &expired,
+				Conditions: []metav1.Condition{
+					{Type: conditionCredentialsIssued, Status: metav1.ConditionFalse, Reason: failureReasonAccessDenied},
+				},
+			},
+			ready:          metav1.ConditionFalse,
+			readyReason:    reasonCredentialsExpired,
+			degraded:       metav1.ConditionTrue,
+			degradedReason: failureReasonAccessDenied,
+		},
+		{
+			msg: "credentials of a previous generation",
+			status: av1.AWSIAMRoleStatus{
+				ObservedGeneration: &previousGeneration,
+				Expiration:         &expiration,
+				Conditions: []metav1.Condition{
+					{Type: conditionCredentialsIssued, Status: metav1.ConditionTrue, Reason: reasonIssued},
+					{Type: conditionSecretSynced, Status: metav1.ConditionFalse, Reason: reasonUpdateSecretFailed},
+				},
+			},
+			ready:          metav1.ConditionFalse,
+			readyReason:    reasonCredentialsOutdated,
+			degraded:       metav1.ConditionTrue,
+			degradedReason: reasonUpdateSecretFailed,
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			awsIAMRole := &av1.AWSIAMRole{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", Generation: currentGeneration},
+				Status:     tc.status,
+			}
+			require.True(t, setReadyConditions(awsIAMRole, now))
+
+			ready := meta.FindStatusCondition(awsIAMRole.Status.Conditions, conditionReady)
+			require.NotNil(t, ready)
+			require.Equal(t, tc.ready, ready.Status)
+			require.Equal(t, tc.readyReason, ready.Reason)
+			require.Equal(t, currentGeneration, ready.ObservedGeneration)
+
+			degraded := meta.FindStatusCondition(awsIAMRole.Status.Conditions, conditionDegraded)
+			require.NotNil(t, degraded)
+			require.Equal(t, tc.degraded, degraded.Status)
+			require.Equal(t, tc.degradedReason, degraded.Reason)
+
+			// setting the same conditions again is no change.
+			require.False(t, setReadyConditions(awsIAMRole, now))
+		})
+	}
+}
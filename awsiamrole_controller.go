@@ -3,8 +3,11 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -12,6 +15,7 @@ import (
 	"github.com/zalando-incubator/kube-aws-iam-controller/pkg/clientset"
 	"github.com/zalando-incubator/kube-aws-iam-controller/pkg/recorder"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/tools/record"
@@ -19,6 +23,18 @@ import (
 
 const (
 	awsIAMRoleGenerationKey = "awsiamrole-generation"
+	stsEndpointKey          = "sts-endpoint"
+
+	// managedLabelsAnnotation and managedAnnotationsAnnotation track the
+	// labels and annotations of a secret set by the controller, so they
+	// can be removed once they are dropped from the AWSIAMRole.
+	managedLabelsAnnotation      = "zalando.org/aws-iam-controller-managed-labels"
+	managedAnnotationsAnnotation = "zalando.org/aws-iam-controller-managed-annotations"
+
+	conditionSessionDurationReduced = "SessionDurationReduced"
+
+	reasonSessionDurationGranted = "SessionDurationGranted"
+	reasonRoleChaining           = "RoleChaining"
 )
 
 var (
@@ -37,10 +53,14 @@ type AWSIAMRoleController struct {
 	refreshLimit time.Duration
 	creds        CredentialsGetter
 	namespace    string
+	session      *SessionConfig
+	breaker      *CircuitBreaker
+	validator    *RoleValidator
+	baseCreds    *BaseCredentialsMonitor
 }
 
 // NewSecretsController initializes a new AWSIAMRoleController.
-func NewAWSIAMRoleController(client clientset.Interface, interval, refreshLimit time.Duration, creds CredentialsGetter, namespace string) *AWSIAMRoleController {
+func NewAWSIAMRoleController(client clientset.Interface, interval, refreshLimit time.Duration, creds CredentialsGetter, namespace string, session *SessionConfig, breaker *CircuitBreaker, validator *RoleValidator, baseCreds *BaseCredentialsMonitor) *AWSIAMRoleController {
 	return &AWSIAMRoleController{
 		client:       client,
 		recorder:     recorder.CreateEventRecorder(client),
@@ -48,17 +68,69 @@ func NewAWSIAMRoleController(client clientset.Interface, interval, refreshLimit
 		refreshLimit: refreshLimit,
 		creds:        creds,
 		namespace:    namespace,
+		session:      session,
+		breaker:      breaker,
+		validator:    validator,
+		baseCreds:    baseCreds,
 	}
 }
 
 // getCreds gets new credentials from the CredentialsGetter and converts them
 // to a secret data map.
-func (c *AWSIAMRoleController) getCreds(ctx context.Context, role string, sessionDuration time.Duration) (*Credentials, map[string][]byte, error) {
-	creds, err := c.creds.Get(ctx, role, sessionDuration)
+func (c *AWSIAMRoleController) getCreds(ctx context.Context, awsIAMRole *av1.AWSIAMRole) (*Credentials, map[string][]byte, error) {
+	roleSessionDuration := getRoleSessionDuration(awsIAMRole)
+
+	opts, err := c.credentialsOptions(ctx, awsIAMRole)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	creds, err := getCredentials(ctx, c.creds, c.breaker, c.baseCreds, awsIAMRole, opts)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	if creds.SessionDuration > 0 && creds.SessionDuration < roleSessionDuration {
+		c.recorder.Event(awsIAMRole,
+			v1.EventTypeWarning,
+			"SessionDurationClamped",
+			fmt.Sprintf("Session duration for role '%s' was limited to %s instead of the requested %s", creds.RoleARN, creds.SessionDuration, roleSessionDuration),
+		)
+	}
+
+	secretData, err := credentialsSecretData(creds, awsIAMRole.Spec.SecretFormats)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return creds, secretData, nil
+}
+
+// getCredentials gets credentials for the role of the AWSIAMRole unless its
+// circuit breaker is open.
+func getCredentials(ctx context.Context, getter CredentialsGetter, breaker *CircuitBreaker, baseCreds *BaseCredentialsMonitor, awsIAMRole *av1.AWSIAMRole, opts CredentialsOptions) (*Credentials, error) {
+	if !breaker.Allow(awsIAMRole) {
+		return nil, errCircuitOpen
+	}
+
+	creds, err := getter.Get(ctx, awsIAMRole.Spec.RoleReference, getRoleSessionDuration(awsIAMRole), opts)
+	if err != nil {
+		// failures caused by the base credentials are not the fault of the
+		// role, so they don't count for the circuit breaker.
+		if baseErr := baseCreds.Err(); baseErr != nil && !errors.Is(baseErr, errBaseCredentialsUnchecked) {
+			return nil, fmt.Errorf("%w (%v): %w", errBaseCredentialsUnavailable, baseErr, err)
+		}
+		breaker.Failure(awsIAMRole, err)
+		return nil, err
+	}
+	breaker.Success(awsIAMRole)
+
+	return creds, nil
+}
+
+// credentialsSecretData converts credentials to the secret data map holding
+// the specified formats.
+func credentialsSecretData(creds *Credentials, formats []string) (map[string][]byte, error) {
 	credsFile := fmt.Sprintf(
 		credentialsFileTemplate,
 		creds.AccessKeyID,
@@ -77,16 +149,254 @@ func (c *AWSIAMRoleController) getCreds(ctx context.Context, role string, sessio
 
 	processCredsData, err := json.Marshal(&processCreds)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	return creds, map[string][]byte{
+	secretData := map[string][]byte{
 		roleARNKey:                []byte(creds.RoleARN),
 		expireKey:                 []byte(creds.Expiration.Format(time.RFC3339)),
 		credentialsFileKey:        []byte(credsFile),
 		credentialsProcessFileKey: []byte(credentialsProcessFileContent),
 		credentialsJSONFileKey:    processCredsData,
-	}, nil
+	}
+
+	if creds.Endpoint != "" {
+		secretData[stsEndpointKey] = []byte(creds.Endpoint)
+	}
+
+	filterSecretFormats(secretData, formats)
+
+	return secretData, nil
+}
+
+// filterSecretFormats removes the credentials formats not listed in formats
+// from the secret data. All formats are kept if none are listed. The
+// credentials.process format reads credentials.json, so it's kept with it.
+func filterSecretFormats(secretData map[string][]byte, formats []string) {
+	if len(formats) == 0 {
+		return
+	}
+
+	keep := make(map[string]bool, len(formats)+1)
+	for _, format := range formats {
+		keep[format] = true
+	}
+	if keep[credentialsProcessFileKey] {
+		keep[credentialsJSONFileKey] = true
+	}
+
+	for _, key := range []string{credentialsFileKey, credentialsProcessFileKey, credentialsJSONFileKey} {
+		if !keep[key] {
+			delete(secretData, key)
+		}
+	}
+}
+
+// credentialsOptions resolves the optional credentials parameters defined in
+// the AWSIAMRole spec.
+func (c *AWSIAMRoleController) credentialsOptions(ctx context.Context, awsIAMRole *av1.AWSIAMRole) (CredentialsOptions, error) {
+	opts := CredentialsOptions{
+		ExternalID: awsIAMRole.Spec.ExternalID,
+		Namespace:  awsIAMRole.Namespace,
+	}
+
+	if ref := awsIAMRole.Spec.ExternalIDSecretRef; ref != nil {
+		secret, err := c.client.CoreV1().Secrets(awsIAMRole.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return opts, fmt.Errorf("failed to get ExternalID from secret %s/%s: %w", awsIAMRole.Namespace, ref.Name, err)
+		}
+
+		externalID, ok := secret.Data[ref.Key]
+		if !ok {
+			return opts, fmt.Errorf("secret %s/%s has no key '%s'", awsIAMRole.Namespace, ref.Name, ref.Key)
+		}
+		opts.ExternalID = string(externalID)
+	}
+
+	if policy := awsIAMRole.Spec.SessionPolicy; policy != nil {
+		opts.Policy = policy.Inline
+		if ref := policy.ConfigMapRef; ref != nil {
+			configMap, err := c.client.CoreV1().ConfigMaps(awsIAMRole.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+			if err != nil {
+				return opts, fmt.Errorf("failed to get session policy from configmap %s/%s: %w", awsIAMRole.Namespace, ref.Name, err)
+			}
+
+			data, ok := configMap.Data[ref.Key]
+			if !ok {
+				return opts, fmt.Errorf("configmap %s/%s has no key '%s'", awsIAMRole.Namespace, ref.Name, ref.Key)
+			}
+			opts.Policy = data
+		}
+	}
+	opts.PolicyARNs = awsIAMRole.Spec.PolicyARNs
+	opts.AssumeRoleChain = awsIAMRole.Spec.AssumeRoleChain
+
+	var namespaceLabels map[string]string
+	if c.session != nil && len(c.session.NamespaceLabelTags) > 0 {
+		namespace, err := c.client.CoreV1().Namespaces().Get(ctx, awsIAMRole.Namespace, metav1.GetOptions{})
+		if err != nil {
+			return opts, fmt.Errorf("failed to get namespace %s: %w", awsIAMRole.Namespace, err)
+		}
+		namespaceLabels = namespace.Labels
+	}
+	tags, ignored := c.session.SessionTags(namespaceLabels, awsIAMRole.Labels, awsIAMRole.Spec.SessionTags)
+	opts.Tags = tags
+	c.setSessionTagsIgnoredCondition(awsIAMRole, ignored)
+
+	sourceIdentity, err := c.session.SourceIdentity(awsIAMRole)
+	if err != nil {
+		return opts, err
+	}
+	opts.SourceIdentity = sourceIdentity
+
+	sessionName, err := c.session.SessionName(awsIAMRole)
+	if err != nil {
+		return opts, err
+	}
+	opts.SessionName = sessionName
+	opts.SessionNameSuffix = awsIAMRole.Spec.SessionName
+
+	return opts, nil
+}
+
+// setSessionTagsIgnoredCondition reports the ignored session tags of the
+// AWSIAMRole spec in the SessionTagsIgnored condition. A warning event is
+// only recorded when the ignored tags change.
+func (c *AWSIAMRoleController) setSessionTagsIgnoredCondition(awsIAMRole *av1.AWSIAMRole, ignored []string) {
+	if len(awsIAMRole.Spec.SessionTags) == 0 {
+		meta.RemoveStatusCondition(&awsIAMRole.Status.Conditions, conditionSessionTagsIgnored)
+		return
+	}
+
+	condition := sessionTagsIgnoredCondition(awsIAMRole.Generation, ignored)
+	if meta.SetStatusCondition(&awsIAMRole.Status.Conditions, condition) && condition.Status == metav1.ConditionTrue {
+		c.recorder.Event(awsIAMRole, v1.EventTypeWarning, condition.Reason, condition.Message)
+	}
+}
+
+// recordGetCredentialsFailed records a warning event on the AWSIAMRole
+// describing why credentials could not be fetched and updates the last
+// failure and the circuit breaker status of the AWSIAMRole. Nothing is
+// recorded while the circuit breaker is open.
+func (c *AWSIAMRoleController) recordGetCredentialsFailed(ctx context.Context, awsIAMRole *av1.AWSIAMRole, err error) {
+	if errors.Is(err, errCircuitOpen) {
+		log.Debugf("Skipping AWSIAMRole %s/%s: %v", awsIAMRole.Namespace, awsIAMRole.Name, err)
+		// the credentials can expire while the circuit breaker is open.
+		if setReadyConditions(awsIAMRole, time.Now()) {
+			c.updateStatus(ctx, awsIAMRole)
+		}
+		return
+	}
+
+	reason := "GetCredentialsFailed"
+	switch {
+	case errors.Is(err, errBaseCredentialsUnavailable):
+		reason = "BaseCredentialsUnavailable"
+	case errors.Is(err, errExternalIDRejected):
+		reason = "ExternalIDRejected"
+	}
+
+	c.recorder.Event(awsIAMRole,
+		v1.EventTypeWarning,
+		reason,
+		fmt.Sprintf("Failed to get credentials for role '%s': %v", awsIAMRole.Spec.RoleReference, err),
+	)
+
+	failure := failureReason(err)
+	// STS doesn't distinguish missing roles from roles not trusting the
+	// controller, but the role validation does.
+	validated := meta.FindStatusCondition(awsIAMRole.Status.Conditions, conditionRoleValidated)
+	if failure == failureReasonAccessDenied && validated != nil && validated.Reason == reasonRoleNotFound && validated.ObservedGeneration == awsIAMRole.Generation {
+		failure = failureReasonRoleNotFound
+	}
+
+	awsIAMRole.Status.LastFailure = &av1.FailureStatus{
+		Reason:  failure,
+		Message: err.Error(),
+		Time:    metav1.Now(),
+	}
+
+	status := c.breaker.Status(awsIAMRole)
+	if status != nil && status.State == circuitBreakerOpen {
+		c.recorder.Event(awsIAMRole,
+			v1.EventTypeWarning,
+			"CircuitBreakerOpen",
+			fmt.Sprintf("Stopped getting credentials for role '%s' after %d consecutive failures, retrying at %s", awsIAMRole.Spec.RoleReference, status.ConsecutiveFailures, status.RetryAfter.String()),
+		)
+	}
+
+	nextRefreshTime := metav1.NewTime(time.Now().Add(c.interval))
+	if status != nil && status.RetryAfter != nil {
+		nextRefreshTime = *status.RetryAfter
+	}
+
+	awsIAMRole.Status.CircuitBreaker = status
+	awsIAMRole.Status.NextRefreshTime = &nextRefreshTime
+	setCondition(awsIAMRole, conditionCredentialsIssued, metav1.ConditionFalse, failure, fmt.Sprintf("Failed to get credentials for role '%s': %v", awsIAMRole.Spec.RoleReference, err))
+	setReadyConditions(awsIAMRole, time.Now())
+	c.updateStatus(ctx, awsIAMRole)
+}
+
+// recordSecretSyncFailed records a warning event on the AWSIAMRole describing
+// why its secret could not be created or updated with the issued credentials
+// and reports it in the SecretSynced condition. The credentials are issued
+// again on the next refresh.
+func (c *AWSIAMRoleController) recordSecretSyncFailed(ctx context.Context, awsIAMRole *av1.AWSIAMRole, creds *Credentials, reason, message string) {
+	c.recorder.Event(awsIAMRole, v1.EventTypeWarning, reason, message)
+
+	nextRefreshTime := metav1.NewTime(time.Now().Add(c.interval))
+	awsIAMRole.Status.NextRefreshTime = &nextRefreshTime
+	awsIAMRole.Status.CircuitBreaker = nil
+	awsIAMRole.Status.LastFailure = nil
+	setCredentialsIssuedCondition(awsIAMRole, creds.RoleARN, creds.Expiration)
+	setCondition(awsIAMRole, conditionSecretSynced, metav1.ConditionFalse, reason, message)
+	setReadyConditions(awsIAMRole, time.Now())
+	c.updateStatus(ctx, awsIAMRole)
+}
+
+// updateStatus updates the status of the AWSIAMRole.
+func (c *AWSIAMRoleController) updateStatus(ctx context.Context, awsIAMRole *av1.AWSIAMRole) {
+	_, err := c.client.ZalandoV1().AWSIAMRoles(awsIAMRole.Namespace).UpdateStatus(ctx, awsIAMRole, metav1.UpdateOptions{})
+	if err != nil {
+		log.Errorf("Failed to update status of AWSIAMRole %s/%s: %v", awsIAMRole.Namespace, awsIAMRole.Name, err)
+	}
+}
+
+// validateRole validates the role of the AWSIAMRole if it wasn't validated
+// for the current generation and stores the result in the RoleValidated
+// condition. Failed validations are also recorded as warning events. The
+// result is informational, credentials are requested either way.
+func (c *AWSIAMRoleController) validateRole(ctx context.Context, awsIAMRole *av1.AWSIAMRole) {
+	if c.validator == nil {
+		return
+	}
+
+	current := meta.FindStatusCondition(awsIAMRole.Status.Conditions, conditionRoleValidated)
+	if current != nil && current.ObservedGeneration == awsIAMRole.Generation {
+		return
+	}
+
+	condition, err := c.validator.Validate(ctx, awsIAMRole)
+	if err != nil {
+		log.Warnf("Failed to validate role of AWSIAMRole %s/%s, retrying: %v", awsIAMRole.Namespace, awsIAMRole.Name, err)
+		return
+	}
+
+	if condition.Status == metav1.ConditionFalse {
+		c.recorder.Event(awsIAMRole, v1.EventTypeWarning, condition.Reason, condition.Message)
+	}
+
+	meta.SetStatusCondition(&awsIAMRole.Status.Conditions, condition)
+	updated, err := c.client.ZalandoV1().AWSIAMRoles(awsIAMRole.Namespace).UpdateStatus(ctx, awsIAMRole, metav1.UpdateOptions{})
+	if err != nil {
+		log.Errorf("Failed to update status of AWSIAMRole %s/%s: %v", awsIAMRole.Namespace, awsIAMRole.Name, err)
+		return
+	}
+
+	// the response has no TypeMeta, which is needed to match the owner
+	// references of the secret.
+	awsIAMRole.ResourceVersion = updated.ResourceVersion
+	awsIAMRole.Status = updated.Status
 }
 
 // Run runs the secret controller loop. This will refresh secrets with AWS IAM
@@ -125,9 +435,10 @@ func (c *AWSIAMRoleController) refresh(ctx context.Context) error {
 		return err
 	}
 
-	secretsMap := make(map[string]v1.Secret, len(secrets.Items))
-	for _, secret := range secrets.Items {
-		secretsMap[secret.Namespace+"/"+secret.Name] = secret
+	c.breaker.Prune(awsIAMRoles.Items)
+
+	for i := range awsIAMRoles.Items {
+		c.validateRole(ctx, &awsIAMRoles.Items[i])
 	}
 
 	awsIAMRolesMap := make(map[string]av1.AWSIAMRole, len(awsIAMRoles.Items))
@@ -135,20 +446,46 @@ func (c *AWSIAMRoleController) refresh(ctx context.Context) error {
 		awsIAMRolesMap[role.Namespace+"/"+role.Name] = role
 	}
 
+	// secrets are mapped to their AWSIAMRole via the owner reference as
+	// their name is defined by the secret template. Secrets of a previous
+	// template are stale and removed once the current secret exists.
+	secretsMap := make(map[string]v1.Secret, len(secrets.Items))
+	staleSecrets := make(map[string][]v1.Secret)
+	recreatedSecrets := make(map[string]struct{})
 	orphanSecrets := make([]v1.Secret, 0, len(secrets.Items))
 	for _, secret := range secrets.Items {
-		awsIAMRole, ok := awsIAMRolesMap[secret.Namespace+"/"+secret.Name]
-		if !ok || !isOwnedReference(awsIAMRole.TypeMeta, awsIAMRole.ObjectMeta, secret.ObjectMeta) {
+		awsIAMRole, ok := secretOwner(secret, awsIAMRolesMap)
+		if !ok {
 			orphanSecrets = append(orphanSecrets, secret)
 			continue
 		}
 
-		role := awsIAMRole.Spec.RoleReference
-		roleSessionDuration := 3600 * time.Second
-		if awsIAMRole.Spec.RoleSessionDuration > 0 {
-			roleSessionDuration = time.Duration(awsIAMRole.Spec.RoleSessionDuration) * time.Second
+		// secrets of AWSIAMRoles with an invalid secret template are left
+		// as is, which is reported below.
+		if !validSecretType(secretType(&awsIAMRole)) {
+			continue
 		}
 
+		key := awsIAMRole.Namespace + "/" + awsIAMRole.Name
+		if secret.Name != secretName(&awsIAMRole) {
+			staleSecrets[key] = append(staleSecrets[key], secret)
+			continue
+		}
+
+		// the type of a secret is immutable, so it's recreated. The API
+		// server defaults the type to Opaque.
+		currentType := secret.Type
+		if currentType == "" {
+			currentType = v1.SecretTypeOpaque
+		}
+		if currentType != secretType(&awsIAMRole) {
+			recreatedSecrets[key] = struct{}{}
+			c.recreateSecret(ctx, &awsIAMRole, secret)
+			continue
+		}
+
+		secretsMap[key] = secret
+
 		// TODO: move to function
 		refreshCreds := false
 
@@ -167,24 +504,22 @@ func (c *AWSIAMRoleController) refresh(ctx context.Context) error {
 
 		if refreshCreds {
 			var creds *Credentials
-			creds, secret.Data, err = c.getCreds(ctx, role, roleSessionDuration)
+			creds, secret.Data, err = c.getCreds(ctx, &awsIAMRole)
 			if err != nil {
-				c.recorder.Event(&awsIAMRole,
-					v1.EventTypeWarning,
-					"GetCredentialsFailed",
-					fmt.Sprintf("Failed to get credentials for role '%s': %v", role, err),
-				)
+				c.recordGetCredentialsFailed(ctx, &awsIAMRole, err)
 				continue
 			}
 
-			// update secret labels
-			secret.Labels = mergeLabels(awsIAMRole.Labels, awsIAMRoleOwnerLabels)
+			// update secret labels and annotations
+			setSecretMetadata(&secret, &awsIAMRole)
 			secret.Data[awsIAMRoleGenerationKey] = []byte(fmt.Sprintf("%d", awsIAMRole.Generation))
 
 			// update secret with refreshed credentials
 			_, err := c.client.CoreV1().Secrets(secret.Namespace).Update(ctx, &secret, metav1.UpdateOptions{})
 			if err != nil {
-				log.Errorf("Failed to update secret %s/%s: %v", secret.Namespace, secret.Name, err)
+				c.recordSecretSyncFailed(ctx, &awsIAMRole, creds, reasonUpdateSecretFailed,
+					fmt.Sprintf("Failed to update secret %s/%s with credentials: %v", secret.Namespace, secret.Name, err),
+				)
 				continue
 			}
 
@@ -203,12 +538,7 @@ func (c *AWSIAMRoleController) refresh(ctx context.Context) error {
 			)
 
 			// update AWSIAMRole status
-			expiryTime := metav1.NewTime(creds.Expiration)
-			awsIAMRole.Status = av1.AWSIAMRoleStatus{
-				ObservedGeneration: &awsIAMRole.Generation,
-				RoleARN:            creds.RoleARN,
-				Expiration:         &expiryTime,
-			}
+			setCredentialsStatus(&awsIAMRole, creds, c.refreshLimit)
 
 			_, err = c.client.ZalandoV1().AWSIAMRoles(awsIAMRole.Namespace).UpdateStatus(ctx, &awsIAMRole, metav1.UpdateOptions{})
 			if err != nil {
@@ -237,13 +567,19 @@ func (c *AWSIAMRoleController) refresh(ctx context.Context) error {
 
 	// create secrets for new AWSIAMRoles without a secret
 	for _, awsIAMRole := range awsIAMRoles.Items {
-		role := awsIAMRole.Spec.RoleReference
-		roleSessionDuration := 3600 * time.Second
-		if awsIAMRole.Spec.RoleSessionDuration > 0 {
-			roleSessionDuration = time.Duration(awsIAMRole.Spec.RoleSessionDuration) * time.Second
+		if !validSecretType(secretType(&awsIAMRole)) {
+			c.recordInvalidSecretTemplate(ctx, &awsIAMRole,
+				fmt.Sprintf("Secret type '%s' of the secret template doesn't accept the credentials stored by the controller, keeping the current secret", secretType(&awsIAMRole)),
+			)
+			continue
+		}
+
+		if _, ok := recreatedSecrets[awsIAMRole.Namespace+"/"+awsIAMRole.Name]; ok {
+			continue
 		}
 
 		if secret, ok := secretsMap[awsIAMRole.Namespace+"/"+awsIAMRole.Name]; ok {
+			c.deleteStaleSecrets(ctx, staleSecrets[awsIAMRole.Namespace+"/"+awsIAMRole.Name])
 			// update secret if out of date
 			generation, err := getGeneration(secret.Data)
 			if err != nil {
@@ -258,28 +594,22 @@ func (c *AWSIAMRoleController) refresh(ctx context.Context) error {
 				continue
 			}
 
+			var creds *Credentials
 			if awsIAMRole.Generation != generation {
-				var creds *Credentials
-				creds, secret.Data, err = c.getCreds(ctx, role, roleSessionDuration)
+				creds, secret.Data, err = c.getCreds(ctx, &awsIAMRole)
 				if err != nil {
-					c.recorder.Event(&awsIAMRole,
-						v1.EventTypeWarning,
-						"GetCredentialsFailed",
-						fmt.Sprintf("Failed to get credentials for role '%s': %v", role, err),
-					)
+					c.recordGetCredentialsFailed(ctx, &awsIAMRole, err)
 					continue
 				}
 
-				// update secret labels
-				secret.Labels = mergeLabels(awsIAMRole.Labels, awsIAMRoleOwnerLabels)
+				// update secret labels and annotations
+				setSecretMetadata(&secret, &awsIAMRole)
 				secret.Data[awsIAMRoleGenerationKey] = []byte(fmt.Sprintf("%d", awsIAMRole.Generation))
 
 				// update secret with refreshed credentials
 				_, err := c.client.CoreV1().Secrets(secret.Namespace).Update(ctx, &secret, metav1.UpdateOptions{})
 				if err != nil {
-					c.recorder.Event(&awsIAMRole,
-						v1.EventTypeWarning,
-						"UpdateSecretFailed",
+					c.recordSecretSyncFailed(ctx, &awsIAMRole, creds, reasonUpdateSecretFailed,
 						fmt.Sprintf("Failed to update secret %s/%s with credentials: %v", secret.Namespace, secret.Name, err),
 					)
 					continue
@@ -301,25 +631,42 @@ func (c *AWSIAMRoleController) refresh(ctx context.Context) error {
 			}
 
 			// update AWSIAMRole status if not up to date
-			if awsIAMRole.Status.ObservedGeneration == nil || *awsIAMRole.Status.ObservedGeneration != awsIAMRole.Generation {
-				expiration, err := time.Parse(time.RFC3339, string(secret.Data[expireKey]))
-				if err != nil {
-					c.recorder.Event(&awsIAMRole,
-						v1.EventTypeWarning,
-						"ReadSecretFailed",
-						fmt.Sprintf("Failed to parse expirary time '%s' from secret %s/%s: %v",
-							string(secret.Data[expireKey]),
-							secret.Namespace,
-							secret.Name,
-							err),
-					)
-					continue
-				}
-				expiryTime := metav1.NewTime(expiration)
-				awsIAMRole.Status = av1.AWSIAMRoleStatus{
-					ObservedGeneration: &awsIAMRole.Generation,
-					RoleARN:            string(secret.Data[roleARNKey]),
-					Expiration:         &expiryTime,
+			if awsIAMRole.Status.ObservedGeneration == nil || *awsIAMRole.Status.ObservedGeneration != awsIAMRole.Generation || meta.FindStatusCondition(awsIAMRole.Status.Conditions, conditionReady) == nil {
+				if creds != nil {
+					setCredentialsStatus(&awsIAMRole, creds, c.refreshLimit)
+				} else {
+					expiration, err := time.Parse(time.RFC3339, string(secret.Data[expireKey]))
+					if err != nil {
+						c.recorder.Event(&awsIAMRole,
+							v1.EventTypeWarning,
+							"ReadSecretFailed",
+							fmt.Sprintf("Failed to parse expirary time '%s' from secret %s/%s: %v",
+								string(secret.Data[expireKey]),
+								secret.Namespace,
+								secret.Name,
+								err),
+						)
+						continue
+					}
+					expiryTime := metav1.NewTime(expiration)
+					nextRefreshTime := metav1.NewTime(expiration.Add(-c.refreshLimit))
+					awsIAMRole.Status = av1.AWSIAMRoleStatus{
+						ObservedGeneration:  &awsIAMRole.Generation,
+						RoleARN:             string(secret.Data[roleARNKey]),
+						Expiration:          &expiryTime,
+						RoleSessionDuration: awsIAMRole.Status.RoleSessionDuration,
+						SessionTags:         awsIAMRole.Status.SessionTags,
+						STSEndpoint:         string(secret.Data[stsEndpointKey]),
+						LastRefreshTime:     awsIAMRole.Status.LastRefreshTime,
+						NextRefreshTime:     &nextRefreshTime,
+						Conditions:          awsIAMRole.Status.Conditions,
+					}
+
+					// the secret holds credentials issued for the current
+					// generation.
+					setCredentialsIssuedCondition(&awsIAMRole, awsIAMRole.Status.RoleARN, expiration)
+					setSecretSyncedCondition(&awsIAMRole)
+					setReadyConditions(&awsIAMRole, time.Now())
 				}
 
 				// update AWSIAMRole status
@@ -332,49 +679,29 @@ func (c *AWSIAMRoleController) refresh(ctx context.Context) error {
 			continue
 		}
 
-		creds, secretData, err := c.getCreds(ctx, role, roleSessionDuration)
+		creds, secretData, err := c.getCreds(ctx, &awsIAMRole)
 		if err != nil {
-			c.recorder.Event(&awsIAMRole,
-				v1.EventTypeWarning,
-				"GetCredentialsFailed",
-				fmt.Sprintf("Failed to get credentials for role '%s': %v", role, err),
-			)
+			c.recordGetCredentialsFailed(ctx, &awsIAMRole, err)
 			continue
 		}
 
 		secretData[awsIAMRoleGenerationKey] = []byte(fmt.Sprintf("%d", awsIAMRole.Generation))
 
 		// create secret
-		secret := &v1.Secret{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      awsIAMRole.Name,
-				Namespace: awsIAMRole.Namespace,
-				Labels:    mergeLabels(awsIAMRole.Labels, awsIAMRoleOwnerLabels),
-				OwnerReferences: []metav1.OwnerReference{
-					{
-						APIVersion: awsIAMRole.APIVersion,
-						Kind:       awsIAMRole.Kind,
-						Name:       awsIAMRole.Name,
-						UID:        awsIAMRole.UID,
-					},
-				},
-			},
-			Data: secretData,
-		}
-
+		secret := newSecret(&awsIAMRole, secretData)
 		_, err = c.client.CoreV1().Secrets(awsIAMRole.Namespace).Create(ctx, secret, metav1.CreateOptions{})
 		if err != nil {
-			c.recorder.Event(&awsIAMRole,
-				v1.EventTypeWarning,
-				"CreateSecretFailed",
-				fmt.Sprintf("Failed to create secret %s/%s with credentials: %v", awsIAMRole.Namespace, awsIAMRole.Name, err),
+			c.recordSecretSyncFailed(ctx, &awsIAMRole, creds, reasonCreateSecretFailed,
+				fmt.Sprintf("Failed to create secret %s/%s with credentials: %v", secret.Namespace, secret.Name, err),
 			)
 			continue
 		}
+		c.deleteStaleSecrets(ctx, staleSecrets[awsIAMRole.Namespace+"/"+awsIAMRole.Name])
+
 		log.WithFields(log.Fields{
 			"action":    "create",
 			"role-arn":  creds.RoleARN,
-			"secret":    awsIAMRole.Name,
+			"secret":    secret.Name,
 			"namespace": awsIAMRole.Namespace,
 			"expire":    creds.Expiration.String(),
 			"type":      "awsiamrole",
@@ -386,12 +713,7 @@ func (c *AWSIAMRoleController) refresh(ctx context.Context) error {
 		)
 
 		// update AWSIAMRole status
-		expiryTime := metav1.NewTime(creds.Expiration)
-		awsIAMRole.Status = av1.AWSIAMRoleStatus{
-			ObservedGeneration: &awsIAMRole.Generation,
-			RoleARN:            creds.RoleARN,
-			Expiration:         &expiryTime,
-		}
+		setCredentialsStatus(&awsIAMRole, creds, c.refreshLimit)
 
 		_, err = c.client.ZalandoV1().AWSIAMRoles(awsIAMRole.Namespace).UpdateStatus(ctx, &awsIAMRole, metav1.UpdateOptions{})
 		if err != nil {
@@ -403,6 +725,309 @@ func (c *AWSIAMRoleController) refresh(ctx context.Context) error {
 	return nil
 }
 
+// setCredentialsStatus sets the AWSIAMRole status describing the credentials
+// issued for the AWSIAMRole after they were stored in its secret. The
+// credentials are refreshed refreshLimit before they expire.
+func setCredentialsStatus(awsIAMRole *av1.AWSIAMRole, creds *Credentials, refreshLimit time.Duration) {
+	now := time.Now()
+	expiryTime := metav1.NewTime(creds.Expiration)
+	refreshTime := metav1.NewTime(now)
+	nextRefreshTime := metav1.NewTime(creds.Expiration.Add(-refreshLimit))
+	status := av1.AWSIAMRoleStatus{
+		ObservedGeneration:  &awsIAMRole.Generation,
+		RoleARN:             creds.RoleARN,
+		Expiration:          &expiryTime,
+		RoleSessionDuration: int64(creds.SessionDuration.Seconds()),
+		STSEndpoint:         creds.Endpoint,
+		LastRefreshTime:     &refreshTime,
+		NextRefreshTime:     &nextRefreshTime,
+		Conditions:          awsIAMRole.Status.Conditions,
+	}
+
+	for _, tag := range creds.SessionTags {
+		status.SessionTags = append(status.SessionTags, av1.SessionTag{
+			Key:        tag.Key,
+			Value:      tag.Value,
+			Transitive: tag.Transitive,
+		})
+	}
+
+	if creds.SessionDuration > 0 {
+		meta.SetStatusCondition(&status.Conditions, sessionDurationCondition(awsIAMRole, creds.SessionDuration))
+	}
+
+	awsIAMRole.Status = status
+	setCredentialsIssuedCondition(awsIAMRole, creds.RoleARN, creds.Expiration)
+	setSecretSyncedCondition(awsIAMRole)
+	setReadyConditions(awsIAMRole, now)
+}
+
+// sessionDurationCondition returns the SessionDurationReduced condition
+// describing whether the granted session duration is lower than requested.
+func sessionDurationCondition(awsIAMRole *av1.AWSIAMRole, granted time.Duration) metav1.Condition {
+	requested := getRoleSessionDuration(awsIAMRole)
+	condition := metav1.Condition{
+		Type:               conditionSessionDurationReduced,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: awsIAMRole.Generation,
+		Reason:             reasonSessionDurationGranted,
+		Message:            fmt.Sprintf("The requested session duration %s was granted", requested),
+	}
+
+	if granted >= requested {
+		return condition
+	}
+
+	condition.Status = metav1.ConditionTrue
+	if len(awsIAMRole.Spec.AssumeRoleChain) > 0 && granted == chainedSessionMaxDuration {
+		condition.Reason = reasonRoleChaining
+		condition.Message = fmt.Sprintf("The session duration was reduced from %s to %s because sessions of chained roles are limited to %s", requested, granted, chainedSessionMaxDuration)
+	} else {
+		condition.Reason = reasonMaxSessionDurationExceeded
+		condition.Message = fmt.Sprintf("The session duration was reduced from %s to %s because the requested duration exceeds the MaxSessionDuration of the role", requested, granted)
+	}
+	return condition
+}
+
+// getRoleSessionDuration returns the session duration requested by the
+// AWSIAMRole. Defaults to one hour.
+func getRoleSessionDuration(awsIAMRole *av1.AWSIAMRole) time.Duration {
+	if awsIAMRole.Spec.RoleSessionDuration > 0 {
+		return time.Duration(awsIAMRole.Spec.RoleSessionDuration) * time.Second
+	}
+	return 3600 * time.Second
+}
+
+// recreateSecret replaces the secret of the AWSIAMRole with a secret of the
+// type defined in its secret template, as the type of a secret is immutable.
+// New credentials are requested before the secret is deleted and if the new
+// secret can't be created, the secret is restored with its previous type.
+func (c *AWSIAMRoleController) recreateSecret(ctx context.Context, awsIAMRole *av1.AWSIAMRole, secret v1.Secret) {
+	creds, secretData, err := c.getCreds(ctx, awsIAMRole)
+	if err != nil {
+		c.recordGetCredentialsFailed(ctx, awsIAMRole, err)
+		return
+	}
+	secretData[awsIAMRoleGenerationKey] = []byte(fmt.Sprintf("%d", awsIAMRole.Generation))
+
+	err = c.client.CoreV1().Secrets(secret.Namespace).Delete(ctx, secret.Name, metav1.DeleteOptions{
+		Preconditions: metav1.NewUIDPreconditions(string(secret.UID)),
+	})
+	if err != nil {
+		c.recordSecretSyncFailed(ctx, awsIAMRole, creds, reasonRecreateSecretFailed,
+			fmt.Sprintf("Failed to delete secret %s/%s to change its type to %s: %v", secret.Namespace, secret.Name, secretType(awsIAMRole), err),
+		)
+		return
+	}
+
+	_, err = c.client.CoreV1().Secrets(secret.Namespace).Create(ctx, newSecret(awsIAMRole, secretData), metav1.CreateOptions{})
+	if err != nil {
+		// restore the secret with the new credentials so they keep being
+		// available to the workload.
+		restored := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            secret.Name,
+				Namespace:       secret.Namespace,
+				Labels:          secret.Labels,
+				Annotations:     secret.Annotations,
+				OwnerReferences: secret.OwnerReferences,
+			},
+			Type: secret.Type,
+			Data: secretData,
+		}
+		_, restoreErr := c.client.CoreV1().Secrets(secret.Namespace).Create(ctx, restored, metav1.CreateOptions{})
+		if restoreErr != nil {
+			log.Errorf("Failed to restore secret %s/%s: %v", secret.Namespace, secret.Name, restoreErr)
+		}
+
+		c.recordSecretSyncFailed(ctx, awsIAMRole, creds, reasonRecreateSecretFailed,
+			fmt.Sprintf("Failed to recreate secret %s/%s with type %s: %v", secret.Namespace, secret.Name, secretType(awsIAMRole), err),
+		)
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"action":    "recreate",
+		"role-arn":  creds.RoleARN,
+		"secret":    secret.Name,
+		"namespace": secret.Namespace,
+		"expire":    creds.Expiration.String(),
+		"type":      "awsiamrole",
+	}).Info()
+	c.recorder.Event(awsIAMRole,
+		v1.EventTypeNormal,
+		"CreateCredentials",
+		fmt.Sprintf("Recreated secret with type %s and credentials for role '%s', expiry time: %s", secretType(awsIAMRole), creds.RoleARN, creds.Expiration.String()),
+	)
+
+	setCredentialsStatus(awsIAMRole, creds, c.refreshLimit)
+	c.updateStatus(ctx, awsIAMRole)
+}
+
+// recordInvalidSecretTemplate reports a secret template which can't be
+// applied in the SecretSynced condition of the AWSIAMRole. The status is
+// only updated and an event recorded if the conditions changed.
+func (c *AWSIAMRoleController) recordInvalidSecretTemplate(ctx context.Context, awsIAMRole *av1.AWSIAMRole, message string) {
+	changed := setCondition(awsIAMRole, conditionSecretSynced, metav1.ConditionFalse, reasonInvalidSecretTemplate, message)
+	if setReadyConditions(awsIAMRole, time.Now()) {
+		changed = true
+	}
+	if !changed {
+		return
+	}
+
+	c.recorder.Event(awsIAMRole, v1.EventTypeWarning, reasonInvalidSecretTemplate, message)
+	c.updateStatus(ctx, awsIAMRole)
+}
+
+// newSecret returns the secret of the AWSIAMRole holding the secret data.
+func newSecret(awsIAMRole *av1.AWSIAMRole, secretData map[string][]byte) *v1.Secret {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName(awsIAMRole),
+			Namespace: awsIAMRole.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: awsIAMRole.APIVersion,
+					Kind:       awsIAMRole.Kind,
+					Name:       awsIAMRole.Name,
+					UID:        awsIAMRole.UID,
+				},
+			},
+		},
+		Type: secretType(awsIAMRole),
+		Data: secretData,
+	}
+	setSecretMetadata(secret, awsIAMRole)
+	return secret
+}
+
+// deleteStaleSecrets deletes secrets of an AWSIAMRole which were created
+// for a previous secret template.
+func (c *AWSIAMRoleController) deleteStaleSecrets(ctx context.Context, secrets []v1.Secret) {
+	for _, secret := range secrets {
+		err := c.client.CoreV1().Secrets(secret.Namespace).Delete(ctx, secret.Name, metav1.DeleteOptions{})
+		if err != nil {
+			log.Errorf("Failed to delete secret %s/%s: %v", secret.Namespace, secret.Name, err)
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"action":    "delete",
+			"role-arn":  string(secret.Data[roleARNKey]),
+			"secret":    secret.Name,
+			"namespace": secret.Namespace,
+		}).Info("Removing credentials of previous secret template")
+	}
+}
+
+// secretOwner returns the AWSIAMRole owning the secret.
+func secretOwner(secret v1.Secret, awsIAMRoles map[string]av1.AWSIAMRole) (av1.AWSIAMRole, bool) {
+	for _, ref := range secret.OwnerReferences {
+		awsIAMRole, ok := awsIAMRoles[secret.Namespace+"/"+ref.Name]
+		if ok && isOwnedReference(awsIAMRole.TypeMeta, awsIAMRole.ObjectMeta, secret.ObjectMeta) {
+			return awsIAMRole, true
+		}
+	}
+	return av1.AWSIAMRole{}, false
+}
+
+// secretName returns the name of the secret of the AWSIAMRole. Defaults to
+// the name of the AWSIAMRole.
+func secretName(awsIAMRole *av1.AWSIAMRole) string {
+	if template := awsIAMRole.Spec.SecretTemplate; template != nil && template.Name != "" {
+		return template.Name
+	}
+	return awsIAMRole.Name
+}
+
+// secretLabels returns the labels of the secret of the AWSIAMRole. The
+// labels identifying secrets managed by the controller take precedence.
+func secretLabels(awsIAMRole *av1.AWSIAMRole) map[string]string {
+	labels := awsIAMRole.Labels
+	if template := awsIAMRole.Spec.SecretTemplate; template != nil {
+		labels = mergeLabels(labels, template.Labels)
+	}
+	return mergeLabels(labels, awsIAMRoleOwnerLabels)
+}
+
+// secretTemplateAnnotations returns the annotations defined in the secret
+// template of the AWSIAMRole.
+func secretTemplateAnnotations(awsIAMRole *av1.AWSIAMRole) map[string]string {
+	if template := awsIAMRole.Spec.SecretTemplate; template != nil {
+		return template.Annotations
+	}
+	return nil
+}
+
+// setSecretMetadata sets the labels and annotations of the AWSIAMRole on the
+// secret. Labels and annotations set by the controller before which are no
+// longer defined are removed, while others, e.g. added by other tools, are
+// kept. Labels of secrets without tracked labels are replaced.
+func setSecretMetadata(secret *v1.Secret, awsIAMRole *av1.AWSIAMRole) {
+	annotations := secret.Annotations
+	previousLabels, ok := annotations[managedLabelsAnnotation]
+	if !ok {
+		previousLabels = strings.Join(sortedKeys(secret.Labels), ",")
+	}
+
+	labels := secretLabels(awsIAMRole)
+	templateAnnotations := secretTemplateAnnotations(awsIAMRole)
+	secret.Labels = syncManagedMetadata(secret.Labels, labels, previousLabels)
+	secret.Annotations = syncManagedMetadata(annotations, templateAnnotations, annotations[managedAnnotationsAnnotation])
+	secret.Annotations[managedLabelsAnnotation] = strings.Join(sortedKeys(labels), ",")
+	delete(secret.Annotations, managedAnnotationsAnnotation)
+	if len(templateAnnotations) > 0 {
+		secret.Annotations[managedAnnotationsAnnotation] = strings.Join(sortedKeys(templateAnnotations), ",")
+	}
+}
+
+// syncManagedMetadata returns a copy of the current labels or annotations
+// with the desired ones set and the previously managed ones, a comma
+// separated list of keys, removed unless they are still desired.
+func syncManagedMetadata(current, desired map[string]string, previous string) map[string]string {
+	result := make(map[string]string, len(current)+len(desired))
+	for k, v := range current {
+		result[k] = v
+	}
+
+	for _, k := range strings.Split(previous, ",") {
+		delete(result, k)
+	}
+
+	for k, v := range desired {
+		result[k] = v
+	}
+	return result
+}
+
+// sortedKeys returns the sorted keys of the map.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// secretType returns the type of the secret of the AWSIAMRole. Defaults to
+// Opaque.
+func secretType(awsIAMRole *av1.AWSIAMRole) v1.SecretType {
+	if template := awsIAMRole.Spec.SecretTemplate; template != nil && template.Type != "" {
+		return template.Type
+	}
+	return v1.SecretTypeOpaque
+}
+
+// validSecretType returns true if secrets of the type accept the credentials
+// stored by the controller. Except for Opaque, the types defined by
+// Kubernetes require specific keys, e.g. tls.crt and tls.key for
+// kubernetes.io/tls.
+func validSecretType(secretType v1.SecretType) bool {
+	return secretType == v1.SecretTypeOpaque || !strings.Contains(string(secretType), "kubernetes.io/")
+}
+
 // isOwnedReference returns true of the dependent object is owned by the owner
 // object.
 func isOwnedReference(ownerTypeMeta metav1.TypeMeta, ownerObjectMeta, dependent metav1.ObjectMeta) bool {
@@ -2,17 +2,25 @@ package main
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/smithy-go"
 	"github.com/stretchr/testify/require"
 	av1 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v1"
 	fakeAWS "github.com/zalando-incubator/kube-aws-iam-controller/pkg/client/clientset/versioned/fake"
 	"github.com/zalando-incubator/kube-aws-iam-controller/pkg/clientset"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	fakeKube "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 )
 
 func TestIsOwnedReference(t *testing.T) {
@@ -162,7 +170,7 @@ func TestRefreshAWSIAMRole(tt *testing.T) {
 				require.NoError(t, err)
 			}
 
-			controller := NewAWSIAMRoleController(client, 0, 15*time.Minute, tc.credsGetter, "default")
+			controller := NewAWSIAMRoleController(client, 0, 15*time.Minute, tc.credsGetter, "default", nil, nil, nil, nil)
 			err := controller.refresh(context.TODO())
 			require.NoError(t, err)
 
@@ -172,3 +180,649 @@ func TestRefreshAWSIAMRole(tt *testing.T) {
 		})
 	}
 }
+
+func TestCredentialsOptions(t *testing.T) {
+	kubeClient := fakeKube.NewSimpleClientset(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "external-id",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"id": []byte("from-secret"),
+		},
+	}, &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "session-policy",
+			Namespace: "default",
+		},
+		Data: map[string]string{
+			"policy.json": `{"Version":"2012-10-17"}`,
+		},
+	})
+	client := clientset.NewClientset(kubeClient, fakeAWS.NewSimpleClientset())
+	controller := NewAWSIAMRoleController(client, 0, 15*time.Minute, &mockCredsGetter{}, "default", nil, nil, nil, nil)
+
+	awsIAMRole := &av1.AWSIAMRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "role",
+			Namespace: "default",
+		},
+		Spec: av1.AWSIAMRoleSpec{
+			RoleReference: "role",
+			ExternalID:    "from-spec",
+		},
+	}
+
+	opts, err := controller.credentialsOptions(context.TODO(), awsIAMRole)
+	require.NoError(t, err)
+	require.Equal(t, "from-spec", opts.ExternalID)
+
+	awsIAMRole.Spec.ExternalIDSecretRef = &v1.SecretKeySelector{
+		LocalObjectReference: v1.LocalObjectReference{Name: "external-id"},
+		Key:                  "id",
+	}
+	opts, err = controller.credentialsOptions(context.TODO(), awsIAMRole)
+	require.NoError(t, err)
+	require.Equal(t, "from-secret", opts.ExternalID)
+
+	awsIAMRole.Spec.ExternalIDSecretRef.Key = "missing"
+	_, err = controller.credentialsOptions(context.TODO(), awsIAMRole)
+	require.Error(t, err)
+	awsIAMRole.Spec.ExternalIDSecretRef = nil
+
+	awsIAMRole.Spec.PolicyARNs = []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}
+	awsIAMRole.Spec.SessionPolicy = &av1.SessionPolicy{
+		Inline: `{"Version":"inline"}`,
+	}
+	opts, err = controller.credentialsOptions(context.TODO(), awsIAMRole)
+	require.NoError(t, err)
+	require.Equal(t, `{"Version":"inline"}`, opts.Policy)
+	require.Equal(t, awsIAMRole.Spec.PolicyARNs, opts.PolicyARNs)
+
+	awsIAMRole.Spec.SessionPolicy.ConfigMapRef = &v1.ConfigMapKeySelector{
+		LocalObjectReference: v1.LocalObjectReference{Name: "session-policy"},
+		Key:                  "policy.json",
+	}
+	opts, err = controller.credentialsOptions(context.TODO(), awsIAMRole)
+	require.NoError(t, err)
+	require.Equal(t, `{"Version":"2012-10-17"}`, opts.Policy)
+
+	awsIAMRole.Spec.SessionPolicy.ConfigMapRef.Key = "missing"
+	_, err = controller.credentialsOptions(context.TODO(), awsIAMRole)
+	require.Error(t, err)
+}
+
+func TestRefreshAWSIAMRoleCircuitBreaker(t *testing.T) {
+	client := clientset.NewClientset(fakeKube.NewSimpleClientset(), fakeAWS.NewSimpleClientset())
+	_, err := client.ZalandoV1().AWSIAMRoles("default").Create(context.TODO(), &av1.AWSIAMRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "failing",
+			Namespace: "default",
+			UID:       types.UID("1234"),
+		},
+		Spec: av1.AWSIAMRoleSpec{
+			RoleReference: "missing",
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	credsGetter := &countingCredsGetter{err: &smithy.GenericAPIError{Code: "AccessDenied"}}
+	controller := NewAWSIAMRoleController(client, 0, 15*time.Minute, credsGetter, "default", nil, NewCircuitBreaker(2, time.Hour, time.Hour), nil, nil)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, controller.refresh(context.TODO()))
+	}
+	require.EqualValues(t, 2, credsGetter.calls)
+
+	awsIAMRole, err := client.ZalandoV1().AWSIAMRoles("default").Get(context.TODO(), "failing", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.NotNil(t, awsIAMRole.Status.CircuitBreaker)
+	require.Equal(t, circuitBreakerOpen, awsIAMRole.Status.CircuitBreaker.State)
+	require.EqualValues(t, 2, awsIAMRole.Status.CircuitBreaker.ConsecutiveFailures)
+}
+
+func TestRefreshAWSIAMRoleValidation(t *testing.T) {
+	client := clientset.NewClientset(fakeKube.NewSimpleClientset(), fakeAWS.NewSimpleClientset())
+	_, err := client.ZalandoV1().AWSIAMRoles("default").Create(context.TODO(), &av1.AWSIAMRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "app",
+			Namespace:  "default",
+			UID:        types.UID("1234"),
+			Generation: 1,
+		},
+		Spec: av1.AWSIAMRoleSpec{
+			RoleReference:       "app",
+			RoleSessionDuration: 7200,
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	iamAPI := &mockIAMAPI{roles: map[string]*iamtypes.Role{"app": testIAMRole(testTrustPolicy, 3600)}}
+	validator, err := newRoleValidator(iamAPI, "arn:aws:iam::123456789012:role/", []string{testControllerIdentity})
+	require.NoError(t, err)
+
+	controller := NewAWSIAMRoleController(client, 0, 15*time.Minute, &mockCredsGetter{creds: &Credentials{Expiration: time.Now().Add(time.Hour)}}, "default", nil, nil, validator, nil)
+	require.NoError(t, controller.refresh(context.TODO()))
+	require.NoError(t, controller.refresh(context.TODO()))
+	require.Equal(t, 1, iamAPI.calls)
+
+	// the condition is kept when the credentials status is updated.
+	awsIAMRole, err := client.ZalandoV1().AWSIAMRoles("default").Get(context.TODO(), "app", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.NotNil(t, awsIAMRole.Status.Expiration)
+	condition := meta.FindStatusCondition(awsIAMRole.Status.Conditions, conditionRoleValidated)
+	require.NotNil(t, condition)
+	require.Equal(t, metav1.ConditionFalse, condition.Status)
+	require.Equal(t, reasonMaxSessionDurationExceeded, condition.Reason)
+
+	// the role is validated again when the AWSIAMRole changes.
+	awsIAMRole.Spec.RoleSessionDuration = 3600
+	awsIAMRole.Generation = 2
+	_, err = client.ZalandoV1().AWSIAMRoles("default").Update(context.TODO(), awsIAMRole, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, controller.refresh(context.TODO()))
+	require.Equal(t, 2, iamAPI.calls)
+
+	awsIAMRole, err = client.ZalandoV1().AWSIAMRoles("default").Get(context.TODO(), "app", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.True(t, meta.IsStatusConditionTrue(awsIAMRole.Status.Conditions, conditionRoleValidated))
+}
+
+func TestRefreshAWSIAMRoleValidationKeepsSecret(t *testing.T) {
+	awsClient := fakeAWS.NewSimpleClientset()
+	client := clientset.NewClientset(fakeKube.NewSimpleClientset(), awsClient)
+	_, err := client.ZalandoV1().AWSIAMRoles("default").Create(context.TODO(), &av1.AWSIAMRole{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "zalando.org/v1",
+			Kind:       "AWSIAMRole",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "app",
+			Namespace:  "default",
+			UID:        types.UID("1234"),
+			Generation: 1,
+		},
+		Spec: av1.AWSIAMRoleSpec{
+			RoleReference: "app",
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	credsGetter := &countingCredsGetter{lifetime: time.Hour}
+	controller := NewAWSIAMRoleController(client, 0, 15*time.Minute, credsGetter, "default", nil, nil, nil, nil)
+	controller.recorder = record.NewFakeRecorder(100)
+	require.NoError(t, controller.refresh(context.TODO()))
+
+	// like the API server, status updates return the object without
+	// TypeMeta.
+	awsClient.PrependReactor("update", "awsiamroles", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		awsIAMRole := action.(k8stesting.UpdateAction).GetObject().(*av1.AWSIAMRole).DeepCopy()
+		err := awsClient.Tracker().Update(action.GetResource(), awsIAMRole, awsIAMRole.Namespace)
+		awsIAMRole.TypeMeta = metav1.TypeMeta{}
+		return true, awsIAMRole, err
+	})
+
+	iamAPI := &mockIAMAPI{roles: map[string]*iamtypes.Role{"app": testIAMRole(testTrustPolicy, 3600)}}
+	controller.validator, err = newRoleValidator(iamAPI, "arn:aws:iam::123456789012:role/", []string{testControllerIdentity})
+	require.NoError(t, err)
+	require.NoError(t, controller.refresh(context.TODO()))
+	require.Equal(t, 1, iamAPI.calls)
+
+	// the secret is kept and not recreated.
+	secret, err := client.CoreV1().Secrets("default").Get(context.TODO(), "app", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "zalando.org/v1", secret.OwnerReferences[0].APIVersion)
+	require.EqualValues(t, 1, credsGetter.calls)
+}
+
+func TestRefreshAWSIAMRoleSessionTagsIgnored(t *testing.T) {
+	client := clientset.NewClientset(fakeKube.NewSimpleClientset(), fakeAWS.NewSimpleClientset())
+	_, err := client.ZalandoV1().AWSIAMRoles("default").Create(context.TODO(), &av1.AWSIAMRole{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "zalando.org/v1",
+			Kind:       "AWSIAMRole",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "app",
+			Namespace:  "default",
+			UID:        types.UID("1234"),
+			Generation: 1,
+		},
+		Spec: av1.AWSIAMRoleSpec{
+			RoleReference: "app",
+			SessionTags: []av1.SessionTag{
+				{Key: "project", Value: "billing"},
+				{Key: "cost-center", Value: "1234"},
+			},
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	// credentials are issued on every refresh as they expire within the
+	// refresh limit.
+	credsGetter := &countingCredsGetter{lifetime: time.Hour}
+	session := &SessionConfig{AllowedSpecTags: []string{"project"}}
+	controller := NewAWSIAMRoleController(client, 0, 2*time.Hour, credsGetter, "default", session, nil, nil, nil)
+	recorder := record.NewFakeRecorder(100)
+	controller.recorder = recorder
+	require.NoError(t, controller.refresh(context.TODO()))
+	require.NoError(t, controller.refresh(context.TODO()))
+	require.EqualValues(t, 2, credsGetter.calls)
+
+	awsIAMRole, err := client.ZalandoV1().AWSIAMRoles("default").Get(context.TODO(), "app", metav1.GetOptions{})
+	require.NoError(t, err)
+	condition := meta.FindStatusCondition(awsIAMRole.Status.Conditions, conditionSessionTagsIgnored)
+	require.NotNil(t, condition)
+	require.Equal(t, metav1.ConditionTrue, condition.Status)
+	require.Equal(t, "Ignored session tags of the spec which are not allowed or set by the controller: cost-center", condition.Message)
+
+	// the ignored tags are only reported once.
+	var events []string
+	for len(recorder.Events) > 0 {
+		event := <-recorder.Events
+		if strings.Contains(event, reasonSpecTagsIgnored) {
+			events = append(events, event)
+		}
+	}
+	require.Len(t, events, 1)
+}
+
+func TestRefreshAWSIAMRoleBaseCredentialsUnavailable(t *testing.T) {
+	client := clientset.NewClientset(fakeKube.NewSimpleClientset(), fakeAWS.NewSimpleClientset())
+	_, err := client.ZalandoV1().AWSIAMRoles("default").Create(context.TODO(), &av1.AWSIAMRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app",
+			Namespace: "default",
+			UID:       types.UID("1234"),
+		},
+		Spec: av1.AWSIAMRoleSpec{
+			RoleReference: "app",
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	baseCreds := newTestBaseCredentialsMonitor(&mockCredentialsProvider{err: errors.New("AccessDenied")}, &mockCallerIdentityAPI{}, time.Now())
+	baseCreds.check(context.TODO())
+
+	credsGetter := &countingCredsGetter{err: &smithy.GenericAPIError{Code: "AccessDenied"}}
+	controller := NewAWSIAMRoleController(client, 0, 15*time.Minute, credsGetter, "default", nil, NewCircuitBreaker(2, time.Hour, time.Hour), nil, baseCreds)
+
+	_, _, err = controller.getCreds(context.TODO(), &av1.AWSIAMRole{Spec: av1.AWSIAMRoleSpec{RoleReference: "app"}})
+	require.ErrorIs(t, err, errBaseCredentialsUnavailable)
+
+	// failures caused by the base credentials don't open the circuit breaker.
+	for i := 0; i < 5; i++ {
+		require.NoError(t, controller.refresh(context.TODO()))
+	}
+	require.EqualValues(t, 6, credsGetter.calls)
+
+	awsIAMRole, err := client.ZalandoV1().AWSIAMRoles("default").Get(context.TODO(), "app", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Nil(t, awsIAMRole.Status.CircuitBreaker)
+}
+
+func TestSessionDurationCondition(tt *testing.T) {
+	for _, tc := range []struct {
+		msg     string
+		spec    av1.AWSIAMRoleSpec
+		granted time.Duration
+		status  metav1.ConditionStatus
+		reason  string
+	}{
+		{
+			msg:     "requested duration granted",
+			spec:    av1.AWSIAMRoleSpec{RoleSessionDuration: 7200},
+			granted: 2 * time.Hour,
+			status:  metav1.ConditionFalse,
+			reason:  reasonSessionDurationGranted,
+		},
+		{
+			msg:     "duration reduced to the MaxSessionDuration",
+			spec:    av1.AWSIAMRoleSpec{RoleSessionDuration: 43200},
+			granted: 2 * time.Hour,
+			status:  metav1.ConditionTrue,
+			reason:  reasonMaxSessionDurationExceeded,
+		},
+		{
+			msg:     "duration reduced by role chaining",
+			spec:    av1.AWSIAMRoleSpec{RoleSessionDuration: 7200, AssumeRoleChain: []string{"hub"}},
+			granted: time.Hour,
+			status:  metav1.ConditionTrue,
+			reason:  reasonRoleChaining,
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			awsIAMRole := &av1.AWSIAMRole{Spec: tc.spec}
+			setCredentialsStatus(awsIAMRole, &Credentials{SessionDuration: tc.granted}, 15*time.Minute)
+			status := awsIAMRole.Status
+			require.Equal(t, int64(tc.granted.Seconds()), status.RoleSessionDuration)
+
+			condition := meta.FindStatusCondition(status.Conditions, conditionSessionDurationReduced)
+			require.NotNil(t, condition)
+			require.Equal(t, tc.status, condition.Status)
+			require.Equal(t, tc.reason, condition.Reason)
+		})
+	}
+}
+
+func TestRefreshAWSIAMRoleLastFailure(t *testing.T) {
+	client := clientset.NewClientset(fakeKube.NewSimpleClientset(), fakeAWS.NewSimpleClientset())
+	_, err := client.ZalandoV1().AWSIAMRoles("default").Create(context.TODO(), &av1.AWSIAMRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "app",
+			Namespace:  "default",
+			UID:        types.UID("1234"),
+			Generation: 1,
+		},
+		Spec: av1.AWSIAMRoleSpec{
+			RoleReference: "app",
+		},
+		Status: av1.AWSIAMRoleStatus{
+			Conditions: []metav1.Condition{
+				{Type: conditionRoleValidated, Status: metav1.ConditionFalse, Reason: reasonRoleNotFound, ObservedGeneration: 1},
+			},
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	credsGetter := &mockCredsGetter{err: &smithy.GenericAPIError{Code: "AccessDenied"}}
+	controller := NewAWSIAMRoleController(client, 0, 15*time.Minute, credsGetter, "default", nil, nil, nil, nil)
+	require.NoError(t, controller.refresh(context.TODO()))
+
+	// AccessDenied is reported as RoleNotFound if the role validation found
+	// that the role doesn't exist.
+	awsIAMRole, err := client.ZalandoV1().AWSIAMRoles("default").Get(context.TODO(), "app", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.NotNil(t, awsIAMRole.Status.LastFailure)
+	require.Equal(t, failureReasonRoleNotFound, awsIAMRole.Status.LastFailure.Reason)
+	require.Contains(t, awsIAMRole.Status.LastFailure.Message, "AccessDenied")
+	require.False(t, awsIAMRole.Status.LastFailure.Time.IsZero())
+
+	// the last failure is cleared when credentials are issued.
+	credsGetter.err = nil
+	credsGetter.creds = &Credentials{Expiration: time.Now().Add(time.Hour)}
+	require.NoError(t, controller.refresh(context.TODO()))
+
+	awsIAMRole, err = client.ZalandoV1().AWSIAMRoles("default").Get(context.TODO(), "app", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Nil(t, awsIAMRole.Status.LastFailure)
+}
+
+func TestRefreshAWSIAMRoleConditions(t *testing.T) {
+	kubeClient := fakeKube.NewSimpleClientset()
+	client := clientset.NewClientset(kubeClient, fakeAWS.NewSimpleClientset())
+	_, err := client.ZalandoV1().AWSIAMRoles("default").Create(context.TODO(), &av1.AWSIAMRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "app",
+			Namespace:  "default",
+			UID:        types.UID("1234"),
+			Generation: 1,
+		},
+		Spec: av1.AWSIAMRoleSpec{
+			RoleReference: "app",
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	getAWSIAMRole := func() *av1.AWSIAMRole {
+		awsIAMRole, err := client.ZalandoV1().AWSIAMRoles("default").Get(context.TODO(), "app", metav1.GetOptions{})
+		require.NoError(t, err)
+		return awsIAMRole
+	}
+
+	requireCondition := func(awsIAMRole *av1.AWSIAMRole, conditionType string, status metav1.ConditionStatus, reason string) {
+		condition := meta.FindStatusCondition(awsIAMRole.Status.Conditions, conditionType)
+		require.NotNil(t, condition, conditionType)
+		require.Equal(t, status, condition.Status, conditionType)
+		require.Equal(t, reason, condition.Reason, conditionType)
+	}
+
+	// failing to create the secret.
+	kubeClient.PrependReactor("create", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("forbidden")
+	})
+
+	expiration := time.Now().Add(time.Hour).Truncate(time.Second)
+	credsGetter := &mockCredsGetter{creds: &Credentials{RoleARN: "arn:aws:iam::123456789012:role/app", Expiration: expiration}}
+	controller := NewAWSIAMRoleController(client, time.Minute, 15*time.Minute, credsGetter, "default", nil, nil, nil, nil)
+	require.NoError(t, controller.refresh(context.TODO()))
+
+	awsIAMRole := getAWSIAMRole()
+	requireCondition(awsIAMRole, conditionReady, metav1.ConditionFalse, reasonCredentialsUnavailable)
+	requireCondition(awsIAMRole, conditionCredentialsIssued, metav1.ConditionTrue, reasonIssued)
+	requireCondition(awsIAMRole, conditionSecretSynced, metav1.ConditionFalse, reasonCreateSecretFailed)
+	requireCondition(awsIAMRole, conditionDegraded, metav1.ConditionTrue, reasonCreateSecretFailed)
+	require.NotNil(t, awsIAMRole.Status.NextRefreshTime)
+	require.Nil(t, awsIAMRole.Status.LastRefreshTime)
+
+	// the secret is created.
+	kubeClient.ReactionChain = kubeClient.ReactionChain[1:]
+	require.NoError(t, controller.refresh(context.TODO()))
+
+	awsIAMRole = getAWSIAMRole()
+	requireCondition(awsIAMRole, conditionReady, metav1.ConditionTrue, reasonCredentialsAvailable)
+	requireCondition(awsIAMRole, conditionCredentialsIssued, metav1.ConditionTrue, reasonIssued)
+	requireCondition(awsIAMRole, conditionSecretSynced, metav1.ConditionTrue, reasonSynced)
+	requireCondition(awsIAMRole, conditionDegraded, metav1.ConditionFalse, reasonAsExpected)
+	require.NotNil(t, awsIAMRole.Status.LastRefreshTime)
+	require.Equal(t, expiration.Add(-15*time.Minute), awsIAMRole.Status.NextRefreshTime.Time)
+
+	// credentials can't be issued for the changed AWSIAMRole, the secret
+	// keeps the credentials of the previous generation.
+	awsIAMRole.Generation = 2
+	_, err = client.ZalandoV1().AWSIAMRoles("default").Update(context.TODO(), awsIAMRole, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	credsGetter.err = &smithy.GenericAPIError{Code: "AccessDenied"}
+	require.NoError(t, controller.refresh(context.TODO()))
+
+	awsIAMRole = getAWSIAMRole()
+	requireCondition(awsIAMRole, conditionReady, metav1.ConditionFalse, reasonCredentialsOutdated)
+	requireCondition(awsIAMRole, conditionCredentialsIssued, metav1.ConditionFalse, failureReasonAccessDenied)
+	requireCondition(awsIAMRole, conditionSecretSynced, metav1.ConditionTrue, reasonSynced)
+	requireCondition(awsIAMRole, conditionDegraded, metav1.ConditionTrue, failureReasonAccessDenied)
+	require.EqualValues(t, 2, meta.FindStatusCondition(awsIAMRole.Status.Conditions, conditionReady).ObservedGeneration)
+}
+
+func TestFilterSecretFormats(tt *testing.T) {
+	for _, tc := range []struct {
+		msg      string
+		formats  []string
+		expected []string
+	}{
+		{
+			msg:      "all formats by default",
+			expected: []string{roleARNKey, expireKey, credentialsFileKey, credentialsProcessFileKey, credentialsJSONFileKey},
+		},
+		{
+			msg:      "only credentials file",
+			formats:  []string{credentialsFileKey},
+			expected: []string{roleARNKey, expireKey, credentialsFileKey},
+		},
+		{
+			msg:      "credentials process keeps credentials json",
+			formats:  []string{credentialsProcessFileKey},
+			expected: []string{roleARNKey, expireKey, credentialsProcessFileKey, credentialsJSONFileKey},
+		},
+		{
+			msg:      "only credentials json",
+			formats:  []string{credentialsJSONFileKey},
+			expected: []string{roleARNKey, expireKey, credentialsJSONFileKey},
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			secretData := map[string][]byte{
+				roleARNKey:                []byte("arn"),
+				expireKey:                 []byte("expiration"),
+				credentialsFileKey:        []byte("credentials"),
+				credentialsProcessFileKey: []byte("process"),
+				credentialsJSONFileKey:    []byte("json"),
+			}
+
+			filterSecretFormats(secretData, tc.formats)
+
+			keys := make([]string, 0, len(secretData))
+			for key := range secretData {
+				keys = append(keys, key)
+			}
+			require.ElementsMatch(t, tc.expected, keys)
+		})
+	}
+}
+
+func TestRefreshAWSIAMRoleSecretTemplate(t *testing.T) {
+	kubeClient := fakeKube.NewSimpleClientset()
+	client := clientset.NewClientset(kubeClient, fakeAWS.NewSimpleClientset())
+	_, err := client.ZalandoV1().AWSIAMRoles("default").Create(context.TODO(), &av1.AWSIAMRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "app",
+			Namespace:  "default",
+			UID:        types.UID("1234"),
+			Generation: 1,
+			Labels:     map[string]string{"application": "app"},
+		},
+		Spec: av1.AWSIAMRoleSpec{
+			RoleReference: "app",
+			SecretTemplate: &av1.SecretTemplate{
+				Name:        "app-aws-credentials",
+				Labels:      map[string]string{"team": "foo"},
+				Annotations: map[string]string{"reloader.stakater.com/match": "true"},
+			},
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	credsGetter := &mockCredsGetter{creds: &Credentials{RoleARN: "arn:aws:iam::123456789012:role/app", Expiration: time.Now().Add(time.Hour)}}
+	controller := NewAWSIAMRoleController(client, time.Minute, 15*time.Minute, credsGetter, "default", nil, nil, nil, nil)
+
+	requireSecret := func(name string) *v1.Secret {
+		secrets, err := client.CoreV1().Secrets("default").List(context.TODO(), metav1.ListOptions{})
+		require.NoError(t, err)
+		require.Len(t, secrets.Items, 1)
+		require.Equal(t, name, secrets.Items[0].Name)
+		return &secrets.Items[0]
+	}
+
+	updateSecretTemplate := func(update func(*av1.SecretTemplate)) {
+		awsIAMRole, err := client.ZalandoV1().AWSIAMRoles("default").Get(context.TODO(), "app", metav1.GetOptions{})
+		require.NoError(t, err)
+		update(awsIAMRole.Spec.SecretTemplate)
+		awsIAMRole.Generation++
+		_, err = client.ZalandoV1().AWSIAMRoles("default").Update(context.TODO(), awsIAMRole, metav1.UpdateOptions{})
+		require.NoError(t, err)
+	}
+
+	requireSecretSynced := func(status metav1.ConditionStatus, reason string) {
+		awsIAMRole, err := client.ZalandoV1().AWSIAMRoles("default").Get(context.TODO(), "app", metav1.GetOptions{})
+		require.NoError(t, err)
+		condition := meta.FindStatusCondition(awsIAMRole.Status.Conditions, conditionSecretSynced)
+		require.NotNil(t, condition)
+		require.Equal(t, status, condition.Status)
+		require.Equal(t, reason, condition.Reason)
+	}
+
+	failCreate := func() {
+		kubeClient.PrependReactor("create", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if action.(k8stesting.CreateAction).GetObject().(*v1.Secret).Type == v1.SecretTypeOpaque {
+				return false, nil, nil
+			}
+			return true, nil, errors.New("forbidden")
+		})
+	}
+
+	require.NoError(t, controller.refresh(context.TODO()))
+	secret := requireSecret("app-aws-credentials")
+	require.Equal(t, "app", secret.Labels["application"])
+	require.Equal(t, "foo", secret.Labels["team"])
+	require.Equal(t, awsIAMControllerLabelValue, secret.Labels[heritageLabelKey])
+	require.Equal(t, "true", secret.Annotations["reloader.stakater.com/match"])
+	require.Equal(t, v1.SecretTypeOpaque, secret.Type)
+	require.Equal(t, "app", secret.OwnerReferences[0].Name)
+
+	// the secret is kept on the next refresh.
+	require.NoError(t, controller.refresh(context.TODO()))
+	requireSecret("app-aws-credentials")
+
+	// labels and annotations dropped from the template are removed while
+	// the ones added by others are kept.
+	secret.Labels["other"] = "kept"
+	secret.Annotations["other"] = "kept"
+	_, err = client.CoreV1().Secrets("default").Update(context.TODO(), secret, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	updateSecretTemplate(func(template *av1.SecretTemplate) {
+		template.Labels = nil
+		template.Annotations = map[string]string{"backup.velero.io/exclude": "true"}
+	})
+	require.NoError(t, controller.refresh(context.TODO()))
+	secret = requireSecret("app-aws-credentials")
+	require.NotContains(t, secret.Labels, "team")
+	require.Equal(t, "kept", secret.Labels["other"])
+	require.NotContains(t, secret.Annotations, "reloader.stakater.com/match")
+	require.Equal(t, "true", secret.Annotations["backup.velero.io/exclude"])
+	require.Equal(t, "kept", secret.Annotations["other"])
+
+	// the secret of the previous name is kept until the new secret is
+	// created.
+	updateSecretTemplate(func(template *av1.SecretTemplate) {
+		template.Name = "app-credentials"
+	})
+	kubeClient.PrependReactor("create", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("forbidden")
+	})
+	require.NoError(t, controller.refresh(context.TODO()))
+	requireSecret("app-aws-credentials")
+
+	kubeClient.ReactionChain = kubeClient.ReactionChain[1:]
+	require.NoError(t, controller.refresh(context.TODO()))
+	requireSecret("app-credentials")
+
+	// types not accepting the credentials are rejected and the secret is
+	// kept as is.
+	updateSecretTemplate(func(template *av1.SecretTemplate) {
+		template.Type = v1.SecretTypeTLS
+	})
+	require.NoError(t, controller.refresh(context.TODO()))
+	secret = requireSecret("app-credentials")
+	require.Equal(t, v1.SecretTypeOpaque, secret.Type)
+	require.Equal(t, "3", string(secret.Data[awsIAMRoleGenerationKey]))
+	requireSecretSynced(metav1.ConditionFalse, reasonInvalidSecretTemplate)
+
+	// the secret is restored if it can't be recreated with the new type.
+	updateSecretTemplate(func(template *av1.SecretTemplate) {
+		template.Type = "zalando.org/aws-credentials"
+	})
+	failCreate()
+	require.NoError(t, controller.refresh(context.TODO()))
+	secret = requireSecret("app-credentials")
+	require.Equal(t, v1.SecretTypeOpaque, secret.Type)
+	require.Equal(t, "5", string(secret.Data[awsIAMRoleGenerationKey]))
+	requireSecretSynced(metav1.ConditionFalse, reasonRecreateSecretFailed)
+
+	// the secret is recreated when the type changes.
+	kubeClient.ReactionChain = kubeClient.ReactionChain[1:]
+	require.NoError(t, controller.refresh(context.TODO()))
+	secret = requireSecret("app-credentials")
+	require.Equal(t, v1.SecretType("zalando.org/aws-credentials"), secret.Type)
+	require.Equal(t, "5", string(secret.Data[awsIAMRoleGenerationKey]))
+	requireSecretSynced(metav1.ConditionTrue, reasonSynced)
+
+	// secrets not managed by the controller are not overwritten.
+	_, err = client.CoreV1().Secrets("default").Create(context.TODO(), &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "unmanaged",
+			Namespace: "default",
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	updateSecretTemplate(func(template *av1.SecretTemplate) {
+		template.Name = "unmanaged"
+	})
+	require.NoError(t, controller.refresh(context.TODO()))
+
+	unmanaged, err := client.CoreV1().Secrets("default").Get(context.TODO(), "unmanaged", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Empty(t, unmanaged.Data)
+	_, err = client.CoreV1().Secrets("default").Get(context.TODO(), "app-credentials", metav1.GetOptions{})
+	require.NoError(t, err)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// errBaseCredentialsUnavailable is returned when getting credentials
+	// failed while the credentials of the controller itself are unusable.
+	errBaseCredentialsUnavailable = errors.New("base credentials of the controller are unavailable")
+	// errBaseCredentialsUnchecked is reported until the base credentials
+	// were checked for the first time.
+	errBaseCredentialsUnchecked = errors.New("base credentials not checked yet")
+)
+
+// BaseCredentialsStatus describes the credentials the controller uses to
+// assume roles.
+type BaseCredentialsStatus struct {
+	// Identity is the ARN of the caller identity of the credentials.
+	Identity string `json:"identity,omitempty"`
+	// Expiration is the expiry time of the credentials if they expire.
+	Expiration *time.Time `json:"expiration,omitempty"`
+	// LastCheck is the time of the last check.
+	LastCheck *time.Time `json:"lastCheck,omitempty"`
+	// LastSuccess is the time of the last successful check.
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	// LastError is the error of the last check if it failed.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// BaseCredentialsMonitor periodically retrieves the credentials the
+// controller uses to assume roles and verifies them with
+// sts:GetCallerIdentity. Failures of all roles can then be attributed to the
+// base credentials instead of the individual roles.
+type BaseCredentialsMonitor struct {
+	credentials aws.CredentialsProvider
+	sts         callerIdentityAPI
+	interval    time.Duration
+	status      BaseCredentialsStatus
+	err         error
+	mu          sync.Mutex
+	now         func() time.Time
+}
+
+// NewBaseCredentialsMonitor initializes a new monitor for the credentials of
+// the AWS config.
+func NewBaseCredentialsMonitor(cfg aws.Config, interval time.Duration, optFns ...func(*sts.Options)) *BaseCredentialsMonitor {
+	return &BaseCredentialsMonitor{
+		credentials: cfg.Credentials,
+		sts:         sts.NewFromConfig(cfg, optFns...),
+		interval:    interval,
+		err:         errBaseCredentialsUnchecked,
+		now:         time.Now,
+	}
+}
+
+// Run checks the base credentials every interval until the context is
+// canceled.
+func (m *BaseCredentialsMonitor) Run(ctx context.Context) {
+	for {
+		m.check(ctx)
+
+		select {
+		case <-time.After(m.interval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// check retrieves the base credentials and gets their caller identity.
+func (m *BaseCredentialsMonitor) check(ctx context.Context) {
+	now := m.now()
+
+	var expiration *time.Time
+	creds, err := m.credentials.Retrieve(ctx)
+	if err == nil && creds.CanExpire {
+		expiration = &creds.Expires
+		if !now.Before(creds.Expires) {
+			err = fmt.Errorf("credentials expired at %s", creds.Expires.Format(time.RFC3339))
+		}
+	}
+
+	var identity string
+	if err == nil {
+		var resp *sts.GetCallerIdentityOutput
+		resp, err = m.sts.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+		if err == nil {
+			identity = aws.ToString(resp.Arn)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.status.LastCheck = &now
+	if err != nil {
+		if m.err == nil || errors.Is(m.err, errBaseCredentialsUnchecked) {
+			log.Errorf("Base credentials of the controller are unavailable: %v", err)
+		}
+		m.err = err
+		m.status.LastError = err.Error()
+		return
+	}
+
+	if m.err != nil && !errors.Is(m.err, errBaseCredentialsUnchecked) {
+		log.Infof("Base credentials of the controller are available again")
+	}
+	if identity != m.status.Identity {
+		log.Infof("Using base credentials of %s", identity)
+	}
+
+	m.err = nil
+	m.status.Identity = identity
+	m.status.Expiration = expiration
+	m.status.LastSuccess = &now
+	m.status.LastError = ""
+}
+
+// Err returns the error of the last check or nil if the base credentials
+// are usable. Nil monitors report no error.
+func (m *BaseCredentialsMonitor) Err() error {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+// ServeHTTP serves the status of the base credentials as JSON. The status
+// code is 503 if the base credentials are unusable.
+func (m *BaseCredentialsMonitor) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	m.mu.Lock()
+	status, err := m.status, m.err
+	m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		status.LastError = err.Error()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/stretchr/testify/require"
+)
+
+type mockCredentialsProvider struct {
+	creds aws.Credentials
+	err   error
+}
+
+func (p *mockCredentialsProvider) Retrieve(_ context.Context) (aws.Credentials, error) {
+	return p.creds, p.err
+}
+
+func newTestBaseCredentialsMonitor(provider aws.CredentialsProvider, svc callerIdentityAPI, now time.Time) *BaseCredentialsMonitor {
+	return &BaseCredentialsMonitor{
+		credentials: provider,
+		sts:         svc,
+		err:         errBaseCredentialsUnchecked,
+		now:         func() time.Time { return now },
+	}
+}
+
+func serveBaseCredentials(t *testing.T, monitor *BaseCredentialsMonitor) (int, BaseCredentialsStatus) {
+	recorder := httptest.NewRecorder()
+	monitor.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/base-credentials", nil))
+
+	var status BaseCredentialsStatus
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	return recorder.Code, status
+}
+
+func TestBaseCredentialsMonitor(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	provider := &mockCredentialsProvider{
+		creds: aws.Credentials{CanExpire: true, Expires: now.Add(time.Hour)},
+	}
+	svc := &mockCallerIdentityAPI{
+		resp: &sts.GetCallerIdentityOutput{Arn: aws.String("arn:aws:sts::123456789012:assumed-role/controller/session")},
+	}
+	monitor := newTestBaseCredentialsMonitor(provider, svc, now)
+
+	// not ready before the first check.
+	require.ErrorIs(t, monitor.Err(), errBaseCredentialsUnchecked)
+	code, _ := serveBaseCredentials(t, monitor)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+
+	monitor.check(context.Background())
+	require.NoError(t, monitor.Err())
+	code, status := serveBaseCredentials(t, monitor)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "arn:aws:sts::123456789012:assumed-role/controller/session", status.Identity)
+	require.Equal(t, now.Add(time.Hour), *status.Expiration)
+	require.Empty(t, status.LastError)
+
+	// failing to refresh the credentials makes the monitor unhealthy but
+	// keeps the last known identity.
+	provider.err = errors.New("AccessDenied: not authorized to perform sts:AssumeRole")
+	monitor.check(context.Background())
+	require.ErrorContains(t, monitor.Err(), "AccessDenied")
+	code, status = serveBaseCredentials(t, monitor)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, "arn:aws:sts::123456789012:assumed-role/controller/session", status.Identity)
+	require.Contains(t, status.LastError, "AccessDenied")
+
+	// expired credentials are unusable.
+	provider.err = nil
+	provider.creds.Expires = now.Add(-time.Minute)
+	monitor.check(context.Background())
+	require.ErrorContains(t, monitor.Err(), "credentials expired")
+
+	provider.creds.Expires = now.Add(time.Hour)
+	svc.err = errors.New("ExpiredToken")
+	monitor.check(context.Background())
+	require.ErrorContains(t, monitor.Err(), "ExpiredToken")
+
+	// the monitor recovers.
+	svc.err = nil
+	monitor.check(context.Background())
+	require.NoError(t, monitor.Err())
+
+	// nil monitors report no error.
+	var nilMonitor *BaseCredentialsMonitor
+	require.NoError(t, nilMonitor.Err())
+}
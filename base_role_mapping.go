@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+// BaseRoleMappingConfig is the configuration file format of base role
+// mappings.
+type BaseRoleMappingConfig struct {
+	Mappings []BaseRoleMapping `json:"mappings"`
+}
+
+// BaseRoleMapping maps AWSIAMRoles by their namespace and/or the account of
+// their role to a source role which is assumed before assuming their role.
+type BaseRoleMapping struct {
+	// Name identifies the mapping in logs.
+	Name string `json:"name"`
+	// NamespaceSelector selects the namespaces of the mapping. If not set
+	// all namespaces match.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// AccountIDs are the account IDs of the roles of the mapping. If empty
+	// all accounts match.
+	AccountIDs []string `json:"accountIDs,omitempty"`
+	// SourceRole is the ARN of the role assumed before assuming the role of
+	// an AWSIAMRole.
+	SourceRole string `json:"sourceRole"`
+}
+
+// LoadBaseRoleMappings loads and validates base role mappings from a YAML
+// file.
+func LoadBaseRoleMappings(path string) ([]BaseRoleMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config BaseRoleMappingConfig
+	err = yaml.UnmarshalStrict(data, &config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base role mappings: %w", err)
+	}
+
+	names := make(map[string]struct{}, len(config.Mappings))
+	for _, mapping := range config.Mappings {
+		if mapping.Name == "" {
+			return nil, fmt.Errorf("base role mapping without name")
+		}
+
+		if _, ok := names[mapping.Name]; ok {
+			return nil, fmt.Errorf("duplicate base role mapping '%s'", mapping.Name)
+		}
+		names[mapping.Name] = struct{}{}
+
+		if mapping.NamespaceSelector == nil && len(mapping.AccountIDs) == 0 {
+			return nil, fmt.Errorf("base role mapping '%s' must define a namespaceSelector and/or accountIDs", mapping.Name)
+		}
+
+		_, err := arn.Parse(mapping.SourceRole)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source role of base role mapping '%s': %w", mapping.Name, err)
+		}
+	}
+
+	return config.Mappings, nil
+}
+
+// mappedCredentialsGetter is a credentials getter selected by a base role
+// mapping.
+type mappedCredentialsGetter struct {
+	name       string
+	selector   labels.Selector
+	accountIDs map[string]struct{}
+	getter     CredentialsGetter
+}
+
+// MappingCredentialsGetter is a credentials getter which routes requests to
+// the credentials getter of the first base role mapping matching the
+// namespace and the account of the role. Requests not matching any mapping
+// are handled by the default credentials getter.
+type MappingCredentialsGetter struct {
+	client           kubernetes.Interface
+	defaultAccountID string
+	defaultGetter    CredentialsGetter
+	mappings         []mappedCredentialsGetter
+}
+
+// NewMappingCredentialsGetter initializes a new mapping credentials getter.
+// The default account ID is the account of roles referenced by name.
+func NewMappingCredentialsGetter(client kubernetes.Interface, defaultAccountID string, defaultGetter CredentialsGetter) *MappingCredentialsGetter {
+	return &MappingCredentialsGetter{
+		client:           client,
+		defaultAccountID: defaultAccountID,
+		defaultGetter:    defaultGetter,
+	}
+}
+
+// Add adds a base role mapping with the credentials getter used for the
+// requests matching it. Mappings are matched in the order they are added.
+func (g *MappingCredentialsGetter) Add(mapping BaseRoleMapping, getter CredentialsGetter) error {
+	mapped := mappedCredentialsGetter{
+		name:   mapping.Name,
+		getter: getter,
+	}
+
+	if mapping.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(mapping.NamespaceSelector)
+		if err != nil {
+			return fmt.Errorf("invalid namespace selector of base role mapping '%s': %w", mapping.Name, err)
+		}
+		mapped.selector = selector
+	}
+
+	if len(mapping.AccountIDs) > 0 {
+		mapped.accountIDs = make(map[string]struct{}, len(mapping.AccountIDs))
+		for _, accountID := range mapping.AccountIDs {
+			mapped.accountIDs[accountID] = struct{}{}
+		}
+	}
+
+	g.mappings = append(g.mappings, mapped)
+	return nil
+}
+
+// Get gets new credentials for the specified role from the credentials getter
+// of the matching base role mapping.
+func (g *MappingCredentialsGetter) Get(ctx context.Context, role string, sessionDuration time.Duration, opts CredentialsOptions) (*Credentials, error) {
+	getter, err := g.getter(ctx, role, opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	return getter.Get(ctx, role, sessionDuration, opts)
+}
+
+// MappingName returns the name of the base role mapping used for the role in
+// the namespace. Without namespace only mappings without namespace selector
+// can match. It's empty if no mapping matches.
+func (g *MappingCredentialsGetter) MappingName(ctx context.Context, role string, namespace *v1.Namespace) (string, error) {
+	var name string
+	var namespaceLabels labels.Set
+	if namespace != nil {
+		name = namespace.Name
+		namespaceLabels = labels.Set(namespace.Labels)
+		if namespaceLabels == nil {
+			namespaceLabels = labels.Set{}
+		}
+	}
+
+	mapping, err := g.mapping(ctx, role, name, namespaceLabels)
+	if err != nil || mapping == nil {
+		return "", err
+	}
+	return mapping.name, nil
+}
+
+// getter returns the credentials getter of the first mapping matching the
+// role and namespace or the default credentials getter.
+func (g *MappingCredentialsGetter) getter(ctx context.Context, role, namespace string) (CredentialsGetter, error) {
+	mapping, err := g.mapping(ctx, role, namespace, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if mapping == nil {
+		return g.defaultGetter, nil
+	}
+	return mapping.getter, nil
+}
+
+// mapping returns the first mapping matching the role and namespace or nil.
+// The namespace labels are looked up if needed and not passed.
+func (g *MappingCredentialsGetter) mapping(ctx context.Context, role, namespace string, namespaceLabels labels.Set) (*mappedCredentialsGetter, error) {
+	accountID, err := roleAccountID(role, g.defaultAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, mapping := range g.mappings {
+		if mapping.accountIDs != nil {
+			if _, ok := mapping.accountIDs[accountID]; !ok {
+				continue
+			}
+		}
+
+		if mapping.selector != nil {
+			if namespace == "" {
+				continue
+			}
+
+			if namespaceLabels == nil {
+				ns, err := g.client.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+				if err != nil {
+					return nil, fmt.Errorf("failed to get namespace %s: %w", namespace, err)
+				}
+				namespaceLabels = labels.Set(ns.Labels)
+				if namespaceLabels == nil {
+					namespaceLabels = labels.Set{}
+				}
+			}
+
+			if !mapping.selector.Matches(namespaceLabels) {
+				continue
+			}
+		}
+
+		return &g.mappings[i], nil
+	}
+
+	return nil, nil
+}
+
+// roleAccountID returns the account ID of a role reference. For role names
+// the default account ID is returned.
+func roleAccountID(role, defaultAccountID string) (string, error) {
+	if !strings.HasPrefix(role, arnPrefix) {
+		return defaultAccountID, nil
+	}
+
+	roleARN, err := arn.Parse(role)
+	if err != nil {
+		return "", fmt.Errorf("invalid role ARN '%s': %w", role, err)
+	}
+	return roleARN.AccountID, nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeKube "k8s.io/client-go/kubernetes/fake"
+)
+
+type namedCredsGetter string
+
+func (g namedCredsGetter) Get(ctx context.Context, role string, sessionDuration time.Duration, opts CredentialsOptions) (*Credentials, error) {
+	return &Credentials{RoleARN: role, Endpoint: string(g)}, nil
+}
+
+func TestLoadBaseRoleMappings(tt *testing.T) {
+	for _, tc := range []struct {
+		msg     string
+		config  string
+		success bool
+	}{
+		{
+			msg: "valid mappings",
+			config: `
+mappings:
+- name: finance
+  namespaceSelector:
+    matchLabels:
+      business-unit: finance
+  sourceRole: arn:aws:iam::111111111111:role/finance-source
+- name: logistics
+  accountIDs: ["222222222222"]
+  sourceRole: arn:aws:iam::222222222222:role/logistics-source
+`,
+			success: true,
+		},
+		{
+			msg: "missing selector and account IDs",
+			config: `
+mappings:
+- name: finance
+  sourceRole: arn:aws:iam::111111111111:role/finance-source
+`,
+		},
+		{
+			msg: "invalid source role",
+			config: `
+mappings:
+- name: finance
+  accountIDs: ["111111111111"]
+  sourceRole: finance-source
+`,
+		},
+		{
+			msg: "duplicate names",
+			config: `
+mappings:
+- name: finance
+  accountIDs: ["111111111111"]
+  sourceRole: arn:aws:iam::111111111111:role/finance-source
+- name: finance
+  accountIDs: ["222222222222"]
+  sourceRole: arn:aws:iam::222222222222:role/finance-source
+`,
+		},
+		{
+			msg: "unknown field",
+			config: `
+mappings:
+- name: finance
+  accountID: "111111111111"
+  sourceRole: arn:aws:iam::111111111111:role/finance-source
+`,
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "mappings.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tc.config), 0o600))
+
+			mappings, err := LoadBaseRoleMappings(path)
+			if tc.success {
+				require.NoError(t, err)
+				require.Len(t, mappings, 2)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestMappingCredentialsGetter(tt *testing.T) {
+	client := fakeKube.NewSimpleClientset(
+		&v1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "finance",
+				Labels: map[string]string{"business-unit": "finance"},
+			},
+		},
+		&v1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "other",
+			},
+		},
+	)
+
+	getter := NewMappingCredentialsGetter(client, "000000000000", namedCredsGetter("default"))
+	require.NoError(tt, getter.Add(BaseRoleMapping{
+		Name: "finance-logistics",
+		NamespaceSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"business-unit": "finance"},
+		},
+		AccountIDs: []string{"222222222222"},
+	}, namedCredsGetter("finance-logistics")))
+	require.NoError(tt, getter.Add(BaseRoleMapping{
+		Name: "finance",
+		NamespaceSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"business-unit": "finance"},
+		},
+	}, namedCredsGetter("finance")))
+	require.NoError(tt, getter.Add(BaseRoleMapping{
+		Name:       "logistics",
+		AccountIDs: []string{"222222222222"},
+	}, namedCredsGetter("logistics")))
+
+	for _, tc := range []struct {
+		msg       string
+		role      string
+		namespace string
+		expected  string
+	}{
+		{
+			msg:       "namespace and account",
+			role:      "arn:aws:iam::222222222222:role/role-name",
+			namespace: "finance",
+			expected:  "finance-logistics",
+		},
+		{
+			msg:       "namespace with role name",
+			role:      "role-name",
+			namespace: "finance",
+			expected:  "finance",
+		},
+		{
+			msg:       "account",
+			role:      "arn:aws:iam::222222222222:role/role-name",
+			namespace: "other",
+			expected:  "logistics",
+		},
+		{
+			msg:       "account without namespace",
+			role:      "arn:aws:iam::222222222222:role/role-name",
+			namespace: "",
+			expected:  "logistics",
+		},
+		{
+			msg:       "no match",
+			role:      "role-name",
+			namespace: "other",
+			expected:  "default",
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			creds, err := getter.Get(context.Background(), tc.role, time.Hour, CredentialsOptions{Namespace: tc.namespace})
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, creds.Endpoint)
+		})
+	}
+
+	_, err := getter.Get(context.Background(), "role-name", time.Hour, CredentialsOptions{Namespace: "missing"})
+	require.Error(tt, err)
+
+	// mappings are matched by the labels of the passed namespace.
+	name, err := getter.MappingName(context.Background(), "role-name", &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "missing",
+			Labels: map[string]string{"business-unit": "finance"},
+		},
+	})
+	require.NoError(tt, err)
+	require.Equal(tt, "finance", name)
+
+	name, err = getter.MappingName(context.Background(), "role-name", nil)
+	require.NoError(tt, err)
+	require.Empty(tt, name)
+}
+
+func TestRoleAccountID(t *testing.T) {
+	accountID, err := roleAccountID("role-name", "000000000000")
+	require.NoError(t, err)
+	require.Equal(t, "000000000000", accountID)
+
+	accountID, err = roleAccountID("arn:aws:iam::123456789012:role/", "")
+	require.NoError(t, err)
+	require.Equal(t, "123456789012", accountID)
+
+	_, err = roleAccountID("arn:invalid", "")
+	require.Error(t, err)
+}
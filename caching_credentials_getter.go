@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// CachingCredentialsGetter is a credentials getter which caches the
+// credentials of another credentials getter keyed by the role and session
+// parameters. Concurrent requests for the same key are coalesced into a
+// single request.
+type CachingCredentialsGetter struct {
+	getter      CredentialsGetter
+	minLifetime time.Duration
+	cache       map[string]*Credentials
+	mu          sync.Mutex
+	group       singleflight.Group
+}
+
+// NewCachingCredentialsGetter initializes a new caching credentials getter.
+// Cached credentials are only returned if they are valid for at least
+// minLifetime.
+func NewCachingCredentialsGetter(getter CredentialsGetter, minLifetime time.Duration) *CachingCredentialsGetter {
+	return &CachingCredentialsGetter{
+		getter:      getter,
+		minLifetime: minLifetime,
+		cache:       make(map[string]*Credentials),
+	}
+}
+
+// Get returns cached credentials for the role and session parameters if
+// they are valid for long enough. Otherwise new credentials are fetched from
+// the underlying credentials getter.
+func (c *CachingCredentialsGetter) Get(ctx context.Context, role string, sessionDuration time.Duration, opts CredentialsOptions) (*Credentials, error) {
+	key, err := credentialsCacheKey(role, sessionDuration, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if creds := c.cached(key); creds != nil {
+		return creds, nil
+	}
+
+	creds, err, _ := c.group.Do(key, func() (interface{}, error) {
+		if creds := c.cached(key); creds != nil {
+			return creds, nil
+		}
+
+		creds, err := c.getter.Get(ctx, role, sessionDuration, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		c.store(key, creds)
+		return creds, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return creds.(*Credentials), nil
+}
+
+// cached returns the cached credentials for the key if they are valid for at
+// least the minimum lifetime.
+func (c *CachingCredentialsGetter) cached(key string) *Credentials {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	creds, ok := c.cache[key]
+	if !ok || time.Now().Add(c.minLifetime).After(creds.Expiration) {
+		return nil
+	}
+	return creds
+}
+
+// store adds credentials to the cache and removes all cached credentials
+// which are no longer valid for the minimum lifetime.
+func (c *CachingCredentialsGetter) store(key string, creds *Credentials) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	minExpiration := time.Now().Add(c.minLifetime)
+	for k, cached := range c.cache {
+		if minExpiration.After(cached.Expiration) {
+			delete(c.cache, k)
+		}
+	}
+
+	c.cache[key] = creds
+}
+
+// credentialsCacheKey returns the cache key for the role and session
+// parameters.
+func credentialsCacheKey(role string, sessionDuration time.Duration, opts CredentialsOptions) (string, error) {
+	data, err := json.Marshal(struct {
+		Role            string
+		SessionDuration time.Duration
+		Options         CredentialsOptions
+	}{
+		Role:            role,
+		SessionDuration: sessionDuration,
+		Options:         opts,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
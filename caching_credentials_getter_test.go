@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type countingCredsGetter struct {
+	calls    int32
+	err      error
+	lifetime time.Duration
+	wait     chan struct{}
+}
+
+func (g *countingCredsGetter) Get(ctx context.Context, role string, sessionDuration time.Duration, opts CredentialsOptions) (*Credentials, error) {
+	atomic.AddInt32(&g.calls, 1)
+	if g.wait != nil {
+		<-g.wait
+	}
+	if g.err != nil {
+		return nil, g.err
+	}
+	return &Credentials{
+		RoleARN:    role,
+		Expiration: time.Now().Add(g.lifetime),
+	}, nil
+}
+
+func TestCachingCredentialsGetter(t *testing.T) {
+	getter := &countingCredsGetter{lifetime: time.Hour}
+	cache := NewCachingCredentialsGetter(getter, 15*time.Minute)
+
+	creds, err := cache.Get(context.Background(), "role", time.Hour, CredentialsOptions{})
+	require.NoError(t, err)
+	cached, err := cache.Get(context.Background(), "role", time.Hour, CredentialsOptions{})
+	require.NoError(t, err)
+	require.Same(t, creds, cached)
+	require.EqualValues(t, 1, getter.calls)
+
+	// different session parameters are cached separately.
+	_, err = cache.Get(context.Background(), "role", 2*time.Hour, CredentialsOptions{})
+	require.NoError(t, err)
+	_, err = cache.Get(context.Background(), "role", time.Hour, CredentialsOptions{Policy: "{}"})
+	require.NoError(t, err)
+	_, err = cache.Get(context.Background(), "role", time.Hour, CredentialsOptions{Tags: []SessionTag{{Key: "k", Value: "v"}}})
+	require.NoError(t, err)
+	_, err = cache.Get(context.Background(), "other-role", time.Hour, CredentialsOptions{})
+	require.NoError(t, err)
+	require.EqualValues(t, 5, getter.calls)
+
+	// errors are not cached.
+	getter.err = errors.New("failed")
+	_, err = cache.Get(context.Background(), "failing-role", time.Hour, CredentialsOptions{})
+	require.Error(t, err)
+	_, err = cache.Get(context.Background(), "failing-role", time.Hour, CredentialsOptions{})
+	require.Error(t, err)
+	require.EqualValues(t, 7, getter.calls)
+}
+
+func TestCachingCredentialsGetterMinLifetime(t *testing.T) {
+	getter := &countingCredsGetter{lifetime: 10 * time.Minute}
+	cache := NewCachingCredentialsGetter(getter, 15*time.Minute)
+
+	_, err := cache.Get(context.Background(), "role", time.Hour, CredentialsOptions{})
+	require.NoError(t, err)
+	_, err = cache.Get(context.Background(), "role", time.Hour, CredentialsOptions{})
+	require.NoError(t, err)
+	require.EqualValues(t, 2, getter.calls)
+}
+
+func TestCachingCredentialsGetterCoalesce(t *testing.T) {
+	getter := &countingCredsGetter{
+		lifetime: time.Hour,
+		wait:     make(chan struct{}),
+	}
+	cache := NewCachingCredentialsGetter(getter, 15*time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.Get(context.Background(), "role", time.Hour, CredentialsOptions{})
+			require.NoError(t, err)
+		}()
+	}
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&getter.calls) == 1
+	}, time.Second, time.Millisecond)
+	close(getter.wait)
+	wg.Wait()
+	require.EqualValues(t, 1, getter.calls)
+}
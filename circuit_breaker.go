@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	av1 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	circuitBreakerClosed = "Closed"
+	circuitBreakerOpen   = "Open"
+)
+
+// errCircuitOpen is returned when credentials are not requested because the
+// circuit breaker of the AWSIAMRole is open.
+var errCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreaker stops requesting credentials for AWSIAMRoles after a number
+// of consecutive permanent failures. While open, a single attempt is allowed
+// after a backoff which doubles with every further failure up to a maximum.
+// The state of an AWSIAMRole is reset when its generation changes.
+type CircuitBreaker struct {
+	threshold   int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+	states      map[types.UID]*circuitState
+	mu          sync.Mutex
+	now         func() time.Time
+}
+
+type circuitState struct {
+	generation int64
+	failures   int
+	lastError  string
+	retryAfter time.Time
+}
+
+// NewCircuitBreaker initializes a new circuit breaker which opens after
+// threshold consecutive permanent failures.
+func NewCircuitBreaker(threshold int, baseBackoff, maxBackoff time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold:   threshold,
+		baseBackoff: baseBackoff,
+		maxBackoff:  maxBackoff,
+		states:      make(map[types.UID]*circuitState),
+		now:         time.Now,
+	}
+}
+
+// Allow returns true if credentials may be requested for the AWSIAMRole.
+func (b *CircuitBreaker) Allow(awsIAMRole *av1.AWSIAMRole) bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.state(awsIAMRole)
+	return state == nil || state.failures < b.threshold || !b.now().Before(state.retryAfter)
+}
+
+// Success resets the state of the AWSIAMRole.
+func (b *CircuitBreaker) Success(awsIAMRole *av1.AWSIAMRole) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.states, awsIAMRole.UID)
+}
+
+// Failure records a failure for the AWSIAMRole. Only permanent errors are
+// counted.
+func (b *CircuitBreaker) Failure(awsIAMRole *av1.AWSIAMRole, err error) {
+	if b == nil || classifyError(err) != errorClassPermanent {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.state(awsIAMRole)
+	if state == nil {
+		state = &circuitState{generation: awsIAMRole.Generation}
+		b.states[awsIAMRole.UID] = state
+	}
+
+	state.failures++
+	state.lastError = err.Error()
+	if state.failures >= b.threshold {
+		state.retryAfter = b.now().Add(b.backoff(state.failures - b.threshold))
+	}
+}
+
+// Status returns the circuit breaker status of the AWSIAMRole or nil if
+// there are no recorded failures.
+func (b *CircuitBreaker) Status(awsIAMRole *av1.AWSIAMRole) *av1.CircuitBreakerStatus {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.state(awsIAMRole)
+	if state == nil {
+		return nil
+	}
+
+	status := &av1.CircuitBreakerStatus{
+		State:               circuitBreakerClosed,
+		ConsecutiveFailures: int32(state.failures),
+		LastError:           state.lastError,
+	}
+
+	if state.failures >= b.threshold {
+		retryAfter := metav1.NewTime(state.retryAfter)
+		status.State = circuitBreakerOpen
+		status.RetryAfter = &retryAfter
+	}
+
+	return status
+}
+
+// Prune removes the state of all AWSIAMRoles not in the list.
+func (b *CircuitBreaker) Prune(awsIAMRoles []av1.AWSIAMRole) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	existing := make(map[types.UID]struct{}, len(awsIAMRoles))
+	for _, awsIAMRole := range awsIAMRoles {
+		existing[awsIAMRole.UID] = struct{}{}
+	}
+
+	for uid := range b.states {
+		if _, ok := existing[uid]; !ok {
+			delete(b.states, uid)
+		}
+	}
+}
+
+// state returns the state of the AWSIAMRole. State recorded for a previous
+// generation is discarded.
+func (b *CircuitBreaker) state(awsIAMRole *av1.AWSIAMRole) *circuitState {
+	state, ok := b.states[awsIAMRole.UID]
+	if !ok {
+		return nil
+	}
+
+	if state.generation != awsIAMRole.Generation {
+		delete(b.states, awsIAMRole.UID)
+		return nil
+	}
+
+	return state
+}
+
+// backoff returns the time to wait before the next attempt after the circuit
+// breaker has been open for the given number of failures.
+func (b *CircuitBreaker) backoff(failures int) time.Duration {
+	if failures >= 32 || b.baseBackoff<<failures > b.maxBackoff || b.baseBackoff<<failures <= 0 {
+		return b.maxBackoff
+	}
+	return b.baseBackoff << failures
+}
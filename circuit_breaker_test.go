@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/stretchr/testify/require"
+	av1 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	breaker := NewCircuitBreaker(2, time.Minute, 3*time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	awsIAMRole := &av1.AWSIAMRole{
+		ObjectMeta: metav1.ObjectMeta{
+			UID:        types.UID("1234"),
+			Generation: 1,
+		},
+	}
+	accessDenied := &smithy.GenericAPIError{Code: "AccessDenied"}
+
+	require.True(t, breaker.Allow(awsIAMRole))
+	require.Nil(t, breaker.Status(awsIAMRole))
+
+	// transient errors are not counted.
+	breaker.Failure(awsIAMRole, &smithy.GenericAPIError{Code: "Throttling"})
+	breaker.Failure(awsIAMRole, fmt.Errorf("failed to get credentials: %w", context.DeadlineExceeded))
+	breaker.Failure(awsIAMRole, &smithy.CanceledError{Err: context.Canceled})
+	breaker.Failure(awsIAMRole, &smithyhttp.RequestSendError{Err: errors.New("connection reset")})
+	require.Nil(t, breaker.Status(awsIAMRole))
+
+	breaker.Failure(awsIAMRole, accessDenied)
+	require.True(t, breaker.Allow(awsIAMRole))
+	status := breaker.Status(awsIAMRole)
+	require.Equal(t, circuitBreakerClosed, status.State)
+	require.EqualValues(t, 1, status.ConsecutiveFailures)
+	require.Contains(t, status.LastError, "AccessDenied")
+
+	breaker.Failure(awsIAMRole, accessDenied)
+	require.False(t, breaker.Allow(awsIAMRole))
+	status = breaker.Status(awsIAMRole)
+	require.Equal(t, circuitBreakerOpen, status.State)
+	require.Equal(t, now.Add(time.Minute).Unix(), status.RetryAfter.Unix())
+
+	// a single attempt is allowed after the backoff.
+	now = now.Add(time.Minute)
+	require.True(t, breaker.Allow(awsIAMRole))
+	breaker.Failure(awsIAMRole, accessDenied)
+	require.False(t, breaker.Allow(awsIAMRole))
+	require.Equal(t, now.Add(2*time.Minute).Unix(), breaker.Status(awsIAMRole).RetryAfter.Unix())
+
+	// the backoff is capped.
+	now = now.Add(2 * time.Minute)
+	breaker.Failure(awsIAMRole, accessDenied)
+	require.Equal(t, now.Add(3*time.Minute).Unix(), breaker.Status(awsIAMRole).RetryAfter.Unix())
+
+	// a new generation resets the circuit breaker.
+	awsIAMRole.Generation = 2
+	require.True(t, breaker.Allow(awsIAMRole))
+	require.Nil(t, breaker.Status(awsIAMRole))
+
+	// success resets the circuit breaker.
+	breaker.Failure(awsIAMRole, accessDenied)
+	breaker.Failure(awsIAMRole, accessDenied)
+	require.False(t, breaker.Allow(awsIAMRole))
+	breaker.Success(awsIAMRole)
+	require.True(t, breaker.Allow(awsIAMRole))
+
+	// deleted AWSIAMRoles are pruned.
+	breaker.Failure(awsIAMRole, errors.New("invalid role ARN"))
+	breaker.Prune(nil)
+	require.Empty(t, breaker.states)
+}
+
+func TestCircuitBreakerNil(t *testing.T) {
+	var breaker *CircuitBreaker
+	awsIAMRole := &av1.AWSIAMRole{}
+
+	breaker.Failure(awsIAMRole, errors.New("failed"))
+	require.True(t, breaker.Allow(awsIAMRole))
+	require.Nil(t, breaker.Status(awsIAMRole))
+}
@@ -0,0 +1,703 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	av1 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v1"
+	"github.com/zalando-incubator/kube-aws-iam-controller/pkg/clientset"
+	"github.com/zalando-incubator/kube-aws-iam-controller/pkg/recorder"
+	v1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	reasonNoNamespacesSelected     = "NoNamespacesSelected"
+	reasonInvalidNamespaceSelector = "InvalidNamespaceSelector"
+	reasonSyncFailed               = "SyncFailed"
+	reasonBaseRoleMappingMismatch  = "BaseRoleMappingMismatch"
+	reasonSecretConflict           = "SecretConflict"
+)
+
+var (
+	clusterAWSIAMRoleOwnerLabels = map[string]string{
+		heritageLabelKey: awsIAMControllerLabelValue,
+		"type":           "clusterawsiamrole",
+	}
+)
+
+// baseRoleMapper is implemented by credentials getters which select the
+// source role by the namespace of the role.
+type baseRoleMapper interface {
+	MappingName(ctx context.Context, role string, namespace *v1.Namespace) (string, error)
+}
+
+// ClusterAWSIAMRoleController is a controller which lists ClusterAWSIAMRole
+// resources and provisions secrets with AWS IAM role credentials in the
+// selected namespaces.
+type ClusterAWSIAMRoleController struct {
+	client       clientset.Interface
+	recorder     record.EventRecorder
+	interval     time.Duration
+	refreshLimit time.Duration
+	creds        CredentialsGetter
+	namespace    string
+	session      *SessionConfig
+	breaker      *CircuitBreaker
+	baseCreds    *BaseCredentialsMonitor
+}
+
+// NewClusterAWSIAMRoleController initializes a new
+// ClusterAWSIAMRoleController. If namespace is set, secrets are only
+// provisioned in that namespace.
+func NewClusterAWSIAMRoleController(client clientset.Interface, interval, refreshLimit time.Duration, creds CredentialsGetter, namespace string, session *SessionConfig, breaker *CircuitBreaker, baseCreds *BaseCredentialsMonitor) *ClusterAWSIAMRoleController {
+	return &ClusterAWSIAMRoleController{
+		client:       client,
+		recorder:     recorder.CreateEventRecorder(client),
+		interval:     interval,
+		refreshLimit: refreshLimit,
+		creds:        creds,
+		namespace:    namespace,
+		session:      session,
+		breaker:      breaker,
+		baseCreds:    baseCreds,
+	}
+}
+
+// Run runs the ClusterAWSIAMRole controller loop.
+func (c *ClusterAWSIAMRoleController) Run(ctx context.Context) {
+	for {
+		err := c.refresh(ctx)
+		if err != nil {
+			log.Error(err)
+		}
+
+		select {
+		case <-time.After(c.interval):
+		case <-ctx.Done():
+			log.Info("Terminating ClusterAWSIAMRole controller loop.")
+			return
+		}
+	}
+}
+
+// refresh syncs the secrets of all ClusterAWSIAMRoles and removes secrets of
+// deleted ClusterAWSIAMRoles.
+func (c *ClusterAWSIAMRoleController) refresh(ctx context.Context) error {
+	opts := metav1.ListOptions{
+		LabelSelector: labels.Set(clusterAWSIAMRoleOwnerLabels).AsSelector().String(),
+	}
+
+	secrets, err := c.client.CoreV1().Secrets(c.namespace).List(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	clusterAWSIAMRoles, err := c.client.ZalandoV1().ClusterAWSIAMRoles().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	namespaces, err := c.client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	templates := make([]av1.AWSIAMRole, 0, len(clusterAWSIAMRoles.Items))
+	clusterAWSIAMRolesMap := make(map[string]*av1.ClusterAWSIAMRole, len(clusterAWSIAMRoles.Items))
+	for i, clusterAWSIAMRole := range clusterAWSIAMRoles.Items {
+		templates = append(templates, *awsIAMRoleTemplate(&clusterAWSIAMRole))
+		clusterAWSIAMRolesMap[clusterAWSIAMRole.Name] = &clusterAWSIAMRoles.Items[i]
+	}
+
+	c.breaker.Prune(templates)
+
+	// group the secrets by ClusterAWSIAMRole and namespace.
+	secretsMap := make(map[string]map[string]v1.Secret, len(clusterAWSIAMRoles.Items))
+	for _, secret := range secrets.Items {
+		clusterAWSIAMRole, ok := clusterAWSIAMRolesMap[secret.Name]
+		if !ok || !isOwnedReference(clusterAWSIAMRole.TypeMeta, clusterAWSIAMRole.ObjectMeta, secret.ObjectMeta) {
+			c.deleteSecret(ctx, secret, "Removing unused credentials")
+			continue
+		}
+
+		if secretsMap[secret.Name] == nil {
+			secretsMap[secret.Name] = make(map[string]v1.Secret)
+		}
+		secretsMap[secret.Name][secret.Namespace] = secret
+	}
+
+	for i := range clusterAWSIAMRoles.Items {
+		clusterAWSIAMRole := &clusterAWSIAMRoles.Items[i]
+		c.sync(ctx, clusterAWSIAMRole, namespaces.Items, secretsMap[clusterAWSIAMRole.Name])
+	}
+
+	return nil
+}
+
+// sync stores the credentials of the ClusterAWSIAMRole in its secret in every
+// selected namespace and removes its secrets from namespaces which are no
+// longer selected. The role is assumed at most once. Secrets are updated
+// with the credentials of an up to date secret if there is one.
+func (c *ClusterAWSIAMRoleController) sync(ctx context.Context, clusterAWSIAMRole *av1.ClusterAWSIAMRole, namespaces []v1.Namespace, secrets map[string]v1.Secret) {
+	previous := clusterAWSIAMRole.Status.DeepCopy()
+
+	selector, err := metav1.LabelSelectorAsSelector(&clusterAWSIAMRole.Spec.NamespaceSelector)
+	if err != nil {
+		message := fmt.Sprintf("Invalid namespace selector: %v", err)
+		c.recorder.Event(clusterAWSIAMRole, v1.EventTypeWarning, reasonInvalidNamespaceSelector, message)
+		setClusterCondition(clusterAWSIAMRole, conditionReady, metav1.ConditionFalse, reasonInvalidNamespaceSelector, message)
+		c.updateStatus(ctx, clusterAWSIAMRole, previous)
+		return
+	}
+
+	selected := make(map[string]bool)
+	for _, namespace := range namespaces {
+		if c.namespace != metav1.NamespaceAll && namespace.Name != c.namespace {
+			continue
+		}
+
+		if namespace.Status.Phase == v1.NamespaceTerminating {
+			continue
+		}
+
+		if selector.Matches(labels.Set(namespace.Labels)) {
+			selected[namespace.Name] = true
+		}
+	}
+
+	mismatched := c.mismatchedNamespaces(ctx, clusterAWSIAMRole, namespaces, selected)
+	if len(mismatched) > 0 {
+		c.recorder.Event(clusterAWSIAMRole, v1.EventTypeWarning, reasonBaseRoleMappingMismatch,
+			fmt.Sprintf("Not storing credentials in namespaces matched by a different base role mapping than the ClusterAWSIAMRole: %s", strings.Join(sortedKeys(mismatched), ", ")),
+		)
+	}
+
+	for namespace, secret := range secrets {
+		if !selected[namespace] {
+			c.deleteSecret(ctx, secret, "Removing credentials from namespace no longer selected")
+			continue
+		}
+
+		if _, ok := mismatched[namespace]; ok {
+			c.deleteSecret(ctx, secret, "Removing credentials from namespace matched by a different base role mapping")
+		}
+	}
+
+	var secretData map[string][]byte
+	outdated := make(map[string]bool)
+	for namespace := range selected {
+		if _, ok := mismatched[namespace]; ok {
+			continue
+		}
+
+		secret, ok := secrets[namespace]
+		if ok && c.upToDate(clusterAWSIAMRole, secret) {
+			secretData = secret.Data
+			continue
+		}
+		outdated[namespace] = true
+	}
+
+	var creds *Credentials
+	if len(outdated) > 0 && secretData == nil {
+		creds, secretData, err = c.getCreds(ctx, clusterAWSIAMRole)
+		if err != nil {
+			c.recordGetCredentialsFailed(clusterAWSIAMRole, err)
+		}
+	}
+
+	lastSyncTimes := make(map[string]*metav1.Time, len(previous.Namespaces))
+	for _, status := range previous.Namespaces {
+		lastSyncTimes[status.Namespace] = status.LastSyncTime
+	}
+
+	now := metav1.Now()
+	var conflicts []string
+	status := make([]av1.NamespaceSyncStatus, 0, len(selected))
+	for namespace := range selected {
+		namespaceStatus := av1.NamespaceSyncStatus{
+			Namespace:    namespace,
+			Synced:       true,
+			LastSyncTime: lastSyncTimes[namespace],
+		}
+
+		if message, ok := mismatched[namespace]; ok {
+			namespaceStatus.Synced = false
+			namespaceStatus.Message = message
+		} else if outdated[namespace] {
+			if secretData == nil {
+				namespaceStatus.Synced = false
+				namespaceStatus.Message = "No credentials are available for the role"
+			} else {
+				var secret *v1.Secret
+				if s, ok := secrets[namespace]; ok {
+					secret = &s
+				}
+
+				err := c.storeSecret(ctx, clusterAWSIAMRole, namespace, secret, secretData)
+				switch {
+				case apierrors.IsAlreadyExists(err):
+					// secrets owned by the ClusterAWSIAMRole are listed,
+					// so the existing secret belongs to someone else.
+					namespaceStatus.Synced = false
+					namespaceStatus.Message = fmt.Sprintf("Secret %s already exists and is not owned by the ClusterAWSIAMRole", clusterAWSIAMRole.Name)
+					conflicts = append(conflicts, namespace)
+				case err != nil:
+					namespaceStatus.Synced = false
+					namespaceStatus.Message = err.Error()
+					c.recorder.Event(clusterAWSIAMRole, v1.EventTypeWarning, reasonSyncFailed, err.Error())
+				default:
+					namespaceStatus.LastSyncTime = &now
+				}
+			}
+		}
+
+		status = append(status, namespaceStatus)
+	}
+	sort.Slice(status, func(i, j int) bool {
+		return status[i].Namespace < status[j].Namespace
+	})
+	clusterAWSIAMRole.Status.Namespaces = status
+
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		c.recorder.Event(clusterAWSIAMRole, v1.EventTypeWarning, reasonSecretConflict,
+			fmt.Sprintf("Secret %s already exists and is not owned by the ClusterAWSIAMRole in namespaces: %s", clusterAWSIAMRole.Name, strings.Join(conflicts, ", ")),
+		)
+	}
+
+	switch {
+	case creds != nil:
+		c.recorder.Event(clusterAWSIAMRole,
+			v1.EventTypeNormal,
+			"UpdateCredentials",
+			fmt.Sprintf("Issued credentials for role '%s' for %d namespaces, expiry time: %s", creds.RoleARN, len(outdated), creds.Expiration.String()),
+		)
+		setClusterCredentialsStatus(clusterAWSIAMRole, creds, c.refreshLimit)
+	case secretData != nil && (clusterAWSIAMRole.Status.ObservedGeneration == nil || *clusterAWSIAMRole.Status.ObservedGeneration != clusterAWSIAMRole.Generation || clusterAWSIAMRole.Status.Expiration == nil):
+		// the secrets hold credentials issued for the current generation.
+		expiration, err := time.Parse(time.RFC3339, string(secretData[expireKey]))
+		if err == nil {
+			expiryTime := metav1.NewTime(expiration)
+			nextRefreshTime := metav1.NewTime(expiration.Add(-c.refreshLimit))
+			clusterAWSIAMRole.Status.ObservedGeneration = &clusterAWSIAMRole.Generation
+			clusterAWSIAMRole.Status.RoleARN = string(secretData[roleARNKey])
+			clusterAWSIAMRole.Status.Expiration = &expiryTime
+			clusterAWSIAMRole.Status.STSEndpoint = string(secretData[stsEndpointKey])
+			clusterAWSIAMRole.Status.NextRefreshTime = &nextRefreshTime
+			setClusterCondition(clusterAWSIAMRole, conditionCredentialsIssued, metav1.ConditionTrue, reasonIssued,
+				fmt.Sprintf("Issued credentials for role '%s' expiring at %s", clusterAWSIAMRole.Status.RoleARN, expiration.UTC().Format(time.RFC3339)),
+			)
+		}
+	}
+
+	setClusterReadyConditions(clusterAWSIAMRole, time.Now())
+	c.updateStatus(ctx, clusterAWSIAMRole, previous)
+}
+
+// mismatchedNamespaces returns the selected namespaces in which the role
+// would be assumed through a different base role mapping than without
+// namespace, with a message describing the mismatch. The credentials are
+// shared by all selected namespaces, so only base role mappings without
+// namespace selector apply to ClusterAWSIAMRoles and the mismatched
+// namespaces don't get credentials.
+func (c *ClusterAWSIAMRoleController) mismatchedNamespaces(ctx context.Context, clusterAWSIAMRole *av1.ClusterAWSIAMRole, namespaces []v1.Namespace, selected map[string]bool) map[string]string {
+	mapper, ok := c.creds.(baseRoleMapper)
+	if !ok {
+		return nil
+	}
+
+	role := clusterAWSIAMRole.Spec.RoleReference
+	expected, err := mapper.MappingName(ctx, role, nil)
+	if err != nil {
+		// getting credentials fails with the same error.
+		return nil
+	}
+
+	mismatched := make(map[string]string)
+	for i, namespace := range namespaces {
+		if !selected[namespace.Name] {
+			continue
+		}
+
+		name, err := mapper.MappingName(ctx, role, &namespaces[i])
+		if err != nil {
+			mismatched[namespace.Name] = fmt.Sprintf("Failed to match base role mappings: %v", err)
+			continue
+		}
+
+		if name != expected {
+			mismatched[namespace.Name] = fmt.Sprintf("The namespace is matched by base role mapping '%s', which doesn't apply to ClusterAWSIAMRoles", name)
+		}
+	}
+	return mismatched
+}
+
+// upToDate returns true if the secret holds credentials issued for the
+// current generation of the ClusterAWSIAMRole which don't need to be
+// refreshed yet.
+func (c *ClusterAWSIAMRoleController) upToDate(clusterAWSIAMRole *av1.ClusterAWSIAMRole, secret v1.Secret) bool {
+	generation, err := getGeneration(secret.Data)
+	if err != nil || generation != clusterAWSIAMRole.Generation {
+		return false
+	}
+
+	expiration, err := time.Parse(time.RFC3339, string(secret.Data[expireKey]))
+	if err != nil {
+		return false
+	}
+
+	return time.Now().UTC().Add(c.refreshLimit).Before(expiration)
+}
+
+// getCreds gets new credentials for the role of the ClusterAWSIAMRole and
+// converts them to a secret data map.
+func (c *ClusterAWSIAMRoleController) getCreds(ctx context.Context, clusterAWSIAMRole *av1.ClusterAWSIAMRole) (*Credentials, map[string][]byte, error) {
+	awsIAMRole := awsIAMRoleTemplate(clusterAWSIAMRole)
+	roleSessionDuration := getRoleSessionDuration(awsIAMRole)
+
+	opts, err := c.credentialsOptions(clusterAWSIAMRole, awsIAMRole)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	creds, err := getCredentials(ctx, c.creds, c.breaker, c.baseCreds, awsIAMRole, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if creds.SessionDuration > 0 && creds.SessionDuration < roleSessionDuration {
+		c.recorder.Event(clusterAWSIAMRole,
+			v1.EventTypeWarning,
+			"SessionDurationClamped",
+			fmt.Sprintf("Session duration for role '%s' was limited to %s instead of the requested %s", creds.RoleARN, creds.SessionDuration, roleSessionDuration),
+		)
+	}
+
+	secretData, err := credentialsSecretData(creds, clusterAWSIAMRole.Spec.SecretFormats)
+	if err != nil {
+		return nil, nil, err
+	}
+	secretData[awsIAMRoleGenerationKey] = []byte(fmt.Sprintf("%d", clusterAWSIAMRole.Generation))
+
+	return creds, secretData, nil
+}
+
+// credentialsOptions resolves the optional credentials parameters of the
+// ClusterAWSIAMRole from its AWSIAMRole template. Namespace labels are not
+// copied to session tags as the credentials are shared by all selected
+// namespaces. Ignored session tags of the spec are reported in the
+// SessionTagsIgnored condition of the ClusterAWSIAMRole.
+func (c *ClusterAWSIAMRoleController) credentialsOptions(clusterAWSIAMRole *av1.ClusterAWSIAMRole, awsIAMRole *av1.AWSIAMRole) (CredentialsOptions, error) {
+	tags, ignored := c.session.SessionTags(nil, awsIAMRole.Labels, awsIAMRole.Spec.SessionTags)
+	opts := CredentialsOptions{
+		ExternalID:        awsIAMRole.Spec.ExternalID,
+		PolicyARNs:        awsIAMRole.Spec.PolicyARNs,
+		AssumeRoleChain:   awsIAMRole.Spec.AssumeRoleChain,
+		SessionNameSuffix: awsIAMRole.Spec.SessionName,
+		Tags:              tags,
+	}
+
+	if len(clusterAWSIAMRole.Spec.SessionTags) == 0 {
+		meta.RemoveStatusCondition(&clusterAWSIAMRole.Status.Conditions, conditionSessionTagsIgnored)
+	} else {
+		condition := sessionTagsIgnoredCondition(clusterAWSIAMRole.Generation, ignored)
+		if meta.SetStatusCondition(&clusterAWSIAMRole.Status.Conditions, condition) && condition.Status == metav1.ConditionTrue {
+			c.recorder.Event(clusterAWSIAMRole, v1.EventTypeWarning, condition.Reason, condition.Message)
+		}
+	}
+
+	if policy := awsIAMRole.Spec.SessionPolicy; policy != nil {
+		opts.Policy = policy.Inline
+	}
+
+	sourceIdentity, err := c.session.SourceIdentity(awsIAMRole)
+	if err != nil {
+		return opts, err
+	}
+	opts.SourceIdentity = sourceIdentity
+
+	sessionName, err := c.session.SessionName(awsIAMRole)
+	if err != nil {
+		return opts, err
+	}
+	opts.SessionName = sessionName
+
+	return opts, nil
+}
+
+// storeSecret stores the secret data in the secret of the ClusterAWSIAMRole
+// in the namespace. The secret is created if it doesn't exist.
+func (c *ClusterAWSIAMRoleController) storeSecret(ctx context.Context, clusterAWSIAMRole *av1.ClusterAWSIAMRole, namespace string, secret *v1.Secret, secretData map[string][]byte) error {
+	data := make(map[string][]byte, len(secretData))
+	for key, value := range secretData {
+		data[key] = value
+	}
+
+	action := "update"
+	var err error
+	if secret == nil {
+		action = "create"
+		secret = &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      clusterAWSIAMRole.Name,
+				Namespace: namespace,
+				Labels:    mergeLabels(clusterAWSIAMRole.Labels, clusterAWSIAMRoleOwnerLabels),
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: clusterAWSIAMRole.APIVersion,
+						Kind:       clusterAWSIAMRole.Kind,
+						Name:       clusterAWSIAMRole.Name,
+						UID:        clusterAWSIAMRole.UID,
+					},
+				},
+			},
+			Data: data,
+		}
+		_, err = c.client.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{})
+	} else {
+		secret.Labels = mergeLabels(clusterAWSIAMRole.Labels, clusterAWSIAMRoleOwnerLabels)
+		secret.Data = data
+		_, err = c.client.CoreV1().Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to %s secret %s/%s with credentials: %w", action, namespace, secret.Name, err)
+	}
+
+	log.WithFields(log.Fields{
+		"action":    action,
+		"role-arn":  string(data[roleARNKey]),
+		"secret":    secret.Name,
+		"namespace": namespace,
+		"expire":    string(data[expireKey]),
+		"type":      "clusterawsiamrole",
+	}).Info()
+	return nil
+}
+
+// deleteSecret deletes a secret managed for a ClusterAWSIAMRole.
+func (c *ClusterAWSIAMRoleController) deleteSecret(ctx context.Context, secret v1.Secret, message string) {
+	err := c.client.CoreV1().Secrets(secret.Namespace).Delete(ctx, secret.Name, metav1.DeleteOptions{})
+	if err != nil {
+		log.Errorf("Failed to delete secret %s/%s: %v", secret.Namespace, secret.Name, err)
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"action":    "delete",
+		"role-arn":  string(secret.Data[roleARNKey]),
+		"secret":    secret.Name,
+		"namespace": secret.Namespace,
+		"type":      "clusterawsiamrole",
+	}).Info(message)
+}
+
+// recordGetCredentialsFailed records a warning event on the
+// ClusterAWSIAMRole describing why credentials could not be fetched and
+// updates the last failure and the circuit breaker status. Nothing is
+// recorded while the circuit breaker is open.
+func (c *ClusterAWSIAMRoleController) recordGetCredentialsFailed(clusterAWSIAMRole *av1.ClusterAWSIAMRole, err error) {
+	if errors.Is(err, errCircuitOpen) {
+		log.Debugf("Skipping ClusterAWSIAMRole %s: %v", clusterAWSIAMRole.Name, err)
+		return
+	}
+
+	reason := "GetCredentialsFailed"
+	if errors.Is(err, errBaseCredentialsUnavailable) {
+		reason = "BaseCredentialsUnavailable"
+	}
+
+	message := fmt.Sprintf("Failed to get credentials for role '%s': %v", clusterAWSIAMRole.Spec.RoleReference, err)
+	c.recorder.Event(clusterAWSIAMRole, v1.EventTypeWarning, reason, message)
+
+	failure := failureReason(err)
+	clusterAWSIAMRole.Status.LastFailure = &av1.FailureStatus{
+		Reason:  failure,
+		Message: err.Error(),
+		Time:    metav1.Now(),
+	}
+
+	status := c.breaker.Status(awsIAMRoleTemplate(clusterAWSIAMRole))
+	if status != nil && status.State == circuitBreakerOpen {
+		c.recorder.Event(clusterAWSIAMRole,
+			v1.EventTypeWarning,
+			"CircuitBreakerOpen",
+			fmt.Sprintf("Stopped getting credentials for role '%s' after %d consecutive failures, retrying at %s", clusterAWSIAMRole.Spec.RoleReference, status.ConsecutiveFailures, status.RetryAfter.String()),
+		)
+	}
+
+	nextRefreshTime := metav1.NewTime(time.Now().Add(c.interval))
+	if status != nil && status.RetryAfter != nil {
+		nextRefreshTime = *status.RetryAfter
+	}
+
+	clusterAWSIAMRole.Status.CircuitBreaker = status
+	clusterAWSIAMRole.Status.NextRefreshTime = &nextRefreshTime
+	setClusterCondition(clusterAWSIAMRole, conditionCredentialsIssued, metav1.ConditionFalse, failure, message)
+}
+
+// updateStatus updates the status of the ClusterAWSIAMRole if it differs
+// from the previous status.
+func (c *ClusterAWSIAMRoleController) updateStatus(ctx context.Context, clusterAWSIAMRole *av1.ClusterAWSIAMRole, previous *av1.ClusterAWSIAMRoleStatus) {
+	if apiequality.Semantic.DeepEqual(previous, &clusterAWSIAMRole.Status) {
+		return
+	}
+
+	_, err := c.client.ZalandoV1().ClusterAWSIAMRoles().UpdateStatus(ctx, clusterAWSIAMRole, metav1.UpdateOptions{})
+	if err != nil {
+		log.Errorf("Failed to update status of ClusterAWSIAMRole %s: %v", clusterAWSIAMRole.Name, err)
+	}
+}
+
+// setClusterCredentialsStatus sets the ClusterAWSIAMRole status describing
+// the credentials issued for the ClusterAWSIAMRole.
+func setClusterCredentialsStatus(clusterAWSIAMRole *av1.ClusterAWSIAMRole, creds *Credentials, refreshLimit time.Duration) {
+	expiryTime := metav1.NewTime(creds.Expiration)
+	refreshTime := metav1.Now()
+	nextRefreshTime := metav1.NewTime(creds.Expiration.Add(-refreshLimit))
+
+	status := &clusterAWSIAMRole.Status
+	status.ObservedGeneration = &clusterAWSIAMRole.Generation
+	status.RoleARN = creds.RoleARN
+	status.Expiration = &expiryTime
+	status.RoleSessionDuration = int64(creds.SessionDuration.Seconds())
+	status.STSEndpoint = creds.Endpoint
+	status.CircuitBreaker = nil
+	status.LastFailure = nil
+	status.LastRefreshTime = &refreshTime
+	status.NextRefreshTime = &nextRefreshTime
+
+	status.SessionTags = nil
+	for _, tag := range creds.SessionTags {
+		status.SessionTags = append(status.SessionTags, av1.SessionTag{
+			Key:        tag.Key,
+			Value:      tag.Value,
+			Transitive: tag.Transitive,
+		})
+	}
+
+	if creds.SessionDuration > 0 {
+		meta.SetStatusCondition(&status.Conditions, sessionDurationCondition(awsIAMRoleTemplate(clusterAWSIAMRole), creds.SessionDuration))
+	}
+
+	setClusterCondition(clusterAWSIAMRole, conditionCredentialsIssued, metav1.ConditionTrue, reasonIssued,
+		fmt.Sprintf("Issued credentials for role '%s' expiring at %s", creds.RoleARN, creds.Expiration.UTC().Format(time.RFC3339)),
+	)
+}
+
+// setClusterCondition sets a condition of the ClusterAWSIAMRole observed for
+// its current generation.
+func setClusterCondition(clusterAWSIAMRole *av1.ClusterAWSIAMRole, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&clusterAWSIAMRole.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: clusterAWSIAMRole.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// setClusterReadyConditions derives the SecretSynced, Ready and Degraded
+// conditions of the ClusterAWSIAMRole. It's Ready while the secrets in all
+// selected namespaces hold unexpired credentials issued for the current
+// generation.
+func setClusterReadyConditions(clusterAWSIAMRole *av1.ClusterAWSIAMRole, now time.Time) {
+	status := &clusterAWSIAMRole.Status
+
+	var unsynced []string
+	for _, namespace := range status.Namespaces {
+		if !namespace.Synced {
+			unsynced = append(unsynced, namespace.Namespace)
+		}
+	}
+
+	if len(unsynced) > 0 {
+		setClusterCondition(clusterAWSIAMRole, conditionSecretSynced, metav1.ConditionFalse, reasonSyncFailed,
+			fmt.Sprintf("Failed to store the credentials in %d of %d namespaces: %s", len(unsynced), len(status.Namespaces), strings.Join(unsynced, ", ")),
+		)
+	} else {
+		setClusterCondition(clusterAWSIAMRole, conditionSecretSynced, metav1.ConditionTrue, reasonSynced,
+			fmt.Sprintf("Stored the credentials in %d namespaces", len(status.Namespaces)),
+		)
+	}
+
+	ready := metav1.ConditionFalse
+	var reason, message string
+	switch {
+	case len(status.Namespaces) == 0:
+		reason = reasonNoNamespacesSelected
+		message = "The namespace selector doesn't select any namespace"
+	case status.Expiration == nil:
+		reason = reasonCredentialsUnavailable
+		message = "No credentials were issued yet"
+	case !now.Before(status.Expiration.Time):
+		reason = reasonCredentialsExpired
+		message = fmt.Sprintf("Credentials expired at %s", status.Expiration.UTC().Format(time.RFC3339))
+	case status.ObservedGeneration == nil || *status.ObservedGeneration != clusterAWSIAMRole.Generation:
+		reason = reasonCredentialsOutdated
+		message = "Credentials were issued for a previous generation of the ClusterAWSIAMRole"
+	case len(unsynced) > 0:
+		reason = reasonSyncFailed
+		message = fmt.Sprintf("Credentials are not available in namespaces: %s", strings.Join(unsynced, ", "))
+	default:
+		ready = metav1.ConditionTrue
+		reason = reasonCredentialsAvailable
+		message = fmt.Sprintf("Credentials for role '%s' are available in %d namespaces", status.RoleARN, len(status.Namespaces))
+	}
+	setClusterCondition(clusterAWSIAMRole, conditionReady, ready, reason, message)
+
+	degraded := metav1.ConditionFalse
+	reason = reasonAsExpected
+	message = "Credentials are issued and synced to the secrets"
+	for _, conditionType := range []string{conditionCredentialsIssued, conditionSecretSynced} {
+		condition := meta.FindStatusCondition(status.Conditions, conditionType)
+		if condition != nil && condition.Status == metav1.ConditionFalse {
+			degraded = metav1.ConditionTrue
+			reason = condition.Reason
+			message = condition.Message
+			break
+		}
+	}
+	setClusterCondition(clusterAWSIAMRole, conditionDegraded, degraded, reason, message)
+}
+
+// awsIAMRoleTemplate returns the AWSIAMRole equivalent to the
+// ClusterAWSIAMRole. It has no namespace and is used to derive the session
+// parameters and to track the state of the circuit breaker.
+func awsIAMRoleTemplate(clusterAWSIAMRole *av1.ClusterAWSIAMRole) *av1.AWSIAMRole {
+	awsIAMRole := &av1.AWSIAMRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       clusterAWSIAMRole.Name,
+			UID:        clusterAWSIAMRole.UID,
+			Generation: clusterAWSIAMRole.Generation,
+			Labels:     clusterAWSIAMRole.Labels,
+		},
+		Spec: av1.AWSIAMRoleSpec{
+			RoleReference:       clusterAWSIAMRole.Spec.RoleReference,
+			RoleSessionDuration: clusterAWSIAMRole.Spec.RoleSessionDuration,
+			ExternalID:          clusterAWSIAMRole.Spec.ExternalID,
+			PolicyARNs:          clusterAWSIAMRole.Spec.PolicyARNs,
+			AssumeRoleChain:     clusterAWSIAMRole.Spec.AssumeRoleChain,
+			SessionName:         clusterAWSIAMRole.Spec.SessionName,
+			SessionTags:         clusterAWSIAMRole.Spec.SessionTags,
+			SecretFormats:       clusterAWSIAMRole.Spec.SecretFormats,
+		},
+	}
+
+	if policy := clusterAWSIAMRole.Spec.SessionPolicy; policy != nil {
+		awsIAMRole.Spec.SessionPolicy = &av1.SessionPolicy{Inline: policy.Inline}
+	}
+
+	return awsIAMRole
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/require"
+	av1 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v1"
+	fakeAWS "github.com/zalando-incubator/kube-aws-iam-controller/pkg/client/clientset/versioned/fake"
+	"github.com/zalando-incubator/kube-aws-iam-controller/pkg/clientset"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	fakeKube "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func testNamespace(name string, labels map[string]string) *v1.Namespace {
+	return &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+	}
+}
+
+func testClusterAWSIAMRole() *av1.ClusterAWSIAMRole {
+	return &av1.ClusterAWSIAMRole{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "zalando.org/v1",
+			Kind:       "ClusterAWSIAMRole",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "log-shipper",
+			UID:        types.UID("1234"),
+			Generation: 1,
+		},
+		Spec: av1.ClusterAWSIAMRoleSpec{
+			NamespaceSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{"logs": "enabled"},
+			},
+			RoleReference: "log-shipper",
+		},
+	}
+}
+
+func TestRefreshClusterAWSIAMRole(t *testing.T) {
+	kubeClient := fakeKube.NewSimpleClientset(
+		testNamespace("a", map[string]string{"logs": "enabled"}),
+		testNamespace("b", map[string]string{"logs": "enabled"}),
+		testNamespace("c", nil),
+	)
+	client := clientset.NewClientset(kubeClient, fakeAWS.NewSimpleClientset())
+	_, err := client.ZalandoV1().ClusterAWSIAMRoles().Create(context.TODO(), testClusterAWSIAMRole(), metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	getter := &countingCredsGetter{lifetime: time.Hour}
+	controller := NewClusterAWSIAMRoleController(client, time.Minute, 15*time.Minute, getter, "", nil, nil, nil)
+	controller.recorder = record.NewFakeRecorder(100)
+
+	requireSecrets := func(namespaces ...string) map[string]v1.Secret {
+		secrets, err := client.CoreV1().Secrets("").List(context.TODO(), metav1.ListOptions{})
+		require.NoError(t, err)
+
+		secretsMap := make(map[string]v1.Secret, len(secrets.Items))
+		for _, secret := range secrets.Items {
+			require.Equal(t, "log-shipper", secret.Name)
+			secretsMap[secret.Namespace] = secret
+		}
+		require.Len(t, secretsMap, len(namespaces))
+		for _, namespace := range namespaces {
+			require.Contains(t, secretsMap, namespace)
+		}
+		return secretsMap
+	}
+
+	getClusterAWSIAMRole := func() *av1.ClusterAWSIAMRole {
+		clusterAWSIAMRole, err := client.ZalandoV1().ClusterAWSIAMRoles().Get(context.TODO(), "log-shipper", metav1.GetOptions{})
+		require.NoError(t, err)
+		return clusterAWSIAMRole
+	}
+
+	// the role is assumed once for all selected namespaces.
+	require.NoError(t, controller.refresh(context.TODO()))
+	require.EqualValues(t, 1, getter.calls)
+	secrets := requireSecrets("a", "b")
+	require.Equal(t, secrets["a"].Data, secrets["b"].Data)
+	require.Equal(t, "1234", string(secrets["a"].OwnerReferences[0].UID))
+
+	clusterAWSIAMRole := getClusterAWSIAMRole()
+	require.Len(t, clusterAWSIAMRole.Status.Namespaces, 2)
+	for _, status := range clusterAWSIAMRole.Status.Namespaces {
+		require.True(t, status.Synced)
+		require.NotNil(t, status.LastSyncTime)
+	}
+	require.True(t, meta.IsStatusConditionTrue(clusterAWSIAMRole.Status.Conditions, conditionReady))
+	require.NotNil(t, clusterAWSIAMRole.Status.Expiration)
+
+	// nothing changes while the credentials are valid.
+	require.NoError(t, controller.refresh(context.TODO()))
+	require.EqualValues(t, 1, getter.calls)
+
+	// newly selected namespaces get the current credentials.
+	_, err = kubeClient.CoreV1().Namespaces().Update(context.TODO(), testNamespace("c", map[string]string{"logs": "enabled"}), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, controller.refresh(context.TODO()))
+	require.EqualValues(t, 1, getter.calls)
+	secrets = requireSecrets("a", "b", "c")
+	require.Equal(t, secrets["a"].Data, secrets["c"].Data)
+
+	// secrets are removed from namespaces no longer selected.
+	_, err = kubeClient.CoreV1().Namespaces().Update(context.TODO(), testNamespace("b", nil), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, controller.refresh(context.TODO()))
+	requireSecrets("a", "c")
+	require.Len(t, getClusterAWSIAMRole().Status.Namespaces, 2)
+
+	// a new generation is assumed again and stored in all namespaces.
+	clusterAWSIAMRole = getClusterAWSIAMRole()
+	clusterAWSIAMRole.Generation = 2
+	_, err = client.ZalandoV1().ClusterAWSIAMRoles().Update(context.TODO(), clusterAWSIAMRole, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, controller.refresh(context.TODO()))
+	require.EqualValues(t, 2, getter.calls)
+	secrets = requireSecrets("a", "c")
+	require.Equal(t, "2", string(secrets["a"].Data[awsIAMRoleGenerationKey]))
+	require.Equal(t, "2", string(secrets["c"].Data[awsIAMRoleGenerationKey]))
+	require.EqualValues(t, 2, *getClusterAWSIAMRole().Status.ObservedGeneration)
+
+	// secrets of deleted ClusterAWSIAMRoles are removed.
+	require.NoError(t, client.ZalandoV1().ClusterAWSIAMRoles().Delete(context.TODO(), "log-shipper", metav1.DeleteOptions{}))
+	require.NoError(t, controller.refresh(context.TODO()))
+	requireSecrets()
+}
+
+func TestRefreshClusterAWSIAMRoleFailure(t *testing.T) {
+	kubeClient := fakeKube.NewSimpleClientset(
+		testNamespace("a", map[string]string{"logs": "enabled"}),
+	)
+	client := clientset.NewClientset(kubeClient, fakeAWS.NewSimpleClientset())
+	_, err := client.ZalandoV1().ClusterAWSIAMRoles().Create(context.TODO(), testClusterAWSIAMRole(), metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	getter := &countingCredsGetter{err: &smithy.GenericAPIError{Code: "AccessDenied"}}
+	controller := NewClusterAWSIAMRoleController(client, time.Minute, 15*time.Minute, getter, "", nil, nil, nil)
+	controller.recorder = record.NewFakeRecorder(100)
+	require.NoError(t, controller.refresh(context.TODO()))
+
+	clusterAWSIAMRole, err := client.ZalandoV1().ClusterAWSIAMRoles().Get(context.TODO(), "log-shipper", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []av1.NamespaceSyncStatus{
+		{Namespace: "a", Message: "No credentials are available for the role"},
+	}, clusterAWSIAMRole.Status.Namespaces)
+	require.Equal(t, failureReasonAccessDenied, clusterAWSIAMRole.Status.LastFailure.Reason)
+
+	for conditionType, reason := range map[string]string{
+		conditionReady:             reasonCredentialsUnavailable,
+		conditionCredentialsIssued: failureReasonAccessDenied,
+		conditionSecretSynced:      reasonSyncFailed,
+	} {
+		condition := meta.FindStatusCondition(clusterAWSIAMRole.Status.Conditions, conditionType)
+		require.NotNil(t, condition, conditionType)
+		require.Equal(t, metav1.ConditionFalse, condition.Status, conditionType)
+		require.Equal(t, reason, condition.Reason, conditionType)
+	}
+	require.True(t, meta.IsStatusConditionTrue(clusterAWSIAMRole.Status.Conditions, conditionDegraded))
+}
+
+func TestRefreshClusterAWSIAMRoleBaseRoleMapping(t *testing.T) {
+	kubeClient := fakeKube.NewSimpleClientset(
+		testNamespace("a", map[string]string{"logs": "enabled"}),
+		testNamespace("finance", map[string]string{"logs": "enabled", "business-unit": "finance"}),
+	)
+	client := clientset.NewClientset(kubeClient, fakeAWS.NewSimpleClientset())
+	_, err := client.ZalandoV1().ClusterAWSIAMRoles().Create(context.TODO(), testClusterAWSIAMRole(), metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	getter := NewMappingCredentialsGetter(kubeClient, "000000000000", &countingCredsGetter{lifetime: time.Hour})
+	require.NoError(t, getter.Add(BaseRoleMapping{
+		Name: "finance",
+		NamespaceSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"business-unit": "finance"},
+		},
+	}, namedCredsGetter("finance")))
+
+	controller := NewClusterAWSIAMRoleController(client, time.Minute, 15*time.Minute, getter, "", nil, nil, nil)
+	recorder := record.NewFakeRecorder(100)
+	controller.recorder = recorder
+	require.NoError(t, controller.refresh(context.TODO()))
+
+	// the namespace mapped to a different source role doesn't get the
+	// credentials issued with the default source role.
+	secrets, err := client.CoreV1().Secrets("").List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, secrets.Items, 1)
+	require.Equal(t, "a", secrets.Items[0].Namespace)
+
+	clusterAWSIAMRole, err := client.ZalandoV1().ClusterAWSIAMRoles().Get(context.TODO(), "log-shipper", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Len(t, clusterAWSIAMRole.Status.Namespaces, 2)
+	require.True(t, clusterAWSIAMRole.Status.Namespaces[0].Synced)
+	require.Equal(t, av1.NamespaceSyncStatus{
+		Namespace: "finance",
+		Message:   "The namespace is matched by base role mapping 'finance', which doesn't apply to ClusterAWSIAMRoles",
+	}, clusterAWSIAMRole.Status.Namespaces[1])
+	require.False(t, meta.IsStatusConditionTrue(clusterAWSIAMRole.Status.Conditions, conditionSecretSynced))
+	require.Contains(t, <-recorder.Events, reasonBaseRoleMappingMismatch)
+}
+
+func TestRefreshClusterAWSIAMRoleSecretConflict(t *testing.T) {
+	kubeClient := fakeKube.NewSimpleClientset(
+		testNamespace("a", map[string]string{"logs": "enabled"}),
+		testNamespace("b", map[string]string{"logs": "enabled"}),
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "log-shipper",
+				Namespace: "b",
+			},
+			Data: map[string][]byte{"key": []byte("value")},
+		},
+	)
+	client := clientset.NewClientset(kubeClient, fakeAWS.NewSimpleClientset())
+	_, err := client.ZalandoV1().ClusterAWSIAMRoles().Create(context.TODO(), testClusterAWSIAMRole(), metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	controller := NewClusterAWSIAMRoleController(client, time.Minute, 15*time.Minute, &countingCredsGetter{lifetime: time.Hour}, "", nil, nil, nil)
+	recorder := record.NewFakeRecorder(100)
+	controller.recorder = recorder
+	require.NoError(t, controller.refresh(context.TODO()))
+
+	// the existing secret is kept as is.
+	secret, err := client.CoreV1().Secrets("b").Get(context.TODO(), "log-shipper", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]byte{"key": []byte("value")}, secret.Data)
+
+	clusterAWSIAMRole, err := client.ZalandoV1().ClusterAWSIAMRoles().Get(context.TODO(), "log-shipper", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Len(t, clusterAWSIAMRole.Status.Namespaces, 2)
+	require.True(t, clusterAWSIAMRole.Status.Namespaces[0].Synced)
+	require.Equal(t, av1.NamespaceSyncStatus{
+		Namespace: "b",
+		Message:   "Secret log-shipper already exists and is not owned by the ClusterAWSIAMRole",
+	}, clusterAWSIAMRole.Status.Namespaces[1])
+
+	condition := meta.FindStatusCondition(clusterAWSIAMRole.Status.Conditions, conditionSecretSynced)
+	require.NotNil(t, condition)
+	require.Equal(t, metav1.ConditionFalse, condition.Status)
+	require.Contains(t, condition.Message, "b")
+
+	var conflicts []string
+	for len(recorder.Events) > 0 {
+		event := <-recorder.Events
+		if strings.Contains(event, reasonSecretConflict) {
+			conflicts = append(conflicts, event)
+		}
+	}
+	require.Equal(t, []string{"Warning SecretConflict Secret log-shipper already exists and is not owned by the ClusterAWSIAMRole in namespaces: b"}, conflicts)
+}
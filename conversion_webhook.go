@@ -0,0 +1,165 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	av1 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v1"
+	av2 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v2"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	awsIAMRoleKind = "AWSIAMRole"
+
+	conversionWebhookPath = "/convert"
+	// conversionReviewMaxSize limits the size of conversion requests. The
+	// API server limits requests to 3MiB.
+	conversionReviewMaxSize = 4 << 20
+)
+
+// ConversionWebhook is the conversion webhook of the AWSIAMRole custom
+// resource definition. It converts AWSIAMRoles between the served versions
+// via v1, which is the storage version.
+type ConversionWebhook struct{}
+
+// ServeHTTP handles ConversionReview requests of the API server.
+func (w *ConversionWebhook) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var review apiextensionsv1.ConversionReview
+	err := json.NewDecoder(http.MaxBytesReader(rw, req.Body, conversionReviewMaxSize)).Decode(&review)
+	if err != nil {
+		http.Error(rw, fmt.Sprintf("failed to decode ConversionReview: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if review.Request == nil {
+		http.Error(rw, "ConversionReview has no request", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = convertAWSIAMRoles(review.Request)
+	review.Request = nil
+
+	rw.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(rw).Encode(&review)
+	if err != nil {
+		log.Errorf("Failed to write ConversionReview response: %v", err)
+	}
+}
+
+// convertAWSIAMRoles converts the AWSIAMRoles of a conversion request to the
+// desired version. The conversion fails if any of them can't be converted.
+func convertAWSIAMRoles(req *apiextensionsv1.ConversionRequest) *apiextensionsv1.ConversionResponse {
+	resp := &apiextensionsv1.ConversionResponse{
+		UID:              req.UID,
+		ConvertedObjects: make([]runtime.RawExtension, 0, len(req.Objects)),
+		Result:           metav1.Status{Status: metav1.StatusSuccess},
+	}
+
+	for _, object := range req.Objects {
+		converted, err := convertAWSIAMRole(object.Raw, req.DesiredAPIVersion)
+		if err != nil {
+			log.Warnf("Failed to convert AWSIAMRole to %s: %v", req.DesiredAPIVersion, err)
+			return &apiextensionsv1.ConversionResponse{
+				UID: req.UID,
+				Result: metav1.Status{
+					Status:  metav1.StatusFailure,
+					Message: err.Error(),
+				},
+			}
+		}
+		resp.ConvertedObjects = append(resp.ConvertedObjects, runtime.RawExtension{Raw: converted})
+	}
+
+	return resp
+}
+
+// convertAWSIAMRole converts a JSON encoded AWSIAMRole to the desired
+// version.
+func convertAWSIAMRole(raw []byte, desiredAPIVersion string) ([]byte, error) {
+	var typeMeta metav1.TypeMeta
+	err := json.Unmarshal(raw, &typeMeta)
+	if err != nil {
+		return nil, err
+	}
+
+	if typeMeta.Kind != awsIAMRoleKind {
+		return nil, fmt.Errorf("unsupported kind '%s'", typeMeta.Kind)
+	}
+
+	hub := &av1.AWSIAMRole{}
+	switch typeMeta.APIVersion {
+	case av1.SchemeGroupVersion.String():
+		err = json.Unmarshal(raw, hub)
+		if err != nil {
+			return nil, err
+		}
+	case av2.SchemeGroupVersion.String():
+		awsIAMRole := &av2.AWSIAMRole{}
+		err = json.Unmarshal(raw, awsIAMRole)
+		if err != nil {
+			return nil, err
+		}
+
+		err = awsIAMRole.ConvertTo(hub)
+		if err != nil {
+			return nil, fmt.Errorf("AWSIAMRole %s/%s: %w", awsIAMRole.Namespace, awsIAMRole.Name, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported version '%s'", typeMeta.APIVersion)
+	}
+
+	var converted any
+	switch desiredAPIVersion {
+	case av1.SchemeGroupVersion.String():
+		hub.TypeMeta = metav1.TypeMeta{APIVersion: desiredAPIVersion, Kind: awsIAMRoleKind}
+		converted = hub
+	case av2.SchemeGroupVersion.String():
+		awsIAMRole := &av2.AWSIAMRole{}
+		err = awsIAMRole.ConvertFrom(hub)
+		if err != nil {
+			return nil, fmt.Errorf("AWSIAMRole %s/%s: %w", hub.Namespace, hub.Name, err)
+		}
+		awsIAMRole.TypeMeta = metav1.TypeMeta{APIVersion: desiredAPIVersion, Kind: awsIAMRoleKind}
+		converted = awsIAMRole
+	default:
+		return nil, fmt.Errorf("unsupported version '%s'", desiredAPIVersion)
+	}
+
+	return json.Marshal(converted)
+}
+
+// newConversionWebhookServer initializes the HTTPS server of the conversion
+// webhook. The certificate is read again on every TLS handshake so it can
+// be rotated without restarting the controller.
+func newConversionWebhookServer(address, certFile, keyFile string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle(conversionWebhookPath, &ConversionWebhook{})
+
+	return &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+				cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+				if err != nil {
+					return nil, fmt.Errorf("failed to load conversion webhook certificate: %w", err)
+				}
+				return &cert, nil
+			},
+		},
+	}
+}
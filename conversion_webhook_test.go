@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	av1 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v1"
+	av2 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v2"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func testV1AWSIAMRole() *av1.AWSIAMRole {
+	generation := int64(3)
+	expiration := metav1.NewTime(time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC).Local())
+	return &av1.AWSIAMRole{
+		TypeMeta: metav1.TypeMeta{APIVersion: "zalando.org/v1", Kind: "AWSIAMRole"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "app",
+			Namespace:  "default",
+			UID:        types.UID("1234"),
+			Generation: generation,
+			Labels:     map[string]string{"application": "app"},
+		},
+		Spec: av1.AWSIAMRoleSpec{
+			RoleReference:       "arn:aws:iam::123456789012:role/app",
+			RoleSessionDuration: 7200,
+			ExternalIDSecretRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "external-id"},
+				Key:                  "id",
+			},
+			SessionPolicy:   &av1.SessionPolicy{Inline: `{"Version":"2012-10-17"}`},
+			PolicyARNs:      []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"},
+			AssumeRoleChain: []string{"hub"},
+			SessionName:     "worker",
+			SessionTags:     []av1.SessionTag{{Key: "project", Value: "billing", Transitive: true}},
+			SecretFormats:   []string{"credentials.process"},
+			SecretTemplate: &av1.SecretTemplate{
+				Name:        "app-aws-credentials",
+				Annotations: map[string]string{"reloader.stakater.com/match": "true"},
+			},
+		},
+		Status: av1.AWSIAMRoleStatus{
+			ObservedGeneration: &generation,
+			RoleARN:            "arn:aws:iam::123456789012:role/app",
+			Expiration:         &expiration,
+			LastFailure:        &av1.FailureStatus{Reason: failureReasonThrottled, Time: expiration},
+			Conditions: []metav1.Condition{
+				{Type: conditionReady, Status: metav1.ConditionTrue, Reason: reasonCredentialsAvailable, LastTransitionTime: expiration},
+			},
+		},
+	}
+}
+
+func TestConvertAWSIAMRole(t *testing.T) {
+	original := testV1AWSIAMRole()
+	raw, err := json.Marshal(original)
+	require.NoError(t, err)
+
+	converted, err := convertAWSIAMRole(raw, "zalando.org/v2")
+	require.NoError(t, err)
+
+	var awsIAMRole av2.AWSIAMRole
+	require.NoError(t, json.Unmarshal(converted, &awsIAMRole))
+	require.Equal(t, "zalando.org/v2", awsIAMRole.APIVersion)
+	require.Equal(t, original.ObjectMeta, awsIAMRole.ObjectMeta)
+	require.Equal(t, av2.AWSIAMRoleSpec{
+		Role:            av2.RoleReference{ARN: "arn:aws:iam::123456789012:role/app"},
+		AssumeRoleChain: []av2.RoleReference{{Name: "hub"}},
+		Session: &av2.SessionSpec{
+			Duration:   &metav1.Duration{Duration: 2 * time.Hour},
+			Name:       "worker",
+			ExternalID: &av2.ExternalID{SecretKeyRef: original.Spec.ExternalIDSecretRef},
+			Policy: &av2.SessionPolicy{
+				Inline: `{"Version":"2012-10-17"}`,
+				ARNs:   []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"},
+			},
+			Tags: []av2.SessionTag{{Key: "project", Value: "billing", Transitive: true}},
+		},
+		Secret: &av2.SecretSpec{
+			Name:        "app-aws-credentials",
+			Annotations: map[string]string{"reloader.stakater.com/match": "true"},
+			Formats:     []string{"credentials.process"},
+		},
+	}, awsIAMRole.Spec)
+	require.Equal(t, failureReasonThrottled, awsIAMRole.Status.LastFailure.Reason)
+	require.Len(t, awsIAMRole.Status.Conditions, 1)
+
+	// converting back to v1 is lossless.
+	roundTrip, err := convertAWSIAMRole(converted, "zalando.org/v1")
+	require.NoError(t, err)
+
+	var result av1.AWSIAMRole
+	require.NoError(t, json.Unmarshal(roundTrip, &result))
+	require.Equal(t, original, &result)
+}
+
+func TestConvertAWSIAMRoleSessionDuration(t *testing.T) {
+	original := `{"apiVersion":"zalando.org/v2","kind":"AWSIAMRole","spec":{"role":{"name":"app"},"session":{"duration":"1h30m"}}}`
+
+	converted, err := convertAWSIAMRole([]byte(original), "zalando.org/v1")
+	require.NoError(t, err)
+
+	var v1AWSIAMRole av1.AWSIAMRole
+	require.NoError(t, json.Unmarshal(converted, &v1AWSIAMRole))
+	require.EqualValues(t, 5400, v1AWSIAMRole.Spec.RoleSessionDuration)
+
+	roundTrip, err := convertAWSIAMRole(converted, "zalando.org/v2")
+	require.NoError(t, err)
+
+	var awsIAMRole av2.AWSIAMRole
+	require.NoError(t, json.Unmarshal(roundTrip, &awsIAMRole))
+	require.Equal(t, &metav1.Duration{Duration: 90 * time.Minute}, awsIAMRole.Spec.Session.Duration)
+}
+
+func TestConvertAWSIAMRoleErrors(tt *testing.T) {
+	for _, tc := range []struct {
+		msg               string
+		object            string
+		desiredAPIVersion string
+	}{
+		{
+			msg:               "role name and arn",
+			object:            `{"apiVersion":"zalando.org/v2","kind":"AWSIAMRole","spec":{"role":{"name":"app","arn":"arn:aws:iam::123456789012:role/app"}}}`,
+			desiredAPIVersion: "zalando.org/v1",
+		},
+		{
+			msg:               "no role",
+			object:            `{"apiVersion":"zalando.org/v2","kind":"AWSIAMRole","spec":{"role":{}}}`,
+			desiredAPIVersion: "zalando.org/v1",
+		},
+		{
+			msg:               "invalid role arn",
+			object:            `{"apiVersion":"zalando.org/v2","kind":"AWSIAMRole","spec":{"role":{"arn":"app"}}}`,
+			desiredAPIVersion: "zalando.org/v1",
+		},
+		{
+			msg:               "fractional session duration",
+			object:            `{"apiVersion":"zalando.org/v2","kind":"AWSIAMRole","spec":{"role":{"name":"app"},"session":{"duration":"1h0m0.5s"}}}`,
+			desiredAPIVersion: "zalando.org/v1",
+		},
+		{
+			msg:               "unsupported kind",
+			object:            `{"apiVersion":"zalando.org/v1","kind":"Secret"}`,
+			desiredAPIVersion: "zalando.org/v2",
+		},
+		{
+			msg:               "unsupported desired version",
+			object:            `{"apiVersion":"zalando.org/v1","kind":"AWSIAMRole","spec":{"roleReference":"app"}}`,
+			desiredAPIVersion: "zalando.org/v3",
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			_, err := convertAWSIAMRole([]byte(tc.object), tc.desiredAPIVersion)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestConversionWebhook(t *testing.T) {
+	raw, err := json.Marshal(testV1AWSIAMRole())
+	require.NoError(t, err)
+
+	review := apiextensionsv1.ConversionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apiextensions.k8s.io/v1", Kind: "ConversionReview"},
+		Request: &apiextensionsv1.ConversionRequest{
+			UID:               types.UID("review"),
+			DesiredAPIVersion: "zalando.org/v2",
+			Objects:           []runtime.RawExtension{{Raw: raw}},
+		},
+	}
+
+	serve := func(review apiextensionsv1.ConversionReview) *apiextensionsv1.ConversionResponse {
+		body, err := json.Marshal(review)
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		(&ConversionWebhook{}).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, conversionWebhookPath, bytes.NewReader(body)))
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var result apiextensionsv1.ConversionReview
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+		require.Nil(t, result.Request)
+		require.NotNil(t, result.Response)
+		require.Equal(t, types.UID("review"), result.Response.UID)
+		return result.Response
+	}
+
+	resp := serve(review)
+	require.Equal(t, metav1.StatusSuccess, resp.Result.Status)
+	require.Len(t, resp.ConvertedObjects, 1)
+
+	var awsIAMRole av2.AWSIAMRole
+	require.NoError(t, json.Unmarshal(resp.ConvertedObjects[0].Raw, &awsIAMRole))
+	require.Equal(t, "zalando.org/v2", awsIAMRole.APIVersion)
+	require.Equal(t, "app", awsIAMRole.Name)
+
+	// a single invalid object fails the conversion.
+	review.Request.DesiredAPIVersion = "zalando.org/v1"
+	review.Request.Objects = append(review.Request.Objects, runtime.RawExtension{
+		Raw: []byte(`{"apiVersion":"zalando.org/v2","kind":"AWSIAMRole","metadata":{"name":"invalid"},"spec":{"role":{}}}`),
+	})
+	resp = serve(review)
+	require.Equal(t, metav1.StatusFailure, resp.Result.Status)
+	require.Contains(t, resp.Result.Message, "invalid")
+	require.Empty(t, resp.ConvertedObjects)
+
+	recorder := httptest.NewRecorder()
+	(&ConversionWebhook{}).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, conversionWebhookPath, nil))
+	require.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
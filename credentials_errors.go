@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// errorClass classifies errors returned when getting credentials.
+type errorClass int
+
+const (
+	// errorClassPermanent are errors which won't go away by retrying e.g.
+	// AccessDenied or an invalid role ARN.
+	errorClassPermanent errorClass = iota
+	// errorClassTransient are errors which are likely to go away by
+	// retrying e.g. connection errors or server errors.
+	errorClassTransient
+	// errorClassThrottling are errors returned when requests are being
+	// throttled.
+	errorClassThrottling
+)
+
+// Failure reasons are stable machine-readable reasons of failures to get
+// credentials stored in the status of an AWSIAMRole.
+const (
+	failureReasonAccessDenied           = "AccessDenied"
+	failureReasonRoleNotFound           = "RoleNotFound"
+	failureReasonThrottled              = "Throttled"
+	failureReasonRegionDisabled         = "RegionDisabled"
+	failureReasonInvalidDuration        = "InvalidDuration"
+	failureReasonExpiredBaseCredentials = "ExpiredBaseCredentials"
+	failureReasonNetworkError           = "NetworkError"
+	failureReasonVerificationFailed     = "VerificationFailed"
+	failureReasonUnknown                = "Unknown"
+)
+
+var (
+	throttlingErrorCodes = map[string]struct{}{
+		"Throttling":                             {},
+		"ThrottlingException":                    {},
+		"ThrottledException":                     {},
+		"RequestThrottled":                       {},
+		"RequestThrottledException":              {},
+		"RequestLimitExceeded":                   {},
+		"TooManyRequestsException":               {},
+		"ProvisionedThroughputExceededException": {},
+		"SlowDown":                               {},
+	}
+
+	transientErrorCodes = map[string]struct{}{
+		"IDPCommunicationError":   {},
+		"InternalError":           {},
+		"InternalFailure":         {},
+		"ServiceUnavailable":      {},
+		"RequestTimeout":          {},
+		"RequestTimeoutException": {},
+	}
+
+	failureReasonErrorCodes = map[string]string{
+		"AccessDenied":                failureReasonAccessDenied,
+		"AccessDeniedException":       failureReasonAccessDenied,
+		"NoSuchEntity":                failureReasonRoleNotFound,
+		"NoSuchEntityException":       failureReasonRoleNotFound,
+		"RegionDisabledException":     failureReasonRegionDisabled,
+		"ExpiredToken":                failureReasonExpiredBaseCredentials,
+		"ExpiredTokenException":       failureReasonExpiredBaseCredentials,
+		"InvalidClientTokenId":        failureReasonExpiredBaseCredentials,
+		"UnrecognizedClientException": failureReasonExpiredBaseCredentials,
+	}
+)
+
+// httpStatusError is an error of an HTTP request with the status code of the
+// response e.g. *smithyhttp.ResponseError.
+type httpStatusError interface {
+	error
+	HTTPStatusCode() int
+}
+
+var _ httpStatusError = &smithyhttp.ResponseError{}
+
+// String returns a human readable name of the error class.
+func (c errorClass) String() string {
+	switch c {
+	case errorClassTransient:
+		return "transient"
+	case errorClassThrottling:
+		return "throttling"
+	default:
+		return "permanent"
+	}
+}
+
+// classifyError classifies an error returned when getting credentials.
+// Errors which are not known to be transient are considered permanent.
+// Network and transport errors are transient regardless of the backend.
+func classifyError(err error) errorClass {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		if _, ok := throttlingErrorCodes[apiErr.ErrorCode()]; ok {
+			return errorClassThrottling
+		}
+
+		if _, ok := transientErrorCodes[apiErr.ErrorCode()]; ok {
+			return errorClassTransient
+		}
+	}
+
+	var statusErr httpStatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.HTTPStatusCode() == http.StatusTooManyRequests:
+			return errorClassThrottling
+		case statusErr.HTTPStatusCode() >= http.StatusInternalServerError:
+			return errorClassTransient
+		}
+		return errorClassPermanent
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return errorClassTransient
+	}
+
+	// requests which failed to be sent or were canceled or timed out don't
+	// tell anything about the role.
+	var sendErr *smithyhttp.RequestSendError
+	var canceledErr *smithy.CanceledError
+	if errors.As(err, &sendErr) || errors.As(err, &canceledErr) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) {
+		return errorClassTransient
+	}
+
+	return errorClassPermanent
+}
+
+// failureReason returns the failure reason of an error returned when getting
+// credentials.
+func failureReason(err error) string {
+	switch {
+	case errors.Is(err, errBaseCredentialsUnavailable):
+		return failureReasonExpiredBaseCredentials
+	case errors.Is(err, errExternalIDRejected):
+		return failureReasonAccessDenied
+	case errors.Is(err, errCredentialsVerificationFailed):
+		return failureReasonVerificationFailed
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.ErrorCode() == "ValidationError" && strings.Contains(apiErr.ErrorMessage(), "Duration") {
+			return failureReasonInvalidDuration
+		}
+
+		if reason, ok := failureReasonErrorCodes[apiErr.ErrorCode()]; ok {
+			return reason
+		}
+	}
+
+	switch classifyError(err) {
+	case errorClassThrottling:
+		return failureReasonThrottled
+	case errorClassTransient:
+		return failureReasonNetworkError
+	}
+
+	var statusErr httpStatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.HTTPStatusCode() {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return failureReasonAccessDenied
+		case http.StatusNotFound:
+			return failureReasonRoleNotFound
+		}
+	}
+
+	return failureReasonUnknown
+}
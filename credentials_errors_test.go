@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/stretchr/testify/require"
+)
+
+func responseError(statusCode int, err error) error {
+	return &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: statusCode}},
+		Err:      err,
+	}
+}
+
+func TestClassifyError(tt *testing.T) {
+	for _, tc := range []struct {
+		msg   string
+		err   error
+		class errorClass
+	}{
+		{
+			msg:   "throttling error code",
+			err:   &smithy.GenericAPIError{Code: "Throttling"},
+			class: errorClassThrottling,
+		},
+		{
+			msg:   "wrapped throttling error code",
+			err:   fmt.Errorf("failed: %w", responseError(http.StatusBadRequest, &smithy.GenericAPIError{Code: "RequestLimitExceeded"})),
+			class: errorClassThrottling,
+		},
+		{
+			msg:   "too many requests",
+			err:   responseError(http.StatusTooManyRequests, errors.New("slow down")),
+			class: errorClassThrottling,
+		},
+		{
+			msg:   "server error",
+			err:   responseError(http.StatusServiceUnavailable, errors.New("unavailable")),
+			class: errorClassTransient,
+		},
+		{
+			msg:   "transient error code",
+			err:   &smithy.GenericAPIError{Code: "IDPCommunicationError"},
+			class: errorClassTransient,
+		},
+		{
+			msg:   "network error",
+			err:   &net.OpError{Op: "dial", Err: errors.New("connection refused")},
+			class: errorClassTransient,
+		},
+		{
+			msg:   "request send error",
+			err:   &smithyhttp.RequestSendError{Err: errors.New("connection reset")},
+			class: errorClassTransient,
+		},
+		{
+			msg:   "canceled request",
+			err:   &smithy.CanceledError{Err: context.Canceled},
+			class: errorClassTransient,
+		},
+		{
+			msg:   "context canceled",
+			err:   fmt.Errorf("failed to get credentials: %w", context.Canceled),
+			class: errorClassTransient,
+		},
+		{
+			msg:   "context deadline exceeded",
+			err:   fmt.Errorf("failed to get credentials: %w", context.DeadlineExceeded),
+			class: errorClassTransient,
+		},
+		{
+			msg:   "unexpected EOF",
+			err:   fmt.Errorf("failed to read response: %w", io.ErrUnexpectedEOF),
+			class: errorClassTransient,
+		},
+		{
+			msg:   "connection reset",
+			err:   fmt.Errorf("failed to read response: %w", syscall.ECONNRESET),
+			class: errorClassTransient,
+		},
+		{
+			msg:   "access denied",
+			err:   responseError(http.StatusForbidden, &smithy.GenericAPIError{Code: "AccessDenied"}),
+			class: errorClassPermanent,
+		},
+		{
+			msg:   "invalid role ARN",
+			err:   fmt.Errorf("invalid role ARN '%s'", "arn:aws:foo"),
+			class: errorClassPermanent,
+		},
+		{
+			msg:   "external ID rejected",
+			err:   fmt.Errorf("%w: denied", errExternalIDRejected),
+			class: errorClassPermanent,
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			require.Equal(t, tc.class, classifyError(tc.err))
+		})
+	}
+}
+
+func TestFailureReason(tt *testing.T) {
+	for _, tc := range []struct {
+		msg    string
+		err    error
+		reason string
+	}{
+		{
+			msg:    "access denied",
+			err:    responseError(http.StatusForbidden, &smithy.GenericAPIError{Code: "AccessDenied"}),
+			reason: failureReasonAccessDenied,
+		},
+		{
+			msg:    "external ID rejected",
+			err:    fmt.Errorf("%w: denied", errExternalIDRejected),
+			reason: failureReasonAccessDenied,
+		},
+		{
+			msg:    "forbidden response",
+			err:    &vaultError{statusCode: http.StatusForbidden},
+			reason: failureReasonAccessDenied,
+		},
+		{
+			msg:    "missing entity",
+			err:    &smithy.GenericAPIError{Code: "NoSuchEntity"},
+			reason: failureReasonRoleNotFound,
+		},
+		{
+			msg:    "not found response",
+			err:    &vaultError{statusCode: http.StatusNotFound},
+			reason: failureReasonRoleNotFound,
+		},
+		{
+			msg:    "throttling",
+			err:    responseError(http.StatusBadRequest, &smithy.GenericAPIError{Code: "Throttling"}),
+			reason: failureReasonThrottled,
+		},
+		{
+			msg:    "region disabled",
+			err:    &smithy.GenericAPIError{Code: "RegionDisabledException"},
+			reason: failureReasonRegionDisabled,
+		},
+		{
+			msg:    "duration exceeds MaxSessionDuration",
+			err:    &smithy.GenericAPIError{Code: "ValidationError", Message: "The requested DurationSeconds exceeds the MaxSessionDuration set for this role."},
+			reason: failureReasonInvalidDuration,
+		},
+		{
+			msg:    "expired base credentials",
+			err:    &smithy.GenericAPIError{Code: "ExpiredToken"},
+			reason: failureReasonExpiredBaseCredentials,
+		},
+		{
+			msg:    "base credentials unavailable",
+			err:    fmt.Errorf("%w (expired): %w", errBaseCredentialsUnavailable, &smithy.GenericAPIError{Code: "AccessDenied"}),
+			reason: failureReasonExpiredBaseCredentials,
+		},
+		{
+			msg:    "verification failed",
+			err:    fmt.Errorf("%w for role 'arn:aws:iam::123456789012:role/app': %w", errCredentialsVerificationFailed, &smithy.GenericAPIError{Code: "InvalidClientTokenId"}),
+			reason: failureReasonVerificationFailed,
+		},
+		{
+			msg:    "network error",
+			err:    &net.OpError{Op: "dial", Err: errors.New("connection refused")},
+			reason: failureReasonNetworkError,
+		},
+		{
+			msg:    "server error",
+			err:    responseError(http.StatusServiceUnavailable, errors.New("unavailable")),
+			reason: failureReasonNetworkError,
+		},
+		{
+			msg:    "other validation error",
+			err:    &smithy.GenericAPIError{Code: "ValidationError", Message: "invalid session name"},
+			reason: failureReasonUnknown,
+		},
+		{
+			msg:    "unknown error",
+			err:    errors.New("failed"),
+			reason: failureReasonUnknown,
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			require.Equal(t, tc.reason, failureReason(tc.err))
+		})
+	}
+}
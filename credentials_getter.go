@@ -1,25 +1,97 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
+	arnPrefix              = "arn:"
 	roleARNSuffix          = ":role"
 	roleSessionNameMaxSize = 64
+	sessionNameMinSize     = 2
+	sessionPolicyMaxSize   = 2048
+	policyARNsMaxCount     = 10
+	// chainedSessionMaxDuration is the maximum session duration of roles
+	// assumed via role chaining.
+	chainedSessionMaxDuration = time.Hour
+	// chainCredentialsMinLifetime is the minimum remaining lifetime of
+	// cached intermediate credentials of a role chain.
+	chainCredentialsMinLifetime = 15 * time.Minute
+	// minMaxSessionDuration is the lowest MaxSessionDuration a role can
+	// have. It's used if the MaxSessionDuration of a role can't be read.
+	minMaxSessionDuration = time.Hour
+	// maxSessionDurationTTL is how long a negotiated MaxSessionDuration is
+	// used before the requested session duration is tried again.
+	maxSessionDurationTTL = time.Hour
+)
+
+var (
+	// invalidSessionNameChars matches characters not allowed in a
+	// RoleSessionName or SourceIdentity except for the path separator '/'
+	// according to:
+	// https://docs.aws.amazon.com/STS/latest/APIReference/API_AssumeRole.html
+	invalidSessionNameChars = regexp.MustCompile(`[^\w+=,.@/-]`)
+	// errExternalIDRejected is returned when STS denies an AssumeRole call
+	// which included an ExternalId.
+	errExternalIDRejected = errors.New("access denied, the ExternalId may not match the trust policy of the role")
+	// errPackedPolicyTooLarge is returned when the session policies and
+	// session tags exceed the packed size limit of STS.
+	errPackedPolicyTooLarge = errors.New("session policies and session tags exceed the packed size limit")
 )
 
 // CredentialsGetter can get credentials.
 type CredentialsGetter interface {
-	Get(ctx context.Context, role string, sessionDuration time.Duration) (*Credentials, error)
+	Get(ctx context.Context, role string, sessionDuration time.Duration, opts CredentialsOptions) (*Credentials, error)
+}
+
+// CredentialsOptions defines optional parameters used when getting
+// credentials for a role.
+type CredentialsOptions struct {
+	// ExternalID is passed as sts:ExternalId when assuming the role.
+	ExternalID string
+	// Tags are the session tags passed when assuming the role.
+	Tags []SessionTag
+	// Policy is an inline JSON session policy.
+	Policy string
+	// PolicyARNs are the ARNs of managed session policies.
+	PolicyARNs []string
+	// SourceIdentity is the source identity set on the session.
+	SourceIdentity string
+	// AssumeRoleChain is an ordered list of intermediate roles assumed
+	// before assuming the role.
+	AssumeRoleChain []string
+	// SessionName is a '/' separated RoleSessionName. If empty the
+	// RoleSessionName is derived from the role ARN.
+	SessionName string
+	// SessionNameSuffix is appended as the last level of the
+	// RoleSessionName.
+	SessionNameSuffix string
+	// Namespace is the namespace requesting the credentials. It's used for
+	// selecting the base role mapping and isn't passed to STS.
+	Namespace string `json:"-"`
 }
 
 // Credentials defines fetched credentials including expiration time.
@@ -29,50 +101,218 @@ type Credentials struct {
 	SecretAccessKey string
 	SessionToken    string
 	Expiration      time.Time
+	SessionDuration time.Duration
+	SessionTags     []SessionTag
+	// Endpoint is the URL of the endpoint which issued the credentials.
+	Endpoint string
 }
 
 type stsAPI interface {
 	AssumeRole(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error)
 }
 
+type callerIdentityAPI interface {
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
+// stsEndpoint is an STS client for a specific endpoint.
+type stsEndpoint struct {
+	url string
+	svc stsAPI
+}
+
 // STSCredentialsGetter is a credentials getter for getting credentials from
 // STS.
 type STSCredentialsGetter struct {
-	svc               stsAPI
+	endpoints         []stsEndpoint
+	iam               iamAPI
 	baseRoleARN       string
 	baseRoleARNPrefix string
+	chainCache        map[string]*Credentials
+	chainCacheMu      sync.Mutex
+	chainGroup        singleflight.Group
+	maxDurations      map[string]maxSessionDuration
+	maxDurationsMu    sync.Mutex
+}
+
+// maxSessionDuration is the negotiated MaxSessionDuration of a role.
+type maxSessionDuration struct {
+	duration time.Duration
+	expires  time.Time
 }
 
 // NewSTSCredentialsGetter initializes a new STS based credentials fetcher.
-func NewSTSCredentialsGetter(cfg aws.Config, baseRoleARN, baseRoleARNPrefix string, configs ...*aws.Config) *STSCredentialsGetter {
-	return &STSCredentialsGetter{
-		svc:               sts.NewFromConfig(cfg),
+// The endpoints are tried in order, falling over to the next endpoint on
+// connection errors or server errors. An empty endpoint uses the endpoint
+// resolved by the SDK for the configured region. If no endpoints are
+// specified only the endpoint resolved by the SDK is used.
+func NewSTSCredentialsGetter(cfg aws.Config, baseRoleARN, baseRoleARNPrefix string, endpoints ...string) *STSCredentialsGetter {
+	if len(endpoints) == 0 {
+		endpoints = []string{""}
+	}
+
+	getter := &STSCredentialsGetter{
+		iam:               iam.NewFromConfig(cfg),
 		baseRoleARN:       baseRoleARN,
 		baseRoleARNPrefix: baseRoleARNPrefix,
 	}
+
+	for _, endpoint := range endpoints {
+		if endpoint == "" {
+			getter.endpoints = append(getter.endpoints, stsEndpoint{
+				url: defaultSTSEndpoint(cfg.Region),
+				svc: sts.NewFromConfig(cfg),
+			})
+			continue
+		}
+
+		getter.endpoints = append(getter.endpoints, stsEndpoint{
+			url: endpoint,
+			svc: sts.NewFromConfig(cfg, WithSTSEndpoint(endpoint)),
+		})
+	}
+
+	return getter
+}
+
+// WithSTSEndpoint configures an STS client to use a custom endpoint URL.
+func WithSTSEndpoint(endpoint string) func(*sts.Options) {
+	return func(o *sts.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	}
+}
+
+// defaultSTSEndpoint returns the STS endpoint URL resolved by the SDK for the
+// region.
+func defaultSTSEndpoint(region string) string {
+	params := sts.EndpointParameters{Region: aws.String(region)}.WithDefaults()
+	endpoint, err := sts.NewDefaultEndpointResolverV2().ResolveEndpoint(context.Background(), params)
+	if err != nil {
+		return ""
+	}
+	return endpoint.URI.String()
+}
+
+// assumeRole calls AssumeRole on the configured endpoints in order until
+// one of them doesn't fail with a connection or server error. It returns the
+// URL of the endpoint which handled the request.
+func (c *STSCredentialsGetter) assumeRole(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, string, error) {
+	var err error
+	for _, endpoint := range c.endpoints {
+		var resp *sts.AssumeRoleOutput
+		resp, err = endpoint.svc.AssumeRole(ctx, params, optFns...)
+		if err == nil {
+			return resp, endpoint.url, nil
+		}
+
+		if !isEndpointFailure(err) || ctx.Err() != nil {
+			return nil, endpoint.url, err
+		}
+		log.Warnf("STS endpoint '%s' failed, trying next endpoint: %v", endpoint.url, err)
+	}
+	return nil, "", err
+}
+
+// isEndpointFailure returns true if the error indicates that the STS endpoint
+// is unavailable i.e. a connection error or a server error.
+func isEndpointFailure(err error) bool {
+	var respErr *smithyhttp.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.HTTPStatusCode() >= http.StatusInternalServerError
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
 }
 
 // Get gets new credentials for the specified role. The credentials are fetched
 // via STS.
-func (c *STSCredentialsGetter) Get(ctx context.Context, role string, sessionDuration time.Duration) (*Credentials, error) {
-	roleARN := c.baseRoleARN + role
-	if strings.HasPrefix(role, c.baseRoleARNPrefix) {
-		roleARN = role
+func (c *STSCredentialsGetter) Get(ctx context.Context, role string, sessionDuration time.Duration, opts CredentialsOptions) (*Credentials, error) {
+	roleARN, err := c.roleARN(role)
+	if err != nil {
+		return nil, err
 	}
 
-	roleSessionName, err := normalizeRoleARN(roleARN, c.baseRoleARNPrefix)
+	roleSessionName, err := c.roleSessionName(roleARN, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := compactSessionPolicy(opts.Policy, opts.PolicyARNs)
 	if err != nil {
 		return nil, err
 	}
 
+	var optFns []func(*sts.Options)
+	if len(opts.AssumeRoleChain) > 0 {
+		chainCreds, err := c.chainCredentials(ctx, opts.AssumeRoleChain, opts.SourceIdentity)
+		if err != nil {
+			return nil, err
+		}
+		optFns = append(optFns, withCredentials(chainCreds))
+
+		if sessionDuration > chainedSessionMaxDuration {
+			sessionDuration = chainedSessionMaxDuration
+		}
+	}
+
+	if maxDuration, ok := c.maxSessionDuration(roleARN); ok && sessionDuration > maxDuration {
+		sessionDuration = maxDuration
+	}
+
 	params := &sts.AssumeRoleInput{
 		RoleArn:         aws.String(roleARN),
 		RoleSessionName: aws.String(roleSessionName),
 		DurationSeconds: aws.Int32(int32(sessionDuration.Seconds())),
 	}
 
-	resp, err := c.svc.AssumeRole(ctx, params)
+	if opts.ExternalID != "" {
+		params.ExternalId = aws.String(opts.ExternalID)
+	}
+
+	if policy != "" {
+		params.Policy = aws.String(policy)
+	}
+
+	if opts.SourceIdentity != "" {
+		params.SourceIdentity = aws.String(opts.SourceIdentity)
+	}
+
+	for _, arn := range opts.PolicyARNs {
+		params.PolicyArns = append(params.PolicyArns, types.PolicyDescriptorType{
+			Arn: aws.String(arn),
+		})
+	}
+
+	for _, tag := range opts.Tags {
+		params.Tags = append(params.Tags, types.Tag{
+			Key:   aws.String(tag.Key),
+			Value: aws.String(tag.Value),
+		})
+		if tag.Transitive {
+			params.TransitiveTagKeys = append(params.TransitiveTagKeys, tag.Key)
+		}
+	}
+
+	resp, endpoint, err := c.assumeRole(ctx, params, optFns...)
+	if err != nil && isMaxSessionDurationExceeded(err) && sessionDuration > minMaxSessionDuration {
+		maxDuration := c.negotiateMaxSessionDuration(ctx, roleARN, sessionDuration)
+		log.Infof("Requested session duration %s exceeds the MaxSessionDuration of role '%s', retrying with %s", sessionDuration, roleARN, maxDuration)
+		sessionDuration = maxDuration
+		params.DurationSeconds = aws.Int32(int32(sessionDuration.Seconds()))
+		resp, endpoint, err = c.assumeRole(ctx, params, optFns...)
+	}
 	if err != nil {
+		if opts.ExternalID != "" && isAccessDenied(err) {
+			return nil, fmt.Errorf("%w: %w", errExternalIDRejected, err)
+		}
+
+		var tooLarge *types.PackedPolicyTooLargeException
+		if errors.As(err, &tooLarge) {
+			return nil, fmt.Errorf("%w: %w", errPackedPolicyTooLarge, err)
+		}
 		return nil, err
 	}
 
@@ -82,9 +322,253 @@ func (c *STSCredentialsGetter) Get(ctx context.Context, role string, sessionDura
 		SecretAccessKey: aws.ToString(resp.Credentials.SecretAccessKey),
 		SessionToken:    aws.ToString(resp.Credentials.SessionToken),
 		Expiration:      aws.ToTime(resp.Credentials.Expiration),
+		SessionDuration: sessionDuration,
+		SessionTags:     opts.Tags,
+		Endpoint:        endpoint,
 	}, nil
 }
 
+// roleSessionName returns the RoleSessionName used when assuming the role.
+// Without a session name in the options it's derived from the role ARN.
+func (c *STSCredentialsGetter) roleSessionName(roleARN string, opts CredentialsOptions) (string, error) {
+	if opts.SessionName == "" && opts.SessionNameSuffix == "" {
+		return normalizeRoleARN(roleARN, c.baseRoleARNPrefix)
+	}
+
+	name := opts.SessionName
+	if name == "" {
+		roleName, err := normalizeRoleARN(roleARN, c.baseRoleARNPrefix)
+		if err != nil {
+			return "", err
+		}
+		name = roleName
+	}
+
+	if opts.SessionNameSuffix != "" {
+		name += "/" + opts.SessionNameSuffix
+	}
+
+	return normalizeSessionName(name)
+}
+
+// roleARN returns the full ARN of a role reference which can either be a
+// role name or a full role ARN. Full role ARNs must be in the same partition
+// as the base role.
+func (c *STSCredentialsGetter) roleARN(role string) (string, error) {
+	if strings.HasPrefix(role, c.baseRoleARNPrefix) {
+		return role, nil
+	}
+
+	if strings.HasPrefix(role, arnPrefix) {
+		return "", fmt.Errorf("role ARN '%s' doesn't match the base role ARN prefix '%s'", role, c.baseRoleARNPrefix)
+	}
+	return c.baseRoleARN + role, nil
+}
+
+// maxSessionDuration returns the negotiated MaxSessionDuration of the role if
+// it's known.
+func (c *STSCredentialsGetter) maxSessionDuration(roleARN string) (time.Duration, bool) {
+	c.maxDurationsMu.Lock()
+	defer c.maxDurationsMu.Unlock()
+
+	maxDuration, ok := c.maxDurations[roleARN]
+	if !ok || time.Now().After(maxDuration.expires) {
+		return 0, false
+	}
+	return maxDuration.duration, true
+}
+
+// negotiateMaxSessionDuration determines the largest session duration allowed
+// for a role after a request with the session duration was rejected. The
+// MaxSessionDuration is read via IAM GetRole for roles in the account of the
+// base role. If it can't be read, the minimum MaxSessionDuration of one hour
+// is used. The result is remembered for maxSessionDurationTTL.
+func (c *STSCredentialsGetter) negotiateMaxSessionDuration(ctx context.Context, roleARN string, rejected time.Duration) time.Duration {
+	maxDuration := minMaxSessionDuration
+
+	roleAccount, _ := roleAccountID(roleARN, "")
+	baseAccount, _ := roleAccountID(c.baseRoleARN, "")
+	if c.iam != nil && roleAccount != "" && roleAccount == baseAccount {
+		roleName := roleARN[strings.LastIndex(roleARN, "/")+1:]
+		resp, err := c.iam.GetRole(ctx, &iam.GetRoleInput{RoleName: aws.String(roleName)})
+		switch {
+		case err != nil:
+			log.Debugf("Failed to get MaxSessionDuration of role '%s': %v", roleARN, err)
+		case resp.Role.MaxSessionDuration != nil:
+			duration := time.Duration(*resp.Role.MaxSessionDuration) * time.Second
+			if duration >= minMaxSessionDuration && duration < rejected {
+				maxDuration = duration
+			}
+		}
+	}
+
+	c.maxDurationsMu.Lock()
+	defer c.maxDurationsMu.Unlock()
+	if c.maxDurations == nil {
+		c.maxDurations = make(map[string]maxSessionDuration)
+	}
+	c.maxDurations[roleARN] = maxSessionDuration{
+		duration: maxDuration,
+		expires:  time.Now().Add(maxSessionDurationTTL),
+	}
+	return maxDuration
+}
+
+// chainCredentials assumes the intermediate roles of a role chain in order
+// and returns the credentials of the last role in the chain. Intermediate
+// credentials are cached until they are about to expire.
+func (c *STSCredentialsGetter) chainCredentials(ctx context.Context, chain []string, sourceIdentity string) (*Credentials, error) {
+	var creds *Credentials
+	key := sourceIdentity
+	for _, role := range chain {
+		roleARN, err := c.roleARN(role)
+		if err != nil {
+			return nil, err
+		}
+		key += "," + roleARN
+
+		if cached := c.cachedChainCredentials(key); cached != nil {
+			creds = cached
+			continue
+		}
+
+		// concurrent requests for the same hop are coalesced so the cache
+		// lock isn't held while calling STS.
+		source := creds
+		result, err, _ := c.chainGroup.Do(key, func() (interface{}, error) {
+			if cached := c.cachedChainCredentials(key); cached != nil {
+				return cached, nil
+			}
+
+			creds, err := c.assumeIntermediateRole(ctx, roleARN, sourceIdentity, source)
+			if err != nil {
+				return nil, err
+			}
+
+			c.storeChainCredentials(key, creds)
+			return creds, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		creds = result.(*Credentials)
+	}
+
+	return creds, nil
+}
+
+// assumeIntermediateRole assumes an intermediate role of a role chain with
+// the credentials of the previous role in the chain. The first role is
+// assumed with the base credentials.
+func (c *STSCredentialsGetter) assumeIntermediateRole(ctx context.Context, roleARN, sourceIdentity string, source *Credentials) (*Credentials, error) {
+	roleSessionName, err := normalizeRoleARN(roleARN, c.baseRoleARNPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	params := &sts.AssumeRoleInput{
+		RoleArn:         aws.String(roleARN),
+		RoleSessionName: aws.String(roleSessionName),
+		DurationSeconds: aws.Int32(int32(chainedSessionMaxDuration.Seconds())),
+	}
+
+	if sourceIdentity != "" {
+		params.SourceIdentity = aws.String(sourceIdentity)
+	}
+
+	var optFns []func(*sts.Options)
+	if source != nil {
+		optFns = append(optFns, withCredentials(source))
+	}
+
+	resp, endpoint, err := c.assumeRole(ctx, params, optFns...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assume intermediate role '%s': %w", roleARN, err)
+	}
+
+	return &Credentials{
+		RoleARN:         roleARN,
+		AccessKeyID:     aws.ToString(resp.Credentials.AccessKeyId),
+		SecretAccessKey: aws.ToString(resp.Credentials.SecretAccessKey),
+		SessionToken:    aws.ToString(resp.Credentials.SessionToken),
+		Expiration:      aws.ToTime(resp.Credentials.Expiration),
+		SessionDuration: chainedSessionMaxDuration,
+		Endpoint:        endpoint,
+	}, nil
+}
+
+// cachedChainCredentials returns the cached credentials of a role chain hop
+// if they are valid for at least chainCredentialsMinLifetime.
+func (c *STSCredentialsGetter) cachedChainCredentials(key string) *Credentials {
+	c.chainCacheMu.Lock()
+	defer c.chainCacheMu.Unlock()
+
+	creds, ok := c.chainCache[key]
+	if !ok || !time.Now().Add(chainCredentialsMinLifetime).Before(creds.Expiration) {
+		return nil
+	}
+	return creds
+}
+
+// storeChainCredentials caches the credentials of a role chain hop.
+func (c *STSCredentialsGetter) storeChainCredentials(key string, creds *Credentials) {
+	c.chainCacheMu.Lock()
+	defer c.chainCacheMu.Unlock()
+
+	if c.chainCache == nil {
+		c.chainCache = make(map[string]*Credentials)
+	}
+	c.chainCache[key] = creds
+}
+
+// withCredentials configures an STS client to sign requests with the
+// specified credentials.
+func withCredentials(creds *Credentials) func(*sts.Options) {
+	return func(o *sts.Options) {
+		o.Credentials = credentials.NewStaticCredentialsProvider(creds.AccessKeyID, creds.SecretAccessKey, creds.SessionToken)
+	}
+}
+
+// compactSessionPolicy validates the inline session policy and removes
+// insignificant whitespace from it. It returns an error if the session
+// policies exceed the limits of STS.
+func compactSessionPolicy(policy string, policyARNs []string) (string, error) {
+	if len(policyARNs) > policyARNsMaxCount {
+		return "", fmt.Errorf("%w: %d policy ARNs specified, at most %d are allowed", errPackedPolicyTooLarge, len(policyARNs), policyARNsMaxCount)
+	}
+
+	if policy == "" {
+		return "", nil
+	}
+
+	var buf bytes.Buffer
+	err := json.Compact(&buf, []byte(policy))
+	if err != nil {
+		return "", fmt.Errorf("invalid session policy: %w", err)
+	}
+
+	if size := utf8.RuneCount(buf.Bytes()); size > sessionPolicyMaxSize {
+		return "", fmt.Errorf("%w: session policy has %d characters, at most %d are allowed", errPackedPolicyTooLarge, size, sessionPolicyMaxSize)
+	}
+
+	return buf.String(), nil
+}
+
+// isMaxSessionDurationExceeded returns true if STS rejected the session
+// duration because it exceeds the MaxSessionDuration of the role.
+func isMaxSessionDurationExceeded(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "ValidationError" &&
+		strings.Contains(apiErr.ErrorMessage(), "MaxSessionDuration")
+}
+
+// isAccessDenied returns true if the error is an AccessDenied error returned
+// by the AWS API.
+func isAccessDenied(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "AccessDenied"
+}
+
 // GetBaseRoleARN gets base role ARN from EC2 metadata service.
 func GetBaseRoleARN(ctx context.Context, cfg aws.Config) (string, error) {
 	metadata := imds.NewFromConfig(cfg)
@@ -103,6 +587,27 @@ func GetBaseRoleARN(ctx context.Context, cfg aws.Config) (string, error) {
 	return fmt.Sprintf("%s/", baseRoleARN[0]), nil
 }
 
+// GetBaseRoleARNFromCallerIdentity gets base role ARN from the identity of
+// the caller as returned by sts:GetCallerIdentity. This doesn't depend on the
+// EC2 metadata service.
+func GetBaseRoleARNFromCallerIdentity(ctx context.Context, cfg aws.Config, optFns ...func(*sts.Options)) (string, error) {
+	return getBaseRoleARNFromCallerIdentity(ctx, sts.NewFromConfig(cfg, optFns...))
+}
+
+func getBaseRoleARNFromCallerIdentity(ctx context.Context, svc callerIdentityAPI) (string, error) {
+	resp, err := svc.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+
+	callerARN, err := arn.Parse(aws.ToString(resp.Arn))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse caller identity ARN: %w", err)
+	}
+
+	return fmt.Sprintf("arn:%s:iam::%s:role/", callerARN.Partition, callerARN.AccountID), nil
+}
+
 // normalizeRoleARN normalizes a role ARN by substituting special characters
 // with characters allowed for a RoleSessionName according to:
 // https://docs.aws.amazon.com/STS/latest/APIReference/API_AssumeRole.html
@@ -122,6 +627,29 @@ func normalizeRoleARN(roleARN, roleARNPrefix string) (string, error) {
 	return accountID + normalizePath(parts[1:], remainingChars), nil
 }
 
+// normalizeSessionName normalizes a '/' separated name into a string valid as
+// a RoleSessionName or SourceIdentity. Like for normalizeRoleARN the levels
+// are separated by '.' and truncated to fit the size limit.
+// e.g. given the name: "namespace/name:a" it would return the string:
+// "namespace.name_a"
+func normalizeSessionName(name string) (string, error) {
+	name = invalidSessionNameChars.ReplaceAllString(name, "_")
+	levels := strings.Split(name, "/")
+
+	// every level requires at least two chars, so drop the leading levels
+	// which can't fit.
+	maxLevels := (roleSessionNameMaxSize + 1) / 2
+	if len(levels) > maxLevels {
+		levels = levels[len(levels)-maxLevels:]
+	}
+
+	normalized := strings.TrimPrefix(normalizePath(levels, roleSessionNameMaxSize+1), ".")
+	if len(normalized) < sessionNameMinSize {
+		return "", fmt.Errorf("session name '%s' must be at least %d characters long", normalized, sessionNameMinSize)
+	}
+	return normalized, nil
+}
+
 // normalizePath normalizes the path levels into a roleSession valid string.
 // The last level always gets as many chars as possible leaving only a minimum
 // of one char for each of the other levels.
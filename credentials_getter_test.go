@@ -3,26 +3,52 @@ package main
 import (
 	"context"
 	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 	"github.com/stretchr/testify/require"
 )
 
 type mockSTSAPI struct {
 	err            error
 	assumeRoleResp *sts.AssumeRoleOutput
+	params         *sts.AssumeRoleInput
+	calls          []*sts.AssumeRoleInput
+	credentials    []aws.CredentialsProvider
+	// maxDuration rejects requests exceeding it like the MaxSessionDuration
+	// of a role if set.
+	maxDuration int32
 }
 
-func (sts *mockSTSAPI) AssumeRole(_ context.Context, _ *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
-	if sts.err != nil {
-		return nil, sts.err
+func (m *mockSTSAPI) AssumeRole(_ context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	options := sts.Options{}
+	for _, fn := range optFns {
+		fn(&options)
 	}
-	return sts.assumeRoleResp, nil
+	m.params = params
+	m.calls = append(m.calls, params)
+	m.credentials = append(m.credentials, options.Credentials)
+	if m.err != nil {
+		return nil, m.err
+	}
+	if m.maxDuration > 0 && aws.ToInt32(params.DurationSeconds) > m.maxDuration {
+		return nil, &smithy.GenericAPIError{
+			Code:    "ValidationError",
+			Message: "The requested DurationSeconds exceeds the MaxSessionDuration set for this role.",
+		}
+	}
+	return m.assumeRoleResp, nil
 }
 
 func TestGet(t *testing.T) {
@@ -32,7 +58,7 @@ func TestGet(t *testing.T) {
 	}
 	cfg.Region = "region"
 	getter := NewSTSCredentialsGetter(cfg, "", "")
-	getter.svc = &mockSTSAPI{
+	getter.endpoints[0].svc = &mockSTSAPI{
 		err: nil,
 		assumeRoleResp: &sts.AssumeRoleOutput{
 			Credentials: &types.Credentials{
@@ -45,22 +71,574 @@ func TestGet(t *testing.T) {
 	}
 
 	roleARN := "arn:aws:iam::012345678910:role/role-name"
-	creds, err := getter.Get(context.Background(), roleARN, 3600*time.Second)
+	creds, err := getter.Get(context.Background(), roleARN, 3600*time.Second, CredentialsOptions{})
 	require.NoError(t, err)
 	require.Equal(t, "access_key_id", creds.AccessKeyID)
 	require.Equal(t, "secret_access_key", creds.SecretAccessKey)
 	require.Equal(t, "session_token", creds.SessionToken)
 	require.Equal(t, time.Time{}, creds.Expiration)
 
-	getter.svc = &mockSTSAPI{
+	getter.endpoints[0].svc = &mockSTSAPI{
 		err: errors.New("failed"),
 	}
 	roleARNPrefix, err := GetPrefixFromARN(roleARN)
 	require.NoError(t, err)
-	_, err = getter.Get(context.Background(), roleARNPrefix+"role", 3600*time.Second)
+	_, err = getter.Get(context.Background(), roleARNPrefix+"role", 3600*time.Second, CredentialsOptions{})
 	require.Error(t, err)
 }
 
+func TestRoleARN(tt *testing.T) {
+	getter := &STSCredentialsGetter{
+		baseRoleARN:       "arn:aws:iam::012345678910:role/",
+		baseRoleARNPrefix: "arn:aws:iam::",
+	}
+
+	for _, tc := range []struct {
+		msg             string
+		role            string
+		expectedRoleARN string
+		expectedErr     bool
+	}{
+		{
+			msg:             "role name",
+			role:            "role-name",
+			expectedRoleARN: "arn:aws:iam::012345678910:role/role-name",
+		},
+		{
+			msg:             "full role ARN",
+			role:            "arn:aws:iam::109876543210:role/role-name",
+			expectedRoleARN: "arn:aws:iam::109876543210:role/role-name",
+		},
+		{
+			msg:         "full role ARN in other partition",
+			role:        "arn:aws-cn:iam::109876543210:role/role-name",
+			expectedErr: true,
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			roleARN, err := getter.roleARN(tc.role)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedRoleARN, roleARN)
+		})
+	}
+}
+
+func TestGetExternalID(t *testing.T) {
+	getter := &STSCredentialsGetter{}
+	svc := &mockSTSAPI{
+		assumeRoleResp: &sts.AssumeRoleOutput{
+			Credentials: &types.Credentials{
+				Expiration: &time.Time{},
+			},
+		},
+	}
+	getter.endpoints = []stsEndpoint{{svc: svc}}
+
+	roleARN := "arn:aws:iam::012345678910:role/role-name"
+	roleARNPrefix, err := GetPrefixFromARN(roleARN)
+	require.NoError(t, err)
+	getter.baseRoleARNPrefix = roleARNPrefix
+
+	_, err = getter.Get(context.Background(), roleARN, 3600*time.Second, CredentialsOptions{})
+	require.NoError(t, err)
+	require.Nil(t, svc.params.ExternalId)
+
+	_, err = getter.Get(context.Background(), roleARN, 3600*time.Second, CredentialsOptions{ExternalID: "external-id"})
+	require.NoError(t, err)
+	require.Equal(t, "external-id", aws.ToString(svc.params.ExternalId))
+
+	svc.err = &smithy.GenericAPIError{Code: "AccessDenied", Message: "not authorized"}
+	_, err = getter.Get(context.Background(), roleARN, 3600*time.Second, CredentialsOptions{ExternalID: "external-id"})
+	require.ErrorIs(t, err, errExternalIDRejected)
+
+	_, err = getter.Get(context.Background(), roleARN, 3600*time.Second, CredentialsOptions{})
+	require.Error(t, err)
+	require.NotErrorIs(t, err, errExternalIDRejected)
+}
+
+func TestGetSessionTags(t *testing.T) {
+	svc := &mockSTSAPI{
+		assumeRoleResp: &sts.AssumeRoleOutput{
+			Credentials: &types.Credentials{
+				Expiration: &time.Time{},
+			},
+		},
+	}
+	getter := &STSCredentialsGetter{
+		endpoints:         []stsEndpoint{{svc: svc}},
+		baseRoleARNPrefix: "arn:aws:iam::",
+	}
+
+	tags := []SessionTag{
+		{Key: "cluster", Value: "production"},
+		{Key: "team", Value: "teapot", Transitive: true},
+	}
+	creds, err := getter.Get(context.Background(), "arn:aws:iam::012345678910:role/role-name", 3600*time.Second, CredentialsOptions{Tags: tags, SourceIdentity: "namespace.name"})
+	require.NoError(t, err)
+	require.Equal(t, "namespace.name", aws.ToString(svc.params.SourceIdentity))
+	require.Equal(t, tags, creds.SessionTags)
+	require.Equal(t, []types.Tag{
+		{Key: aws.String("cluster"), Value: aws.String("production")},
+		{Key: aws.String("team"), Value: aws.String("teapot")},
+	}, svc.params.Tags)
+	require.Equal(t, []string{"team"}, svc.params.TransitiveTagKeys)
+}
+
+func TestGetRoleSessionName(tt *testing.T) {
+	for _, tc := range []struct {
+		msg      string
+		opts     CredentialsOptions
+		expected string
+	}{
+		{
+			msg:      "derive session name from role ARN",
+			expected: "012345678910.path.role-name",
+		},
+		{
+			msg: "templated session name",
+			opts: CredentialsOptions{
+				SessionName: "cluster/namespace/name",
+			},
+			expected: "cluster.namespace.name",
+		},
+		{
+			msg: "templated session name with suffix",
+			opts: CredentialsOptions{
+				SessionName:       "cluster/namespace/name",
+				SessionNameSuffix: "suffix",
+			},
+			expected: "cluster.namespace.name.suffix",
+		},
+		{
+			msg: "suffix without template",
+			opts: CredentialsOptions{
+				SessionNameSuffix: "suffix",
+			},
+			expected: "012345678910.path.role-name.suffix",
+		},
+		{
+			msg: "long session name is truncated",
+			opts: CredentialsOptions{
+				SessionName:       "cluster/" + strings.Repeat("n", 80) + "/name",
+				SessionNameSuffix: "suffix",
+			},
+			expected: "c." + strings.Repeat("n", 50) + ".name.suffix",
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			svc := &mockSTSAPI{
+				assumeRoleResp: &sts.AssumeRoleOutput{
+					Credentials: &types.Credentials{
+						Expiration: &time.Time{},
+					},
+				},
+			}
+			getter := &STSCredentialsGetter{
+				endpoints:         []stsEndpoint{{svc: svc}},
+				baseRoleARNPrefix: "arn:aws:iam::",
+			}
+
+			_, err := getter.Get(context.Background(), "arn:aws:iam::012345678910:role/path/role-name", time.Hour, tc.opts)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, aws.ToString(svc.params.RoleSessionName))
+			require.LessOrEqual(t, len(aws.ToString(svc.params.RoleSessionName)), roleSessionNameMaxSize)
+		})
+	}
+}
+
+func TestGetSessionPolicy(tt *testing.T) {
+	for _, tc := range []struct {
+		msg                string
+		opts               CredentialsOptions
+		stsErr             error
+		expectedPolicy     *string
+		expectedPolicyARNs []types.PolicyDescriptorType
+		expectedErr        error
+	}{
+		{
+			msg: "compact inline policy and managed policies",
+			opts: CredentialsOptions{
+				Policy: `{
+  "Version": "2012-10-17",
+  "Statement": [{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "*"}]
+}`,
+				PolicyARNs: []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"},
+			},
+			expectedPolicy: aws.String(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"*"}]}`),
+			expectedPolicyARNs: []types.PolicyDescriptorType{
+				{Arn: aws.String("arn:aws:iam::aws:policy/ReadOnlyAccess")},
+			},
+		},
+		{
+			msg: "inline policy too large",
+			opts: CredentialsOptions{
+				Policy: `{"Sid":"` + strings.Repeat("a", sessionPolicyMaxSize) + `"}`,
+			},
+			expectedErr: errPackedPolicyTooLarge,
+		},
+		{
+			msg: "too many managed policies",
+			opts: CredentialsOptions{
+				PolicyARNs: make([]string, policyARNsMaxCount+1),
+			},
+			expectedErr: errPackedPolicyTooLarge,
+		},
+		{
+			msg: "packed policy too large",
+			opts: CredentialsOptions{
+				Policy: `{}`,
+			},
+			stsErr:      &types.PackedPolicyTooLargeException{},
+			expectedErr: errPackedPolicyTooLarge,
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			svc := &mockSTSAPI{
+				err: tc.stsErr,
+				assumeRoleResp: &sts.AssumeRoleOutput{
+					Credentials: &types.Credentials{
+						Expiration: &time.Time{},
+					},
+				},
+			}
+			getter := &STSCredentialsGetter{
+				endpoints:         []stsEndpoint{{svc: svc}},
+				baseRoleARNPrefix: "arn:aws:iam::",
+			}
+
+			_, err := getter.Get(context.Background(), "arn:aws:iam::012345678910:role/role-name", 3600*time.Second, tc.opts)
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedPolicy, svc.params.Policy)
+			require.Equal(t, tc.expectedPolicyARNs, svc.params.PolicyArns)
+		})
+	}
+
+	_, err := compactSessionPolicy("{invalid", nil)
+	require.Error(tt, err)
+}
+
+func TestGetMaxSessionDurationNegotiation(tt *testing.T) {
+	for _, tc := range []struct {
+		msg      string
+		role     string
+		iam      *mockIAMAPI
+		expected time.Duration
+	}{
+		{
+			msg:  "MaxSessionDuration discovered via IAM",
+			role: "arn:aws:iam::012345678910:role/app",
+			iam: &mockIAMAPI{roles: map[string]*iamtypes.Role{
+				"app": {MaxSessionDuration: aws.Int32(7200)},
+			}},
+			expected: 2 * time.Hour,
+		},
+		{
+			msg:      "step down to one hour if GetRole is denied",
+			role:     "arn:aws:iam::012345678910:role/app",
+			iam:      &mockIAMAPI{err: &smithy.GenericAPIError{Code: "AccessDenied"}},
+			expected: time.Hour,
+		},
+		{
+			msg:      "step down to one hour for roles in other accounts",
+			role:     "arn:aws:iam::109876543210:role/app",
+			iam:      &mockIAMAPI{roles: map[string]*iamtypes.Role{"app": {MaxSessionDuration: aws.Int32(43200)}}},
+			expected: time.Hour,
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			svc := &mockSTSAPI{
+				maxDuration: int32(tc.expected.Seconds()),
+				assumeRoleResp: &sts.AssumeRoleOutput{
+					Credentials: &types.Credentials{Expiration: &time.Time{}},
+				},
+			}
+			getter := &STSCredentialsGetter{
+				endpoints:         []stsEndpoint{{svc: svc}},
+				iam:               tc.iam,
+				baseRoleARN:       "arn:aws:iam::012345678910:role/",
+				baseRoleARNPrefix: "arn:aws:iam::",
+			}
+
+			creds, err := getter.Get(context.Background(), tc.role, 12*time.Hour, CredentialsOptions{})
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, creds.SessionDuration)
+			require.Len(t, svc.calls, 2)
+
+			// the negotiated duration is used for further requests.
+			creds, err = getter.Get(context.Background(), tc.role, 12*time.Hour, CredentialsOptions{})
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, creds.SessionDuration)
+			require.Len(t, svc.calls, 3)
+		})
+	}
+
+	// requests of one hour are not negotiated.
+	svc := &mockSTSAPI{maxDuration: 900}
+	getter := &STSCredentialsGetter{endpoints: []stsEndpoint{{svc: svc}}}
+	_, err := getter.Get(context.Background(), "arn:aws:iam::012345678910:role/app", time.Hour, CredentialsOptions{})
+	require.Error(tt, err)
+	require.Len(tt, svc.calls, 1)
+}
+
+func TestGetAssumeRoleChain(t *testing.T) {
+	expiration := time.Now().Add(time.Hour)
+	svc := &mockSTSAPI{
+		assumeRoleResp: &sts.AssumeRoleOutput{
+			Credentials: &types.Credentials{
+				AccessKeyId:     aws.String("access_key_id"),
+				SecretAccessKey: aws.String("secret_access_key"),
+				SessionToken:    aws.String("session_token"),
+				Expiration:      &expiration,
+			},
+		},
+	}
+	getter := &STSCredentialsGetter{
+		endpoints:         []stsEndpoint{{svc: svc}},
+		baseRoleARN:       "arn:aws:iam::012345678910:role/",
+		baseRoleARNPrefix: "arn:aws:iam::",
+	}
+
+	opts := CredentialsOptions{
+		AssumeRoleChain: []string{"hub", "arn:aws:iam::109876543210:role/spoke"},
+		SourceIdentity:  "namespace.name",
+	}
+	creds, err := getter.Get(context.Background(), "arn:aws:iam::109876543210:role/target", 12*time.Hour, opts)
+	require.NoError(t, err)
+	require.Equal(t, time.Hour, creds.SessionDuration)
+	require.Len(t, svc.calls, 3)
+	require.Equal(t, "arn:aws:iam::012345678910:role/hub", aws.ToString(svc.calls[0].RoleArn))
+	require.Equal(t, "arn:aws:iam::109876543210:role/spoke", aws.ToString(svc.calls[1].RoleArn))
+	require.Equal(t, "arn:aws:iam::109876543210:role/target", aws.ToString(svc.calls[2].RoleArn))
+	require.Equal(t, int32(3600), aws.ToInt32(svc.calls[2].DurationSeconds))
+	for _, call := range svc.calls {
+		require.Equal(t, "namespace.name", aws.ToString(call.SourceIdentity))
+	}
+
+	// the first hop is assumed with the base credentials, all others with
+	// the credentials of the previous hop.
+	require.Nil(t, svc.credentials[0])
+	require.NotNil(t, svc.credentials[1])
+	require.NotNil(t, svc.credentials[2])
+
+	// intermediate credentials are cached.
+	_, err = getter.Get(context.Background(), "arn:aws:iam::109876543210:role/target", 12*time.Hour, opts)
+	require.NoError(t, err)
+	require.Len(t, svc.calls, 4)
+
+	svc.err = errors.New("failed")
+	getter.chainCache = nil
+	_, err = getter.Get(context.Background(), "arn:aws:iam::109876543210:role/target", time.Hour, opts)
+	require.Error(t, err)
+}
+
+// blockingSTSAPI blocks requests for the blocked role until unblock is
+// closed.
+type blockingSTSAPI struct {
+	blockedRoleARN string
+	unblock        chan struct{}
+	expiration     time.Time
+	mu             sync.Mutex
+	calls          map[string]int
+}
+
+func (m *blockingSTSAPI) AssumeRole(ctx context.Context, params *sts.AssumeRoleInput, _ ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	m.mu.Lock()
+	m.calls[aws.ToString(params.RoleArn)]++
+	m.mu.Unlock()
+
+	if aws.ToString(params.RoleArn) == m.blockedRoleARN {
+		select {
+		case <-m.unblock:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return &sts.AssumeRoleOutput{
+		Credentials: &types.Credentials{
+			AccessKeyId:     aws.String("access_key_id"),
+			SecretAccessKey: aws.String("secret_access_key"),
+			SessionToken:    aws.String("session_token"),
+			Expiration:      &m.expiration,
+		},
+	}, nil
+}
+
+func (m *blockingSTSAPI) callsFor(roleARN string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls[roleARN]
+}
+
+func TestGetAssumeRoleChainConcurrent(t *testing.T) {
+	svc := &blockingSTSAPI{
+		blockedRoleARN: "arn:aws:iam::012345678910:role/slow",
+		unblock:        make(chan struct{}),
+		expiration:     time.Now().Add(time.Hour),
+		calls:          make(map[string]int),
+	}
+	getter := &STSCredentialsGetter{
+		endpoints:         []stsEndpoint{{svc: svc}},
+		baseRoleARN:       "arn:aws:iam::012345678910:role/",
+		baseRoleARNPrefix: "arn:aws:iam::",
+	}
+
+	// concurrent requests for the same chain share the intermediate
+	// request.
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := getter.Get(context.Background(), "app", time.Hour, CredentialsOptions{AssumeRoleChain: []string{"slow"}})
+			errs <- err
+		}()
+	}
+	require.Eventually(t, func() bool {
+		return svc.callsFor("arn:aws:iam::012345678910:role/slow") == 1
+	}, time.Second, time.Millisecond)
+
+	// other chains are not blocked by the slow intermediate role.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := getter.Get(ctx, "app", time.Hour, CredentialsOptions{AssumeRoleChain: []string{"fast"}})
+	require.NoError(t, err)
+
+	close(svc.unblock)
+	require.NoError(t, <-errs)
+	require.NoError(t, <-errs)
+	require.Equal(t, 1, svc.callsFor("arn:aws:iam::012345678910:role/slow"))
+}
+
+type mockCallerIdentityAPI struct {
+	err  error
+	resp *sts.GetCallerIdentityOutput
+}
+
+func (m *mockCallerIdentityAPI) GetCallerIdentity(_ context.Context, _ *sts.GetCallerIdentityInput, _ ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.resp, nil
+}
+
+func TestGetBaseRoleARNFromCallerIdentity(tt *testing.T) {
+	for _, tc := range []struct {
+		msg             string
+		callerARN       string
+		err             error
+		expectedBaseARN string
+		expectedErr     bool
+	}{
+		{
+			msg:             "assumed role",
+			callerARN:       "arn:aws:sts::012345678910:assumed-role/controller/session",
+			expectedBaseARN: "arn:aws:iam::012345678910:role/",
+		},
+		{
+			msg:             "china partition",
+			callerARN:       "arn:aws-cn:sts::012345678910:assumed-role/controller/session",
+			expectedBaseARN: "arn:aws-cn:iam::012345678910:role/",
+		},
+		{
+			msg:         "invalid ARN",
+			callerARN:   "invalid",
+			expectedErr: true,
+		},
+		{
+			msg:         "sts error",
+			err:         errors.New("failed"),
+			expectedErr: true,
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			svc := &mockCallerIdentityAPI{
+				err:  tc.err,
+				resp: &sts.GetCallerIdentityOutput{Arn: aws.String(tc.callerARN)},
+			}
+			baseRoleARN, err := getBaseRoleARNFromCallerIdentity(context.Background(), svc)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedBaseARN, baseRoleARN)
+		})
+	}
+}
+
+func TestGetEndpointFailover(tt *testing.T) {
+	responseError := func(statusCode int) error {
+		return &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: statusCode}},
+			Err:      errors.New("failed"),
+		}
+	}
+
+	for _, tc := range []struct {
+		msg              string
+		primaryErr       error
+		expectedEndpoint string
+		expectedErr      bool
+	}{
+		{
+			msg:              "primary endpoint succeeds",
+			expectedEndpoint: "https://primary",
+		},
+		{
+			msg:              "fail over on server error",
+			primaryErr:       responseError(http.StatusServiceUnavailable),
+			expectedEndpoint: "https://fallback",
+		},
+		{
+			msg:              "fail over on connection error",
+			primaryErr:       &net.OpError{Op: "dial", Err: errors.New("connection refused")},
+			expectedEndpoint: "https://fallback",
+		},
+		{
+			msg:         "don't fail over on client error",
+			primaryErr:  responseError(http.StatusForbidden),
+			expectedErr: true,
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			resp := &sts.AssumeRoleOutput{
+				Credentials: &types.Credentials{
+					Expiration: &time.Time{},
+				},
+			}
+			fallback := &mockSTSAPI{assumeRoleResp: resp}
+			getter := &STSCredentialsGetter{
+				endpoints: []stsEndpoint{
+					{url: "https://primary", svc: &mockSTSAPI{err: tc.primaryErr, assumeRoleResp: resp}},
+					{url: "https://fallback", svc: fallback},
+				},
+				baseRoleARNPrefix: "arn:aws:iam::",
+			}
+
+			creds, err := getter.Get(context.Background(), "arn:aws:iam::012345678910:role/role-name", time.Hour, CredentialsOptions{})
+			if tc.expectedErr {
+				require.Error(t, err)
+				require.Empty(t, fallback.calls)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedEndpoint, creds.Endpoint)
+		})
+	}
+}
+
+func TestDefaultSTSEndpoint(t *testing.T) {
+	require.Equal(t, "https://sts.eu-central-1.amazonaws.com", defaultSTSEndpoint("eu-central-1"))
+	require.Equal(t, "https://sts.cn-north-1.amazonaws.com.cn", defaultSTSEndpoint("cn-north-1"))
+}
+
 // func TestGetBaseRoleARN(t *testing.T) {
 // 	sess := &session.Session{}
 // 	baseRole, err := GetBaseRoleARN(sess)
@@ -142,3 +720,49 @@ func TestNormalizeRoleARN(tt *testing.T) {
 		})
 	}
 }
+
+func TestNormalizeSessionName(tt *testing.T) {
+	for _, tc := range []struct {
+		msg          string
+		name         string
+		expectedName string
+		expectedErr  bool
+	}{
+		{
+			msg:          "namespace and name",
+			name:         "namespace/name",
+			expectedName: "namespace.name",
+		},
+		{
+			msg:          "invalid characters",
+			name:         "name space:name*",
+			expectedName: "name_space_name_",
+		},
+		{
+			msg:          "truncate leading levels for long names",
+			name:         "aaaaa/bbbbb/" + strings.Repeat("c", 70),
+			expectedName: "a.b." + strings.Repeat("c", 60),
+		},
+		{
+			msg:          "drop levels which can't fit",
+			name:         strings.Repeat("a/", 40) + "b",
+			expectedName: strings.Repeat("a.", 31) + "b",
+		},
+		{
+			msg:         "too short",
+			name:        "a",
+			expectedErr: true,
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			normalized, err := normalizeSessionName(tc.name)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedName, normalized)
+			require.LessOrEqual(t, len(normalized), roleSessionNameMaxSize)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	execPluginVersion     = 1
+	execPluginStderrLimit = 1024
+)
+
+// errExecPluginTimeout is returned when the credentials plugin doesn't
+// finish within the timeout.
+var errExecPluginTimeout = errors.New("credentials plugin timed out")
+
+// ExecPluginRequest is the JSON request written to the stdin of the
+// credentials plugin.
+type ExecPluginRequest struct {
+	Version         int          `json:"version"`
+	Role            string       `json:"role"`
+	Namespace       string       `json:"namespace,omitempty"`
+	DurationSeconds int64        `json:"durationSeconds"`
+	Tags            []SessionTag `json:"tags,omitempty"`
+	ExternalID      string       `json:"externalID,omitempty"`
+	Policy          string       `json:"policy,omitempty"`
+	PolicyARNs      []string     `json:"policyARNs,omitempty"`
+	SourceIdentity  string       `json:"sourceIdentity,omitempty"`
+	AssumeRoleChain []string     `json:"assumeRoleChain,omitempty"`
+}
+
+// execPluginResponse is the JSON response read from the stdout of the
+// credentials plugin. It's the ProcessCredentials format with an optional
+// role ARN.
+type execPluginResponse struct {
+	ProcessCredentials
+	RoleARN string `json:"RoleArn"`
+}
+
+// ExecCredentialsGetter is a credentials getter which executes an external
+// plugin. The plugin gets an ExecPluginRequest on stdin and must write
+// credentials in the ProcessCredentials format to stdout. Stderr is included
+// in the error if the plugin fails.
+type ExecCredentialsGetter struct {
+	command string
+	args    []string
+	timeout time.Duration
+	sem     chan struct{}
+}
+
+// NewExecCredentialsGetter initializes a new exec credentials getter which
+// runs at most concurrency plugin processes at the same time.
+func NewExecCredentialsGetter(command string, args []string, timeout time.Duration, concurrency int) *ExecCredentialsGetter {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &ExecCredentialsGetter{
+		command: command,
+		args:    args,
+		timeout: timeout,
+		sem:     make(chan struct{}, concurrency),
+	}
+}
+
+// Get gets new credentials for the specified role from the plugin.
+func (g *ExecCredentialsGetter) Get(ctx context.Context, role string, sessionDuration time.Duration, opts CredentialsOptions) (*Credentials, error) {
+	request, err := json.Marshal(ExecPluginRequest{
+		Version:         execPluginVersion,
+		Role:            role,
+		Namespace:       opts.Namespace,
+		DurationSeconds: int64(sessionDuration.Seconds()),
+		Tags:            opts.Tags,
+		ExternalID:      opts.ExternalID,
+		Policy:          opts.Policy,
+		PolicyARNs:      opts.PolicyARNs,
+		SourceIdentity:  opts.SourceIdentity,
+		AssumeRoleChain: opts.AssumeRoleChain,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case g.sem <- struct{}{}:
+		defer func() { <-g.sem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, g.command, g.args...)
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("%w after %s", errExecPluginTimeout, g.timeout)
+		}
+		return nil, fmt.Errorf("credentials plugin failed for role '%s': %w%s", role, err, formatStderr(stderr.String()))
+	}
+
+	var resp execPluginResponse
+	err = json.Unmarshal(stdout.Bytes(), &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse response of credentials plugin for role '%s': %w%s", role, err, formatStderr(stderr.String()))
+	}
+
+	if resp.Version != execPluginVersion || resp.AccessKeyID == "" || resp.SecretAccessKey == "" || resp.Expiration.IsZero() {
+		return nil, fmt.Errorf("invalid response of credentials plugin for role '%s': Version must be %d and AccessKeyId, SecretAccessKey and Expiration must be set", role, execPluginVersion)
+	}
+
+	roleARN := resp.RoleARN
+	if roleARN == "" {
+		roleARN = role
+	}
+
+	return &Credentials{
+		RoleARN:         roleARN,
+		AccessKeyID:     resp.AccessKeyID,
+		SecretAccessKey: resp.SecretAccessKey,
+		SessionToken:    resp.SessionToken,
+		Expiration:      resp.Expiration,
+		SessionTags:     opts.Tags,
+	}, nil
+}
+
+// formatStderr formats the stderr output of the plugin for error messages.
+// Long output is truncated to the end which usually holds the error.
+func formatStderr(stderr string) string {
+	stderr = strings.TrimSpace(stderr)
+	if stderr == "" {
+		return ""
+	}
+
+	if len(stderr) > execPluginStderrLimit {
+		stderr = "..." + stderr[len(stderr)-execPluginStderrLimit:]
+	}
+	return ": " + stderr
+}
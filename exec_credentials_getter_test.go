@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testPluginResponse = `{"Version":1,"AccessKeyId":"AKID","SecretAccessKey":"SECRET","SessionToken":"TOKEN","Expiration":"2030-01-01T00:00:00Z"}`
+
+// writeTestPlugin writes a shell script used as credentials plugin.
+func writeTestPlugin(t *testing.T, script string) string {
+	path := filepath.Join(t.TempDir(), "plugin.sh")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o700))
+	return path
+}
+
+func TestExecCredentialsGetter(t *testing.T) {
+	requestFile := filepath.Join(t.TempDir(), "request.json")
+	plugin := writeTestPlugin(t, `cat > "$1"
+echo '`+testPluginResponse+`'`)
+
+	getter := NewExecCredentialsGetter(plugin, []string{requestFile}, 10*time.Second, 1)
+	tags := []SessionTag{{Key: "team", Value: "foo"}}
+	creds, err := getter.Get(context.Background(), "my-role", time.Hour, CredentialsOptions{
+		Namespace:  "default",
+		Tags:       tags,
+		ExternalID: "external",
+	})
+	require.NoError(t, err)
+	require.Equal(t, &Credentials{
+		RoleARN:         "my-role",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "SECRET",
+		SessionToken:    "TOKEN",
+		Expiration:      time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		SessionTags:     tags,
+	}, creds)
+
+	data, err := os.ReadFile(requestFile)
+	require.NoError(t, err)
+	var request ExecPluginRequest
+	require.NoError(t, json.Unmarshal(data, &request))
+	require.Equal(t, ExecPluginRequest{
+		Version:         1,
+		Role:            "my-role",
+		Namespace:       "default",
+		DurationSeconds: 3600,
+		Tags:            tags,
+		ExternalID:      "external",
+	}, request)
+
+	// the role ARN is taken from the response if set.
+	plugin = writeTestPlugin(t, `echo '{"Version":1,"AccessKeyId":"AKID","SecretAccessKey":"SECRET","Expiration":"2030-01-01T00:00:00Z","RoleArn":"arn:aws:iam::123456789012:role/my-role"}'`)
+	getter = NewExecCredentialsGetter(plugin, nil, 10*time.Second, 1)
+	creds, err = getter.Get(context.Background(), "my-role", time.Hour, CredentialsOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "arn:aws:iam::123456789012:role/my-role", creds.RoleARN)
+}
+
+func TestExecCredentialsGetterErrors(tt *testing.T) {
+	for _, tc := range []struct {
+		msg     string
+		script  string
+		timeout time.Duration
+		err     string
+	}{
+		{
+			msg:     "stderr is included in the error",
+			script:  "echo 'role my-role is not allowed' >&2\nexit 1",
+			timeout: 10 * time.Second,
+			err:     "credentials plugin failed for role 'my-role': exit status 1: role my-role is not allowed",
+		},
+		{
+			msg:     "long stderr is truncated",
+			script:  "head -c 5000 /dev/zero | tr '\\0' 'x' >&2\nexit 1",
+			timeout: 10 * time.Second,
+			err:     "exit status 1: ..." + strings.Repeat("x", execPluginStderrLimit),
+		},
+		{
+			msg:     "plugin times out",
+			script:  "exec sleep 10",
+			timeout: 100 * time.Millisecond,
+			err:     "credentials plugin timed out after 100ms",
+		},
+		{
+			msg:     "invalid JSON",
+			script:  "echo 'not json'",
+			timeout: 10 * time.Second,
+			err:     "failed to parse response of credentials plugin",
+		},
+		{
+			msg:     "missing credentials",
+			script:  `echo '{"Version":1}'`,
+			timeout: 10 * time.Second,
+			err:     "invalid response of credentials plugin",
+		},
+		{
+			msg:     "unsupported version",
+			script:  `echo '{"Version":2,"AccessKeyId":"AKID","SecretAccessKey":"SECRET","Expiration":"2030-01-01T00:00:00Z"}'`,
+			timeout: 10 * time.Second,
+			err:     "invalid response of credentials plugin",
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			getter := NewExecCredentialsGetter(writeTestPlugin(t, tc.script), nil, tc.timeout, 1)
+			_, err := getter.Get(context.Background(), "my-role", time.Hour, CredentialsOptions{})
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestExecCredentialsGetterConcurrency(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "log")
+	plugin := writeTestPlugin(t, `echo start >> "$1"
+sleep 0.1
+echo end >> "$1"
+echo '`+testPluginResponse+`'`)
+
+	getter := NewExecCredentialsGetter(plugin, []string{logFile}, 10*time.Second, 1)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = getter.Get(context.Background(), "my-role", time.Hour, CredentialsOptions{})
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, []error{nil, nil, nil}, errs)
+
+	data, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	require.Equal(t, strings.Repeat("start\nend\n", 3), string(data))
+
+	// waiting for a free slot is aborted with the context.
+	getter.sem <- struct{}{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = getter.Get(ctx, "my-role", time.Hour, CredentialsOptions{})
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -21,27 +21,32 @@ set -o pipefail
 SRC="github.com"
 GOPKG="$SRC/zalando-incubator/kube-aws-iam-controller"
 CUSTOM_RESOURCE_NAME="zalando.org"
-CUSTOM_RESOURCE_VERSION="v1"
+CUSTOM_RESOURCE_VERSIONS="v1 v2"
 
 SCRIPT_ROOT="$(dirname "${BASH_SOURCE[0]}")/.."
 
 OUTPUT_DIR="pkg/client"
 OUTPUT_PKG="${GOPKG}/${OUTPUT_DIR}"
 APIS_PKG="${GOPKG}/pkg/apis"
-GROUPS_WITH_VERSIONS="${CUSTOM_RESOURCE_NAME}:${CUSTOM_RESOURCE_VERSION}"
+GROUPS_WITH_VERSIONS="${CUSTOM_RESOURCE_NAME}:${CUSTOM_RESOURCE_VERSIONS// /,}"
+
+INPUT_PKGS=()
+for version in ${CUSTOM_RESOURCE_VERSIONS}; do
+  INPUT_PKGS+=("${APIS_PKG}/${CUSTOM_RESOURCE_NAME}/${version}")
+done
+INPUT_PKGS_LIST="$(IFS=,; echo "${INPUT_PKGS[*]}")"
 
 echo "Generating deepcopy funcs"
 go run k8s.io/code-generator/cmd/deepcopy-gen \
   --output-file zz_generated.deepcopy.go \
-  --bounding-dirs "${APIS_PKG}" \
   --go-header-file "${SCRIPT_ROOT}/hack/boilerplate.go.txt" \
-  "${APIS_PKG}/${CUSTOM_RESOURCE_NAME}/${CUSTOM_RESOURCE_VERSION}"
+  "${INPUT_PKGS[@]}"
 
 echo "Generating clientset for ${GROUPS_WITH_VERSIONS} at ${OUTPUT_PKG}/${CLIENTSET_PKG_NAME:-clientset}"
 go run k8s.io/code-generator/cmd/client-gen \
   --clientset-name versioned \
   --input-base "" \
-  --input "${APIS_PKG}/${CUSTOM_RESOURCE_NAME}/${CUSTOM_RESOURCE_VERSION}" \
+  --input "${INPUT_PKGS_LIST}" \
   --output-pkg "${OUTPUT_PKG}/clientset" \
   --go-header-file "${SCRIPT_ROOT}/hack/boilerplate.go.txt" \
   --output-dir "${OUTPUT_DIR}/clientset"
@@ -51,7 +56,7 @@ go run k8s.io/code-generator/cmd/lister-gen \
   --output-pkg "${OUTPUT_PKG}/listers" \
   --go-header-file "${SCRIPT_ROOT}/hack/boilerplate.go.txt" \
   --output-dir "${OUTPUT_DIR}/listers" \
-  "${APIS_PKG}/${CUSTOM_RESOURCE_NAME}/${CUSTOM_RESOURCE_VERSION}"
+  "${INPUT_PKGS[@]}"
 
 echo "Generating informers for ${GROUPS_WITH_VERSIONS} at ${OUTPUT_PKG}/informers"
 go run k8s.io/code-generator/cmd/informer-gen \
@@ -60,4 +65,4 @@ go run k8s.io/code-generator/cmd/informer-gen \
   --output-pkg "${OUTPUT_PKG}/informers" \
   --go-header-file "${SCRIPT_ROOT}/hack/boilerplate.go.txt" \
   --output-dir "${OUTPUT_DIR}/informers" \
-  "${APIS_PKG}/${CUSTOM_RESOURCE_NAME}/${CUSTOM_RESOURCE_VERSION}"
+  "${INPUT_PKGS[@]}"
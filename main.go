@@ -2,16 +2,21 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
+	"text/template"
 	"time"
 
 	kingpin "github.com/alecthomas/kingpin/v2"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	log "github.com/sirupsen/logrus"
 	"github.com/zalando-incubator/kube-aws-iam-controller/pkg/clientset"
@@ -19,20 +24,71 @@ import (
 )
 
 const (
-	defaultInterval        = "10s"
-	defaultRefreshLimit    = "15m"
-	defaultClientGOTimeout = 30 * time.Second
+	defaultInterval                 = "10s"
+	defaultRefreshLimit             = "15m"
+	defaultSTSRetryDeadline         = "10s"
+	defaultCircuitBreakerThreshold  = "5"
+	defaultCircuitBreakerMaxBackoff = "1h"
+	defaultServiceAccountTokenFile  = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	defaultExecPluginTimeout        = "30s"
+	defaultBaseCredentialsInterval  = "1m"
+	defaultExecPluginConcurrency    = "4"
+	defaultVerifyCredentialsTimeout = "5s"
+	defaultClientGOTimeout          = 30 * time.Second
+
+	stsBackend   = "sts"
+	vaultBackend = "vault"
+	execBackend  = "exec"
 )
 
 var (
 	config struct {
-		Debug        bool
-		Interval     time.Duration
-		RefreshLimit time.Duration
-		BaseRoleARN  string
-		APIServer    *url.URL
-		Namespace    string
-		AssumeRole   string
+		Debug                       bool
+		Interval                    time.Duration
+		RefreshLimit                time.Duration
+		BaseRoleARN                 string
+		APIServer                   *url.URL
+		Namespace                   string
+		AssumeRole                  string
+		SessionTags                 map[string]string
+		NamespaceLabelTags          map[string]string
+		AWSIAMRoleLabelTags         map[string]string
+		TransitiveTags              []string
+		AllowedSessionTags          []string
+		SourceIdentity              string
+		SessionName                 string
+		ClusterID                   string
+		WebIdentityToken            string
+		WebIdentityRoleARN          string
+		RolesAnywhereTrustAnchorARN string
+		RolesAnywhereProfileARN     string
+		RolesAnywhereRoleARN        string
+		RolesAnywhereCertificate    string
+		RolesAnywherePrivateKey     string
+		RolesAnywhereEndpoint       string
+		STSRegion                   string
+		STSEndpoint                 string
+		STSFallbacks                []string
+		PartitionProfiles           map[string]string
+		BaseRoleMapping             string
+		CredentialsCache            bool
+		VerifyCredentials           bool
+		VerifyCredentialsTimeout    time.Duration
+		ValidateRoles               bool
+		BaseCredentialsInterval     time.Duration
+		STSRetryDeadline            time.Duration
+		CircuitBreakerThreshold     int
+		CircuitBreakerMaxBackoff    time.Duration
+		CredentialsBackend          string
+		Vault                       VaultConfig
+		ExecPluginCommand           string
+		ExecPluginArgs              []string
+		ExecPluginTimeout           time.Duration
+		ExecPluginConcurrency       int
+		ConversionWebhookAddress    string
+		ConversionWebhookCertFile   string
+		ConversionWebhookKeyFile    string
+		ClusterAWSIAMRoles          bool
 	}
 )
 
@@ -42,13 +98,101 @@ func main() {
 		Default(defaultInterval).DurationVar(&config.Interval)
 	kingpin.Flag("refresh-limit", "Time limit when AWS IAM credentials should be refreshed. I.e. 15 min. before they expire.").
 		Default(defaultRefreshLimit).DurationVar(&config.RefreshLimit)
-	kingpin.Flag("base-role-arn", "Base Role ARN. If not defined it will be autodiscovered from EC2 Metadata.").
+	kingpin.Flag("base-role-arn", "Base Role ARN. If not defined it will be autodiscovered from EC2 Metadata or from sts:GetCallerIdentity when using --web-identity-token-file or --roles-anywhere-trust-anchor-arn.").
 		StringVar(&config.BaseRoleARN)
 	kingpin.Flag("assume-role", "Assume Role can be specified to assume a role at start-up which is used for further assuming other roles managed by the controller.").
 		StringVar(&config.AssumeRole)
+	kingpin.Flag("web-identity-token-file", "Path to a web identity token file, e.g. a projected service account token. If defined the controller gets its own credentials via AssumeRoleWithWebIdentity and doesn't use the EC2 Metadata service.").
+		StringVar(&config.WebIdentityToken)
+	kingpin.Flag("web-identity-role-arn", "Role ARN assumed with the web identity token. Required if --web-identity-token-file is defined.").
+		StringVar(&config.WebIdentityRoleARN)
+	kingpin.Flag("roles-anywhere-trust-anchor-arn", "IAM Roles Anywhere trust anchor ARN. If defined the controller gets its own credentials from IAM Roles Anywhere using --roles-anywhere-certificate and --roles-anywhere-private-key and doesn't use the EC2 Metadata service.").
+		StringVar(&config.RolesAnywhereTrustAnchorARN)
+	kingpin.Flag("roles-anywhere-profile-arn", "IAM Roles Anywhere profile ARN.").
+		StringVar(&config.RolesAnywhereProfileARN)
+	kingpin.Flag("roles-anywhere-role-arn", "Role ARN of the IAM Roles Anywhere session.").
+		StringVar(&config.RolesAnywhereRoleARN)
+	kingpin.Flag("roles-anywhere-certificate", "Path to the PEM encoded X.509 certificate, optionally followed by intermediate certificates, used for IAM Roles Anywhere. The file is read again on every refresh.").
+		StringVar(&config.RolesAnywhereCertificate)
+	kingpin.Flag("roles-anywhere-private-key", "Path to the PEM encoded private key of the IAM Roles Anywhere certificate. The file is read again on every refresh.").
+		StringVar(&config.RolesAnywherePrivateKey)
+	kingpin.Flag("roles-anywhere-endpoint", "IAM Roles Anywhere endpoint URL. If not defined the regional endpoint of the trust anchor is used.").
+		StringVar(&config.RolesAnywhereEndpoint)
+	kingpin.Flag("sts-region", "Region used for STS calls. If not defined it's resolved from the environment.").
+		StringVar(&config.STSRegion)
+	kingpin.Flag("sts-endpoint", "STS endpoint URL, e.g. https://sts.eu-central-1.amazonaws.com. If not defined the regional endpoint of the STS region is used.").
+		StringVar(&config.STSEndpoint)
+	kingpin.Flag("sts-fallback-endpoint", "STS endpoint URL to fall back to on connection errors or server errors. Can be repeated, endpoints are tried in order.").
+		StringsVar(&config.STSFallbacks)
+	kingpin.Flag("partition-profile", "AWS shared config profile providing the credentials and region used for roles in another partition in the format <partition>=<profile>, e.g. aws-cn=china. Can be repeated.").
+		StringMapVar(&config.PartitionProfiles)
+	kingpin.Flag("credentials-cache", "Share credentials between secrets requesting the same role with the same session parameters instead of assuming the role for each of them.").
+		Default("true").BoolVar(&config.CredentialsCache)
+	kingpin.Flag("verify-credentials", "Verify issued credentials with sts:GetCallerIdentity before storing them in secrets. Secrets keep their current credentials if the verification fails.").
+		BoolVar(&config.VerifyCredentials)
+	kingpin.Flag("verify-credentials-timeout", "Maximum time spent retrying the verification of issued credentials which are not valid yet.").
+		Default(defaultVerifyCredentialsTimeout).DurationVar(&config.VerifyCredentialsTimeout)
+	kingpin.Flag("validate-roles", "Validate the roles of AWSIAMRoles with IAM GetRole when they are created or changed and report problems in the RoleValidated condition. Only supported with the sts credentials backend.").
+		BoolVar(&config.ValidateRoles)
+	kingpin.Flag("base-credentials-check-interval", "Interval between checks of the credentials the controller uses to assume roles. Readiness fails while they are unusable. Only used with the sts credentials backend.").
+		Default(defaultBaseCredentialsInterval).DurationVar(&config.BaseCredentialsInterval)
+	kingpin.Flag("sts-retry-deadline", "Maximum time spent retrying throttled or transiently failing STS calls for a single role. Set to 0 to disable retries.").
+		Default(defaultSTSRetryDeadline).DurationVar(&config.STSRetryDeadline)
+	kingpin.Flag("circuit-breaker-threshold", "Number of consecutive permanent failures (e.g. AccessDenied) after which the controller stops getting credentials for an AWSIAMRole until a backoff expires or the AWSIAMRole is changed. Set to 0 to disable.").
+		Default(defaultCircuitBreakerThreshold).IntVar(&config.CircuitBreakerThreshold)
+	kingpin.Flag("circuit-breaker-max-backoff", "Maximum backoff of an open circuit breaker. The backoff starts at --interval and doubles with every failure.").
+		Default(defaultCircuitBreakerMaxBackoff).DurationVar(&config.CircuitBreakerMaxBackoff)
+	kingpin.Flag("base-role-mapping", "Path to a YAML file mapping namespaces and/or role account IDs to a source role which is assumed before assuming the roles of matching AWSIAMRoles.").
+		StringVar(&config.BaseRoleMapping)
+	kingpin.Flag("credentials-backend", "Backend used to get credentials. 'sts' assumes roles with the identity of the controller, 'vault' gets credentials from the AWS secrets engine of Vault, 'exec' gets credentials from an external plugin.").
+		Default(stsBackend).EnumVar(&config.CredentialsBackend, stsBackend, vaultBackend, execBackend)
+	kingpin.Flag("vault-address", "Address of the Vault server used by the vault credentials backend.").
+		Envar("VAULT_ADDR").StringVar(&config.Vault.Address)
+	kingpin.Flag("vault-ca-cert", "Path to a PEM encoded CA certificate used to verify the Vault server certificate.").
+		Envar("VAULT_CACERT").StringVar(&config.Vault.CACert)
+	kingpin.Flag("vault-auth-mount", "Mount path of the Vault Kubernetes auth method.").
+		Default("kubernetes").StringVar(&config.Vault.AuthMount)
+	kingpin.Flag("vault-auth-role", "Role used to log in with the Vault Kubernetes auth method.").
+		StringVar(&config.Vault.AuthRole)
+	kingpin.Flag("vault-token-file", "Path to the service account token used to log in with the Vault Kubernetes auth method.").
+		Default(defaultServiceAccountTokenFile).StringVar(&config.Vault.TokenFile)
+	kingpin.Flag("vault-aws-mount", "Mount path of the Vault AWS secrets engine. The roleReference of an AWSIAMRole is the name of an assumed_role role in it.").
+		Default("aws").StringVar(&config.Vault.AWSMount)
+	kingpin.Flag("exec-plugin-command", "Command of the credentials plugin used by the exec credentials backend.").
+		StringVar(&config.ExecPluginCommand)
+	kingpin.Flag("exec-plugin-arg", "Argument passed to the credentials plugin. Can be repeated.").
+		StringsVar(&config.ExecPluginArgs)
+	kingpin.Flag("exec-plugin-timeout", "Time limit for a single run of the credentials plugin.").
+		Default(defaultExecPluginTimeout).DurationVar(&config.ExecPluginTimeout)
+	kingpin.Flag("exec-plugin-concurrency", "Maximum number of credentials plugin processes running at the same time.").
+		Default(defaultExecPluginConcurrency).IntVar(&config.ExecPluginConcurrency)
+	kingpin.Flag("conversion-webhook-address", "Address of the HTTPS server of the AWSIAMRole conversion webhook, e.g. :8443. The webhook is served on /convert and is required to serve zalando.org/v2. Disabled if not defined.").
+		StringVar(&config.ConversionWebhookAddress)
+	kingpin.Flag("conversion-webhook-cert-file", "Path to the PEM encoded TLS certificate of the conversion webhook.").
+		StringVar(&config.ConversionWebhookCertFile)
+	kingpin.Flag("conversion-webhook-key-file", "Path to the PEM encoded TLS private key of the conversion webhook.").
+		StringVar(&config.ConversionWebhookKeyFile)
+	kingpin.Flag("cluster-awsiamroles", "Provision credentials for ClusterAWSIAMRoles in the namespaces selected by them. Requires the ClusterAWSIAMRole CRD.").
+		BoolVar(&config.ClusterAWSIAMRoles)
 	kingpin.Flag("namespace", "Limit the controller to a certain namespace.").
 		Default(v1.NamespaceAll).StringVar(&config.Namespace)
 	kingpin.Flag("apiserver", "API server url.").URLVar(&config.APIServer)
+	kingpin.Flag("session-tag", "Session tag applied to all sessions in the format <key>=<value>. Can be repeated.").
+		StringMapVar(&config.SessionTags)
+	kingpin.Flag("namespace-label-tag", "Copy a namespace label to a session tag in the format <label>=<tag-key>. Can be repeated.").
+		StringMapVar(&config.NamespaceLabelTags)
+	kingpin.Flag("awsiamrole-label-tag", "Copy an AWSIAMRole label to a session tag in the format <label>=<tag-key>. Can be repeated.").
+		StringMapVar(&config.AWSIAMRoleLabelTags)
+	kingpin.Flag("transitive-session-tag", "Session tag key which should be marked as transitive. Can be repeated.").
+		StringsVar(&config.TransitiveTags)
+	kingpin.Flag("allowed-session-tag", "Session tag key which can be defined in the spec of AWSIAMRoles. Can be repeated.").
+		StringsVar(&config.AllowedSessionTags)
+	kingpin.Flag("source-identity-template", "Go template used to render the SourceIdentity of sessions, e.g. '{{.Namespace}}/{{.Name}}'. Available fields: Namespace, Name, RoleReference.").
+		StringVar(&config.SourceIdentity)
+	kingpin.Flag("session-name-template", "Go template used to render the RoleSessionName of sessions for AWSIAMRoles, e.g. '{{.Cluster}}/{{.Namespace}}/{{.Name}}'. '/' separates levels which are truncated to fit the 64 character limit. Available fields: Cluster, Namespace, Name, RoleReference, RoleName. If not defined the RoleSessionName is derived from the role ARN.").
+		StringVar(&config.SessionName)
+	kingpin.Flag("cluster-id", "ID of the cluster available as Cluster in session templates.").
+		StringVar(&config.ClusterID)
 	kingpin.Parse()
 
 	if config.Debug {
@@ -66,37 +210,46 @@ func main() {
 		log.Fatalf("Failed to initialize Kubernetes client: %v.", err)
 	}
 
-	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background())
-	if err != nil {
-		log.Fatalf("unable to load SDK config, %v", err)
+	var sourceIdentityTemplate *template.Template
+	if config.SourceIdentity != "" {
+		sourceIdentityTemplate, err = template.New("source-identity").Option("missingkey=error").Parse(config.SourceIdentity)
+		if err != nil {
+			log.Fatalf("Failed to parse source identity template: %v", err)
+		}
 	}
 
-	if config.BaseRoleARN == "" {
-		config.BaseRoleARN, err = GetBaseRoleARN(context.Background(), awsCfg)
+	var sessionNameTemplate *template.Template
+	if config.SessionName != "" {
+		sessionNameTemplate, err = template.New("session-name").Option("missingkey=error").Parse(config.SessionName)
 		if err != nil {
-			log.Fatalf("Failed to autodiscover Base Role ARN: %v", err)
+			log.Fatalf("Failed to parse session name template: %v", err)
 		}
+	}
 
-		log.Infof("Autodiscovered Base Role ARN: %s", config.BaseRoleARN)
+	if config.ValidateRoles && config.CredentialsBackend != stsBackend {
+		log.Fatal("--validate-roles is only supported with the sts credentials backend")
 	}
 
-	baseRoleARNPrefix, err := GetPrefixFromARN(config.BaseRoleARN)
-	if err != nil {
-		log.Fatalf("Failed to parse ARN prefix from Base Role ARN: %v", err)
+	if flags := sessionFlags(); len(flags) > 0 && config.CredentialsBackend == vaultBackend {
+		log.Fatalf("%s can't be used with the vault credentials backend", strings.Join(flags, ", "))
 	}
-	log.Debugf("Parsed Base Role ARN prefix: %s", baseRoleARNPrefix)
 
-	if config.AssumeRole != "" {
-		if !strings.HasPrefix(config.AssumeRole, baseRoleARNPrefix) {
-			config.AssumeRole = config.BaseRoleARN + config.AssumeRole
-		}
-		log.Infof("Using custom Assume Role: %s", config.AssumeRole)
-		stssvc := sts.NewFromConfig(awsCfg)
-		creds := stscreds.NewAssumeRoleProvider(stssvc, config.AssumeRole)
-		awsCfg.Credentials = creds
+	var credsGetter CredentialsGetter
+	var validator *RoleValidator
+	var baseCreds *BaseCredentialsMonitor
+	switch config.CredentialsBackend {
+	case vaultBackend:
+		credsGetter = newVaultBackend()
+	case execBackend:
+		credsGetter = newExecBackend()
+	default:
+		credsGetter, validator, baseCreds = newSTSBackend(client)
 	}
 
-	credsGetter := NewSTSCredentialsGetter(awsCfg, config.BaseRoleARN, baseRoleARNPrefix)
+	var breaker *CircuitBreaker
+	if config.CircuitBreakerThreshold > 0 {
+		breaker = NewCircuitBreaker(config.CircuitBreakerThreshold, config.Interval, config.CircuitBreakerMaxBackoff)
+	}
 
 	controller := NewSecretsController(
 		client,
@@ -108,19 +261,382 @@ func main() {
 
 	go handleSigterm(cancel)
 
+	if config.ConversionWebhookAddress != "" {
+		if config.ConversionWebhookCertFile == "" || config.ConversionWebhookKeyFile == "" {
+			log.Fatal("--conversion-webhook-cert-file and --conversion-webhook-key-file must be defined when using --conversion-webhook-address")
+		}
+
+		server := newConversionWebhookServer(config.ConversionWebhookAddress, config.ConversionWebhookCertFile, config.ConversionWebhookKeyFile)
+		go func() {
+			log.Infof("Serving AWSIAMRole conversion webhook on %s", config.ConversionWebhookAddress)
+			err := server.ListenAndServeTLS("", "")
+			if err != nil {
+				log.Fatalf("Failed to serve conversion webhook: %v", err)
+			}
+		}()
+	}
+
+	if baseCreds != nil {
+		controller.AddReadinessCheck("baseCredentials", baseCreds.Err)
+		http.Handle("/base-credentials", baseCreds)
+		go baseCreds.Run(ctx)
+	}
+
+	sessionConfig := &SessionConfig{
+		Tags:                   config.SessionTags,
+		NamespaceLabelTags:     config.NamespaceLabelTags,
+		AWSIAMRoleLabelTags:    config.AWSIAMRoleLabelTags,
+		TransitiveTags:         config.TransitiveTags,
+		AllowedSpecTags:        config.AllowedSessionTags,
+		SourceIdentityTemplate: sourceIdentityTemplate,
+		SessionNameTemplate:    sessionNameTemplate,
+		ClusterID:              config.ClusterID,
+	}
+
 	awsIAMRoleController := NewAWSIAMRoleController(
 		client,
 		config.Interval,
 		config.RefreshLimit,
 		credsGetter,
 		config.Namespace,
+		sessionConfig,
+		breaker,
+		validator,
+		baseCreds,
 	)
 
 	go awsIAMRoleController.Run(ctx)
 
+	if config.ClusterAWSIAMRoles {
+		// ClusterAWSIAMRoles get their own circuit breaker as each
+		// controller prunes the state of the resources it doesn't know.
+		var clusterBreaker *CircuitBreaker
+		if config.CircuitBreakerThreshold > 0 {
+			clusterBreaker = NewCircuitBreaker(config.CircuitBreakerThreshold, config.Interval, config.CircuitBreakerMaxBackoff)
+		}
+
+		clusterAWSIAMRoleController := NewClusterAWSIAMRoleController(
+			client,
+			config.Interval,
+			config.RefreshLimit,
+			credsGetter,
+			config.Namespace,
+			sessionConfig,
+			clusterBreaker,
+			baseCreds,
+		)
+
+		go clusterAWSIAMRoleController.Run(ctx)
+	}
+
 	controller.Run(ctx)
 }
 
+// newSTSBackend sets up the credentials getter assuming roles via STS with
+// the identity of the controller, the role validator if enabled and the
+// monitor of the controller's own credentials.
+func newSTSBackend(client clientset.Interface) (CredentialsGetter, *RoleValidator, *BaseCredentialsMonitor) {
+	rolesAnywhere := config.RolesAnywhereTrustAnchorARN != ""
+	if rolesAnywhere && config.WebIdentityToken != "" {
+		log.Fatal("--web-identity-token-file and --roles-anywhere-trust-anchor-arn can't be used together")
+	}
+
+	var loadOpts []func(*awsconfig.LoadOptions) error
+	if config.WebIdentityToken != "" || rolesAnywhere {
+		loadOpts = append(loadOpts, awsconfig.WithEC2IMDSClientEnableState(imds.ClientDisabled))
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), loadOpts...)
+	if err != nil {
+		log.Fatalf("unable to load SDK config, %v", err)
+	}
+
+	if config.STSRegion != "" {
+		awsCfg.Region = config.STSRegion
+	}
+
+	if config.WebIdentityToken != "" {
+		if config.WebIdentityRoleARN == "" {
+			log.Fatal("--web-identity-role-arn must be defined when using --web-identity-token-file")
+		}
+		log.Infof("Using web identity token %s to assume role: %s", config.WebIdentityToken, config.WebIdentityRoleARN)
+		stssvc := sts.NewFromConfig(awsCfg, WithSTSEndpoint(config.STSEndpoint))
+		creds := stscreds.NewWebIdentityRoleProvider(stssvc, config.WebIdentityRoleARN, stscreds.IdentityTokenFile(config.WebIdentityToken))
+		awsCfg.Credentials = aws.NewCredentialsCache(creds)
+	}
+
+	if rolesAnywhere {
+		if config.RolesAnywhereProfileARN == "" || config.RolesAnywhereRoleARN == "" || config.RolesAnywhereCertificate == "" || config.RolesAnywherePrivateKey == "" {
+			log.Fatal("--roles-anywhere-profile-arn, --roles-anywhere-role-arn, --roles-anywhere-certificate and --roles-anywhere-private-key must be defined when using --roles-anywhere-trust-anchor-arn")
+		}
+		log.Infof("Using IAM Roles Anywhere trust anchor %s to get credentials for role: %s", config.RolesAnywhereTrustAnchorARN, config.RolesAnywhereRoleARN)
+		creds, err := NewRolesAnywhereCredentialsProvider(
+			config.RolesAnywhereTrustAnchorARN,
+			config.RolesAnywhereProfileARN,
+			config.RolesAnywhereRoleARN,
+			config.RolesAnywhereCertificate,
+			config.RolesAnywherePrivateKey,
+			config.RolesAnywhereEndpoint,
+		)
+		if err != nil {
+			log.Fatalf("Failed to set up IAM Roles Anywhere: %v", err)
+		}
+		awsCfg.Credentials = aws.NewCredentialsCache(creds)
+	}
+
+	if config.BaseRoleARN == "" {
+		if config.WebIdentityToken != "" || rolesAnywhere {
+			config.BaseRoleARN, err = GetBaseRoleARNFromCallerIdentity(context.Background(), awsCfg, WithSTSEndpoint(config.STSEndpoint))
+		} else {
+			config.BaseRoleARN, err = GetBaseRoleARN(context.Background(), awsCfg)
+		}
+		if err != nil {
+			log.Fatalf("Failed to autodiscover Base Role ARN: %v", err)
+		}
+
+		log.Infof("Autodiscovered Base Role ARN: %s", config.BaseRoleARN)
+	}
+
+	baseRoleARNPrefix, err := GetPrefixFromARN(config.BaseRoleARN)
+	if err != nil {
+		log.Fatalf("Failed to parse ARN prefix from Base Role ARN: %v", err)
+	}
+	log.Debugf("Parsed Base Role ARN prefix: %s", baseRoleARNPrefix)
+
+	if config.AssumeRole != "" {
+		if !strings.HasPrefix(config.AssumeRole, baseRoleARNPrefix) {
+			config.AssumeRole = config.BaseRoleARN + config.AssumeRole
+		}
+		log.Infof("Using custom Assume Role: %s", config.AssumeRole)
+		stssvc := sts.NewFromConfig(awsCfg, WithSTSEndpoint(config.STSEndpoint))
+		creds := stscreds.NewAssumeRoleProvider(stssvc, config.AssumeRole)
+		awsCfg.Credentials = aws.NewCredentialsCache(creds)
+	}
+
+	baseCreds := NewBaseCredentialsMonitor(awsCfg, config.BaseCredentialsInterval, WithSTSEndpoint(config.STSEndpoint))
+
+	stsEndpoints := append([]string{config.STSEndpoint}, config.STSFallbacks...)
+	getterCfg := credentialsGetterConfig(awsCfg)
+	credsGetter := verifyCredentialsGetter(NewSTSCredentialsGetter(getterCfg, config.BaseRoleARN, baseRoleARNPrefix, stsEndpoints...), getterCfg, WithSTSEndpoint(config.STSEndpoint))
+
+	if len(config.PartitionProfiles) > 0 {
+		defaultPartition, err := rolePartition(config.BaseRoleARN, "")
+		if err != nil {
+			log.Fatalf("Failed to parse partition from Base Role ARN: %v", err)
+		}
+
+		getters := map[string]CredentialsGetter{
+			defaultPartition: credsGetter,
+		}
+		for partition, profile := range config.PartitionProfiles {
+			if partition == defaultPartition {
+				log.Fatalf("Partition '%s' is the partition of the Base Role ARN and can't be configured via a profile", partition)
+			}
+
+			getters[partition], err = newProfileCredentialsGetter(context.Background(), partition, profile)
+			if err != nil {
+				log.Fatalf("Failed to set up credentials for partition '%s': %v", partition, err)
+			}
+			log.Infof("Using profile '%s' for roles in partition '%s'", profile, partition)
+		}
+		credsGetter = NewPartitionCredentialsGetter(defaultPartition, getters)
+	}
+
+	credsGetter = wrapCredentialsGetter(credsGetter)
+
+	var principals []string
+	if config.ValidateRoles {
+		identity, err := sts.NewFromConfig(awsCfg, WithSTSEndpoint(config.STSEndpoint)).GetCallerIdentity(context.Background(), &sts.GetCallerIdentityInput{})
+		if err != nil {
+			log.Fatalf("Failed to get caller identity for role validation: %v", err)
+		}
+		principals = append(principals, aws.ToString(identity.Arn))
+	}
+
+	if config.BaseRoleMapping != "" {
+		mappings, err := LoadBaseRoleMappings(config.BaseRoleMapping)
+		if err != nil {
+			log.Fatalf("Failed to load base role mappings: %v", err)
+		}
+
+		defaultAccountID, err := roleAccountID(config.BaseRoleARN, "")
+		if err != nil {
+			log.Fatalf("Failed to parse account ID from Base Role ARN: %v", err)
+		}
+
+		mappingGetter := NewMappingCredentialsGetter(client, defaultAccountID, credsGetter)
+		for _, mapping := range mappings {
+			getter := verifyCredentialsGetter(newSourceRoleCredentialsGetter(getterCfg, mapping.SourceRole, baseRoleARNPrefix, stsEndpoints), getterCfg, WithSTSEndpoint(config.STSEndpoint))
+			err := mappingGetter.Add(mapping, wrapCredentialsGetter(getter))
+			if err != nil {
+				log.Fatalf("Failed to set up base role mapping: %v", err)
+			}
+			log.Infof("Using source role '%s' for base role mapping '%s'", mapping.SourceRole, mapping.Name)
+			principals = append(principals, mapping.SourceRole)
+		}
+		credsGetter = mappingGetter
+	}
+
+	if !config.ValidateRoles {
+		return credsGetter, nil, baseCreds
+	}
+
+	validator, err := NewRoleValidator(awsCfg, config.BaseRoleARN, principals)
+	if err != nil {
+		log.Fatalf("Failed to set up role validation: %v", err)
+	}
+	log.Infof("Validating roles assumed by %s", strings.Join(principals, ", "))
+	return credsGetter, validator, baseCreds
+}
+
+// newVaultBackend sets up the credentials getter getting credentials from
+// the AWS secrets engine of Vault.
+func newVaultBackend() CredentialsGetter {
+	if config.Vault.Address == "" || config.Vault.AuthRole == "" {
+		log.Fatal("--vault-address and --vault-auth-role must be defined when using the vault credentials backend")
+	}
+
+	log.Infof("Using Vault %s with auth role '%s' to get credentials", config.Vault.Address, config.Vault.AuthRole)
+	credsGetter, err := NewVaultCredentialsGetter(config.Vault)
+	if err != nil {
+		log.Fatalf("Failed to set up Vault credentials backend: %v", err)
+	}
+	return wrapCredentialsGetter(verifyCredentialsGetter(credsGetter, loadVerificationConfig()))
+}
+
+// newExecBackend sets up the credentials getter getting credentials from an
+// external plugin.
+func newExecBackend() CredentialsGetter {
+	if config.ExecPluginCommand == "" {
+		log.Fatal("--exec-plugin-command must be defined when using the exec credentials backend")
+	}
+
+	log.Infof("Using credentials plugin %s to get credentials", config.ExecPluginCommand)
+	credsGetter := NewExecCredentialsGetter(config.ExecPluginCommand, config.ExecPluginArgs, config.ExecPluginTimeout, config.ExecPluginConcurrency)
+	return wrapCredentialsGetter(verifyCredentialsGetter(credsGetter, loadVerificationConfig()))
+}
+
+// verifyCredentialsGetter adds verification of issued credentials to a
+// credentials getter if enabled. The STS client used for the verification is
+// configured from the AWS config and options.
+func verifyCredentialsGetter(getter CredentialsGetter, cfg aws.Config, optFns ...func(*sts.Options)) CredentialsGetter {
+	if !config.VerifyCredentials {
+		return getter
+	}
+	return NewVerifyingCredentialsGetter(getter, cfg, config.VerifyCredentialsTimeout, optFns...)
+}
+
+// loadVerificationConfig loads the AWS config used to verify credentials of
+// backends which don't use the identity of the controller.
+func loadVerificationConfig() aws.Config {
+	if !config.VerifyCredentials {
+		return aws.Config{}
+	}
+
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background())
+	if err != nil {
+		log.Fatalf("unable to load SDK config, %v", err)
+	}
+
+	if config.STSRegion != "" {
+		cfg.Region = config.STSRegion
+	}
+
+	if cfg.Region == "" {
+		log.Fatal("--sts-region must be defined to verify credentials if the region can't be resolved from the environment")
+	}
+	return cfg
+}
+
+// sessionFlags returns the set flags configuring session tags, source
+// identities and session names.
+func sessionFlags() []string {
+	var flags []string
+	if len(config.SessionTags) > 0 {
+		flags = append(flags, "--session-tag")
+	}
+	if len(config.NamespaceLabelTags) > 0 {
+		flags = append(flags, "--namespace-label-tag")
+	}
+	if len(config.AWSIAMRoleLabelTags) > 0 {
+		flags = append(flags, "--awsiamrole-label-tag")
+	}
+	if len(config.AllowedSessionTags) > 0 {
+		flags = append(flags, "--allowed-session-tag")
+	}
+	if config.SourceIdentity != "" {
+		flags = append(flags, "--source-identity-template")
+	}
+	if config.SessionName != "" {
+		flags = append(flags, "--session-name-template")
+	}
+	return flags
+}
+
+// wrapCredentialsGetter adds retries and caching to a credentials getter as
+// configured.
+func wrapCredentialsGetter(getter CredentialsGetter) CredentialsGetter {
+	if config.STSRetryDeadline > 0 {
+		getter = NewRetryingCredentialsGetter(getter, config.STSRetryDeadline)
+	}
+
+	if config.CredentialsCache {
+		getter = NewCachingCredentialsGetter(getter, config.RefreshLimit+config.Interval)
+	}
+	return getter
+}
+
+// credentialsGetterConfig returns the AWS config of the clients used by
+// credentials getters. The retries of the SDK are disabled if the credentials
+// getters are retried by wrapCredentialsGetter.
+func credentialsGetterConfig(cfg aws.Config) aws.Config {
+	if config.STSRetryDeadline > 0 {
+		return withoutSDKRetries(cfg)
+	}
+	return cfg
+}
+
+// newSourceRoleCredentialsGetter initializes an STS credentials getter which
+// assumes roles with the credentials of a source role. Role names are
+// resolved relative to the Base Role ARN.
+func newSourceRoleCredentialsGetter(cfg aws.Config, sourceRole, baseRoleARNPrefix string, endpoints []string) *STSCredentialsGetter {
+	stssvc := sts.NewFromConfig(cfg, WithSTSEndpoint(endpoints[0]))
+	cfg = cfg.Copy()
+	cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(stssvc, sourceRole))
+	return NewSTSCredentialsGetter(cfg, config.BaseRoleARN, baseRoleARNPrefix, endpoints...)
+}
+
+// newProfileCredentialsGetter initializes an STS credentials getter for roles
+// in a partition using the credentials and region of a shared config profile.
+func newProfileCredentialsGetter(ctx context.Context, partition, profile string) (CredentialsGetter, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithSharedConfigProfile(profile))
+	if err != nil {
+		return nil, err
+	}
+
+	baseRoleARN, err := GetBaseRoleARNFromCallerIdentity(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caller identity: %w", err)
+	}
+
+	profilePartition, err := rolePartition(baseRoleARN, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if profilePartition != partition {
+		return nil, fmt.Errorf("profile '%s' has credentials for partition '%s'", profile, profilePartition)
+	}
+
+	baseRoleARNPrefix, err := GetPrefixFromARN(baseRoleARN)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg = credentialsGetterConfig(cfg)
+	return verifyCredentialsGetter(NewSTSCredentialsGetter(cfg, baseRoleARN, baseRoleARNPrefix), cfg), nil
+}
+
 // handleSigterm handles SIGTERM signal sent to the process.
 func handleSigterm(cancelFunc func()) {
 	signals := make(chan os.Signal, 1)
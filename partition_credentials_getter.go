@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// errPartitionNotConfigured is returned when credentials are requested for a
+// role in a partition for which no credentials are configured.
+var errPartitionNotConfigured = errors.New("no credentials configured for partition")
+
+// PartitionCredentialsGetter is a credentials getter which routes requests
+// for full role ARNs to the credentials getter configured for the partition
+// of the role. Role names are handled by the getter of the default partition.
+type PartitionCredentialsGetter struct {
+	defaultPartition string
+	getters          map[string]CredentialsGetter
+}
+
+// NewPartitionCredentialsGetter initializes a new partition aware credentials
+// getter. getters maps partitions e.g. "aws-cn" to the credentials getter
+// used for roles in that partition.
+func NewPartitionCredentialsGetter(defaultPartition string, getters map[string]CredentialsGetter) *PartitionCredentialsGetter {
+	return &PartitionCredentialsGetter{
+		defaultPartition: defaultPartition,
+		getters:          getters,
+	}
+}
+
+// Get gets new credentials for the specified role from the credentials getter
+// of the partition of the role.
+func (g *PartitionCredentialsGetter) Get(ctx context.Context, role string, sessionDuration time.Duration, opts CredentialsOptions) (*Credentials, error) {
+	partition, err := rolePartition(role, g.defaultPartition)
+	if err != nil {
+		return nil, err
+	}
+
+	getter, ok := g.getters[partition]
+	if !ok {
+		return nil, fmt.Errorf("%w '%s' of role '%s'", errPartitionNotConfigured, partition, role)
+	}
+
+	return getter.Get(ctx, role, sessionDuration, opts)
+}
+
+// rolePartition returns the partition of a role reference. For role names
+// the default partition is returned.
+func rolePartition(role, defaultPartition string) (string, error) {
+	if !strings.HasPrefix(role, arnPrefix) {
+		return defaultPartition, nil
+	}
+
+	roleARN, err := arn.Parse(role)
+	if err != nil {
+		return "", fmt.Errorf("invalid role ARN '%s': %w", role, err)
+	}
+	return roleARN.Partition, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartitionCredentialsGetter(tt *testing.T) {
+	getter := NewPartitionCredentialsGetter("aws", map[string]CredentialsGetter{
+		"aws":    &mockCredsGetter{creds: &Credentials{RoleARN: "aws"}},
+		"aws-cn": &mockCredsGetter{creds: &Credentials{RoleARN: "aws-cn"}},
+	})
+
+	for _, tc := range []struct {
+		msg            string
+		role           string
+		expectedGetter string
+		expectedErr    error
+		expectedAnyErr bool
+	}{
+		{
+			msg:            "role name uses default partition",
+			role:           "role-name",
+			expectedGetter: "aws",
+		},
+		{
+			msg:            "full ARN in default partition",
+			role:           "arn:aws:iam::012345678910:role/role-name",
+			expectedGetter: "aws",
+		},
+		{
+			msg:            "full ARN in other partition",
+			role:           "arn:aws-cn:iam::012345678910:role/role-name",
+			expectedGetter: "aws-cn",
+		},
+		{
+			msg:         "partition not configured",
+			role:        "arn:aws-us-gov:iam::012345678910:role/role-name",
+			expectedErr: errPartitionNotConfigured,
+		},
+		{
+			msg:            "invalid ARN",
+			role:           "arn:invalid",
+			expectedAnyErr: true,
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			creds, err := getter.Get(context.Background(), tc.role, time.Hour, CredentialsOptions{})
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			if tc.expectedAnyErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedGetter, creds.RoleARN)
+		})
+	}
+}
@@ -27,6 +27,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&AWSIAMRole{},
 		&AWSIAMRoleList{},
+		&ClusterAWSIAMRole{},
+		&ClusterAWSIAMRoleList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
@@ -1,6 +1,7 @@
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -23,6 +24,78 @@ type AWSIAMRole struct {
 type AWSIAMRoleSpec struct {
 	RoleReference       string `json:"roleReference"`
 	RoleSessionDuration int64  `json:"roleSessionDuration"`
+	// externalID is passed as sts:ExternalId when assuming the role.
+	// +optional
+	ExternalID string `json:"externalID,omitempty"`
+	// externalIDSecretRef references a key in a Secret in the same
+	// namespace holding the ExternalID. If set it takes precedence over
+	// externalID.
+	// +optional
+	ExternalIDSecretRef *corev1.SecretKeySelector `json:"externalIDSecretRef,omitempty"`
+	// sessionPolicy is an inline session policy used to scope down the
+	// permissions of the role.
+	// +optional
+	SessionPolicy *SessionPolicy `json:"sessionPolicy,omitempty"`
+	// policyARNs are the ARNs of managed policies used as session
+	// policies to scope down the permissions of the role.
+	// +optional
+	PolicyARNs []string `json:"policyARNs,omitempty"`
+	// assumeRoleChain is an ordered list of references to intermediate
+	// roles which are assumed before assuming the role. The session
+	// duration of chained roles is limited to one hour.
+	// +optional
+	AssumeRoleChain []string `json:"assumeRoleChain,omitempty"`
+	// sessionName is appended as the last level of the RoleSessionName
+	// used when assuming the role.
+	// +optional
+	SessionName string `json:"sessionName,omitempty"`
+	// sessionTags are session tags applied when assuming the role. Tags
+	// configured for the controller take precedence.
+	// +optional
+	SessionTags []SessionTag `json:"sessionTags,omitempty"`
+	// secretFormats limits the credentials formats stored in the secret to
+	// any of credentials, credentials.process and credentials.json. All
+	// formats are stored by default.
+	// +optional
+	SecretFormats []string `json:"secretFormats,omitempty"`
+	// secretTemplate defines the name, labels, annotations and type of the
+	// secret.
+	// +optional
+	SecretTemplate *SecretTemplate `json:"secretTemplate,omitempty"`
+}
+
+// SecretTemplate defines the metadata and the type of the secret the
+// credentials are stored in.
+// +k8s:deepcopy-gen=true
+type SecretTemplate struct {
+	// name is the name of the secret. Defaults to the name of the
+	// AWSIAMRole.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// labels are added to the labels copied from the AWSIAMRole.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// annotations are added to the annotations of the secret.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// type is the type of the secret. Defaults to Opaque. Types defined by
+	// Kubernetes other than Opaque are not supported.
+	// +optional
+	Type corev1.SecretType `json:"type,omitempty"`
+}
+
+// SessionPolicy defines an inline session policy either directly or via a
+// reference to a ConfigMap.
+// +k8s:deepcopy-gen=true
+type SessionPolicy struct {
+	// inline is the JSON policy document.
+	// +optional
+	Inline string `json:"inline,omitempty"`
+	// configMapRef references a key in a ConfigMap in the same namespace
+	// holding the JSON policy document. If set it takes precedence over
+	// inline.
+	// +optional
+	ConfigMapRef *corev1.ConfigMapKeySelector `json:"configMapRef,omitempty"`
 }
 
 // AWSIAMRoleStatus is the status section of the AWSIAMRole resource.
@@ -36,6 +109,86 @@ type AWSIAMRoleStatus struct {
 	ObservedGeneration *int64       `json:"observedGeneration,omitempty" protobuf:"varint,1,opt,name=observedGeneration"`
 	RoleARN            string       `json:"roleARN"`
 	Expiration         *metav1.Time `json:"expiration"`
+	// roleSessionDuration is the effective session duration in seconds of
+	// the current credentials. It can be lower than requested in the spec
+	// e.g. when using role chaining.
+	// +optional
+	RoleSessionDuration int64 `json:"roleSessionDuration,omitempty"`
+	// sessionTags are the session tags applied when the current
+	// credentials were issued.
+	// +optional
+	SessionTags []SessionTag `json:"sessionTags,omitempty"`
+	// stsEndpoint is the URL of the STS endpoint which issued the current
+	// credentials.
+	// +optional
+	STSEndpoint string `json:"stsEndpoint,omitempty"`
+	// circuitBreaker describes the consecutive permanent failures to get
+	// credentials for the role. It's unset if the last attempt succeeded.
+	// +optional
+	CircuitBreaker *CircuitBreakerStatus `json:"circuitBreaker,omitempty"`
+	// lastFailure describes the last failure to get credentials for the
+	// role. It's unset if the last attempt succeeded.
+	// +optional
+	LastFailure *FailureStatus `json:"lastFailure,omitempty"`
+	// lastRefreshTime is the time the current credentials were issued and
+	// stored in the secret.
+	// +optional
+	LastRefreshTime *metav1.Time `json:"lastRefreshTime,omitempty"`
+	// nextRefreshTime is the time the credentials are refreshed next.
+	// After a failure it's the time of the next attempt.
+	// +optional
+	NextRefreshTime *metav1.Time `json:"nextRefreshTime,omitempty"`
+	// conditions describe the state of the AWSIAMRole. Ready reports
+	// whether the secret holds unexpired credentials for the current
+	// generation, CredentialsIssued and SecretSynced report the result of
+	// the last attempt to issue credentials and store them in the secret
+	// and Degraded whether either of them failed. The RoleValidated
+	// condition reports the result of validating the role with IAM.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// CircuitBreakerStatus describes the state of the circuit breaker which stops
+// requesting credentials after consecutive permanent failures.
+// +k8s:deepcopy-gen=true
+type CircuitBreakerStatus struct {
+	// state is either Closed or Open. While Open no credentials are
+	// requested until retryAfter.
+	State string `json:"state"`
+	// consecutiveFailures is the number of consecutive permanent failures.
+	ConsecutiveFailures int32 `json:"consecutiveFailures"`
+	// lastError is the error of the last failed attempt.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+	// retryAfter is the time of the next attempt while the circuit breaker
+	// is open.
+	// +optional
+	RetryAfter *metav1.Time `json:"retryAfter,omitempty"`
+}
+
+// FailureStatus describes a failure to get credentials.
+// +k8s:deepcopy-gen=true
+type FailureStatus struct {
+	// reason is a machine-readable reason of the failure. One of
+	// AccessDenied, RoleNotFound, Throttled, RegionDisabled,
+	// InvalidDuration, ExpiredBaseCredentials, NetworkError,
+	// VerificationFailed or Unknown.
+	Reason string `json:"reason"`
+	// message is the error of the failure.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// time is the time of the failure.
+	Time metav1.Time `json:"time"`
+}
+
+// SessionTag is a session tag applied when assuming an AWS IAM role.
+// +k8s:deepcopy-gen=true
+type SessionTag struct {
+	Key        string `json:"key"`
+	Value      string `json:"value"`
+	Transitive bool   `json:"transitive,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -48,3 +201,150 @@ type AWSIAMRoleList struct {
 
 	Items []AWSIAMRole `json:"items"`
 }
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterAWSIAMRole describes an AWS IAM Role for which credentials are
+// provisioned in all namespaces matching a namespace selector.
+// +k8s:deepcopy-gen=true
+type ClusterAWSIAMRole struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterAWSIAMRoleSpec   `json:"spec"`
+	Status ClusterAWSIAMRoleStatus `json:"status"`
+}
+
+// ClusterAWSIAMRoleSpec is the spec part of the ClusterAWSIAMRole resource.
+// The role is assumed once per refresh and the credentials are stored in a
+// secret named after the ClusterAWSIAMRole in every selected namespace.
+// +k8s:deepcopy-gen=true
+type ClusterAWSIAMRoleSpec struct {
+	// namespaceSelector selects the namespaces the credentials are
+	// provisioned in. An empty selector selects all namespaces. Only base
+	// role mappings without namespace selector apply, namespaces matched by
+	// a different base role mapping don't get the credentials.
+	NamespaceSelector metav1.LabelSelector `json:"namespaceSelector"`
+	RoleReference     string               `json:"roleReference"`
+	// +optional
+	RoleSessionDuration int64 `json:"roleSessionDuration,omitempty"`
+	// externalID is passed as sts:ExternalId when assuming the role.
+	// +optional
+	ExternalID string `json:"externalID,omitempty"`
+	// sessionPolicy is an inline session policy used to scope down the
+	// permissions of the role.
+	// +optional
+	SessionPolicy *ClusterSessionPolicy `json:"sessionPolicy,omitempty"`
+	// policyARNs are the ARNs of managed policies used as session
+	// policies to scope down the permissions of the role.
+	// +optional
+	PolicyARNs []string `json:"policyARNs,omitempty"`
+	// assumeRoleChain is an ordered list of references to intermediate
+	// roles which are assumed before assuming the role.
+	// +optional
+	AssumeRoleChain []string `json:"assumeRoleChain,omitempty"`
+	// sessionName is appended as the last level of the RoleSessionName
+	// used when assuming the role.
+	// +optional
+	SessionName string `json:"sessionName,omitempty"`
+	// sessionTags are session tags applied when assuming the role. Tags
+	// configured for the controller take precedence.
+	// +optional
+	SessionTags []SessionTag `json:"sessionTags,omitempty"`
+	// secretFormats limits the credentials formats stored in the secrets.
+	// +optional
+	SecretFormats []string `json:"secretFormats,omitempty"`
+}
+
+// ClusterSessionPolicy is the session policy of a ClusterAWSIAMRole. Unlike
+// SessionPolicy it can't reference a ConfigMap as the ClusterAWSIAMRole has
+// no namespace.
+// +k8s:deepcopy-gen=true
+type ClusterSessionPolicy struct {
+	// inline is the JSON policy document.
+	// +optional
+	Inline string `json:"inline,omitempty"`
+}
+
+// ClusterAWSIAMRoleStatus is the status section of the ClusterAWSIAMRole
+// resource.
+// +k8s:deepcopy-gen=true
+type ClusterAWSIAMRoleStatus struct {
+	// observedGeneration is the generation the current credentials were
+	// issued for.
+	// +optional
+	ObservedGeneration *int64 `json:"observedGeneration,omitempty"`
+	// roleARN is the ARN of the role of the current credentials.
+	// +optional
+	RoleARN string `json:"roleARN,omitempty"`
+	// expiration is the expiry time of the current credentials.
+	// +optional
+	Expiration *metav1.Time `json:"expiration,omitempty"`
+	// roleSessionDuration is the effective session duration in seconds of
+	// the current credentials.
+	// +optional
+	RoleSessionDuration int64 `json:"roleSessionDuration,omitempty"`
+	// sessionTags are the session tags applied when the current
+	// credentials were issued.
+	// +optional
+	SessionTags []SessionTag `json:"sessionTags,omitempty"`
+	// stsEndpoint is the URL of the STS endpoint which issued the current
+	// credentials.
+	// +optional
+	STSEndpoint string `json:"stsEndpoint,omitempty"`
+	// circuitBreaker describes the consecutive permanent failures to get
+	// credentials for the role.
+	// +optional
+	CircuitBreaker *CircuitBreakerStatus `json:"circuitBreaker,omitempty"`
+	// lastFailure describes the last failure to get credentials for the
+	// role.
+	// +optional
+	LastFailure *FailureStatus `json:"lastFailure,omitempty"`
+	// lastRefreshTime is the time the current credentials were issued.
+	// +optional
+	LastRefreshTime *metav1.Time `json:"lastRefreshTime,omitempty"`
+	// nextRefreshTime is the time the credentials are refreshed next.
+	// +optional
+	NextRefreshTime *metav1.Time `json:"nextRefreshTime,omitempty"`
+	// namespaces describe the secrets in the selected namespaces.
+	// +optional
+	// +listType=map
+	// +listMapKey=namespace
+	Namespaces []NamespaceSyncStatus `json:"namespaces,omitempty"`
+	// conditions describe the state of the ClusterAWSIAMRole. Ready
+	// reports whether the secrets in all selected namespaces hold
+	// unexpired credentials for the current generation.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// NamespaceSyncStatus describes the secret of a ClusterAWSIAMRole in a
+// selected namespace.
+// +k8s:deepcopy-gen=true
+type NamespaceSyncStatus struct {
+	Namespace string `json:"namespace"`
+	// synced is true if the secret holds the current credentials.
+	Synced bool `json:"synced"`
+	// message describes why the secret could not be synced.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// lastSyncTime is the time credentials were last stored in the
+	// secret.
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterAWSIAMRoleList is a list of ClusterAWSIAMRoles.
+// +k8s:deepcopy-gen=true
+type ClusterAWSIAMRoleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ClusterAWSIAMRole `json:"items"`
+}
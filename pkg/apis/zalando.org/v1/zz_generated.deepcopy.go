@@ -22,6 +22,8 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -30,7 +32,7 @@ func (in *AWSIAMRole) DeepCopyInto(out *AWSIAMRole) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 	return
 }
@@ -89,6 +91,41 @@ func (in *AWSIAMRoleList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AWSIAMRoleSpec) DeepCopyInto(out *AWSIAMRoleSpec) {
 	*out = *in
+	if in.ExternalIDSecretRef != nil {
+		in, out := &in.ExternalIDSecretRef, &out.ExternalIDSecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SessionPolicy != nil {
+		in, out := &in.SessionPolicy, &out.SessionPolicy
+		*out = new(SessionPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PolicyARNs != nil {
+		in, out := &in.PolicyARNs, &out.PolicyARNs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AssumeRoleChain != nil {
+		in, out := &in.AssumeRoleChain, &out.AssumeRoleChain
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SessionTags != nil {
+		in, out := &in.SessionTags, &out.SessionTags
+		*out = make([]SessionTag, len(*in))
+		copy(*out, *in)
+	}
+	if in.SecretFormats != nil {
+		in, out := &in.SecretFormats, &out.SecretFormats
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SecretTemplate != nil {
+		in, out := &in.SecretTemplate, &out.SecretTemplate
+		*out = new(SecretTemplate)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -114,6 +151,36 @@ func (in *AWSIAMRoleStatus) DeepCopyInto(out *AWSIAMRoleStatus) {
 		in, out := &in.Expiration, &out.Expiration
 		*out = (*in).DeepCopy()
 	}
+	if in.SessionTags != nil {
+		in, out := &in.SessionTags, &out.SessionTags
+		*out = make([]SessionTag, len(*in))
+		copy(*out, *in)
+	}
+	if in.CircuitBreaker != nil {
+		in, out := &in.CircuitBreaker, &out.CircuitBreaker
+		*out = new(CircuitBreakerStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastFailure != nil {
+		in, out := &in.LastFailure, &out.LastFailure
+		*out = new(FailureStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastRefreshTime != nil {
+		in, out := &in.LastRefreshTime, &out.LastRefreshTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextRefreshTime != nil {
+		in, out := &in.NextRefreshTime, &out.NextRefreshTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -126,3 +193,308 @@ func (in *AWSIAMRoleStatus) DeepCopy() *AWSIAMRoleStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CircuitBreakerStatus) DeepCopyInto(out *CircuitBreakerStatus) {
+	*out = *in
+	if in.RetryAfter != nil {
+		in, out := &in.RetryAfter, &out.RetryAfter
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CircuitBreakerStatus.
+func (in *CircuitBreakerStatus) DeepCopy() *CircuitBreakerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CircuitBreakerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterAWSIAMRole) DeepCopyInto(out *ClusterAWSIAMRole) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterAWSIAMRole.
+func (in *ClusterAWSIAMRole) DeepCopy() *ClusterAWSIAMRole {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterAWSIAMRole)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterAWSIAMRole) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterAWSIAMRoleList) DeepCopyInto(out *ClusterAWSIAMRoleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterAWSIAMRole, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterAWSIAMRoleList.
+func (in *ClusterAWSIAMRoleList) DeepCopy() *ClusterAWSIAMRoleList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterAWSIAMRoleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterAWSIAMRoleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterAWSIAMRoleSpec) DeepCopyInto(out *ClusterAWSIAMRoleSpec) {
+	*out = *in
+	in.NamespaceSelector.DeepCopyInto(&out.NamespaceSelector)
+	if in.SessionPolicy != nil {
+		in, out := &in.SessionPolicy, &out.SessionPolicy
+		*out = new(ClusterSessionPolicy)
+		**out = **in
+	}
+	if in.PolicyARNs != nil {
+		in, out := &in.PolicyARNs, &out.PolicyARNs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AssumeRoleChain != nil {
+		in, out := &in.AssumeRoleChain, &out.AssumeRoleChain
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SessionTags != nil {
+		in, out := &in.SessionTags, &out.SessionTags
+		*out = make([]SessionTag, len(*in))
+		copy(*out, *in)
+	}
+	if in.SecretFormats != nil {
+		in, out := &in.SecretFormats, &out.SecretFormats
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterAWSIAMRoleSpec.
+func (in *ClusterAWSIAMRoleSpec) DeepCopy() *ClusterAWSIAMRoleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterAWSIAMRoleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterAWSIAMRoleStatus) DeepCopyInto(out *ClusterAWSIAMRoleStatus) {
+	*out = *in
+	if in.ObservedGeneration != nil {
+		in, out := &in.ObservedGeneration, &out.ObservedGeneration
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Expiration != nil {
+		in, out := &in.Expiration, &out.Expiration
+		*out = (*in).DeepCopy()
+	}
+	if in.SessionTags != nil {
+		in, out := &in.SessionTags, &out.SessionTags
+		*out = make([]SessionTag, len(*in))
+		copy(*out, *in)
+	}
+	if in.CircuitBreaker != nil {
+		in, out := &in.CircuitBreaker, &out.CircuitBreaker
+		*out = new(CircuitBreakerStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastFailure != nil {
+		in, out := &in.LastFailure, &out.LastFailure
+		*out = new(FailureStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastRefreshTime != nil {
+		in, out := &in.LastRefreshTime, &out.LastRefreshTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextRefreshTime != nil {
+		in, out := &in.NextRefreshTime, &out.NextRefreshTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]NamespaceSyncStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterAWSIAMRoleStatus.
+func (in *ClusterAWSIAMRoleStatus) DeepCopy() *ClusterAWSIAMRoleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterAWSIAMRoleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterSessionPolicy) DeepCopyInto(out *ClusterSessionPolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterSessionPolicy.
+func (in *ClusterSessionPolicy) DeepCopy() *ClusterSessionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterSessionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FailureStatus) DeepCopyInto(out *FailureStatus) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FailureStatus.
+func (in *FailureStatus) DeepCopy() *FailureStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FailureStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceSyncStatus) DeepCopyInto(out *NamespaceSyncStatus) {
+	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceSyncStatus.
+func (in *NamespaceSyncStatus) DeepCopy() *NamespaceSyncStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceSyncStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretTemplate) DeepCopyInto(out *SecretTemplate) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretTemplate.
+func (in *SecretTemplate) DeepCopy() *SecretTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SessionPolicy) DeepCopyInto(out *SessionPolicy) {
+	*out = *in
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(corev1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SessionPolicy.
+func (in *SessionPolicy) DeepCopy() *SessionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(SessionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SessionTag) DeepCopyInto(out *SessionTag) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SessionTag.
+func (in *SessionTag) DeepCopy() *SessionTag {
+	if in == nil {
+		return nil
+	}
+	out := new(SessionTag)
+	in.DeepCopyInto(out)
+	return out
+}
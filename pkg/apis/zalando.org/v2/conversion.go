@@ -0,0 +1,217 @@
+package v2
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConvertTo converts the AWSIAMRole to the v1 AWSIAMRole, which is the
+// storage version.
+func (r *AWSIAMRole) ConvertTo(dst *v1.AWSIAMRole) error {
+	roleReference, err := r.Spec.Role.convertTo()
+	if err != nil {
+		return fmt.Errorf("invalid role: %w", err)
+	}
+
+	dst.ObjectMeta = *r.ObjectMeta.DeepCopy()
+	dst.Spec = v1.AWSIAMRoleSpec{
+		RoleReference: roleReference,
+	}
+
+	for i, role := range r.Spec.AssumeRoleChain {
+		roleReference, err := role.convertTo()
+		if err != nil {
+			return fmt.Errorf("invalid assumeRoleChain[%d]: %w", i, err)
+		}
+		dst.Spec.AssumeRoleChain = append(dst.Spec.AssumeRoleChain, roleReference)
+	}
+
+	if session := r.Spec.Session; session != nil {
+		if session.Duration != nil {
+			// v1 stores the duration in seconds, so fractions of a second
+			// would be lost on the round trip.
+			if session.Duration.Duration%time.Second != 0 {
+				return fmt.Errorf("invalid session duration %s: must be a whole number of seconds", session.Duration.Duration)
+			}
+			dst.Spec.RoleSessionDuration = int64(session.Duration.Seconds())
+		}
+		dst.Spec.SessionName = session.Name
+
+		if externalID := session.ExternalID; externalID != nil {
+			dst.Spec.ExternalID = externalID.Value
+			dst.Spec.ExternalIDSecretRef = externalID.SecretKeyRef.DeepCopy()
+		}
+
+		if policy := session.Policy; policy != nil {
+			if policy.Inline != "" || policy.ConfigMapRef != nil {
+				dst.Spec.SessionPolicy = &v1.SessionPolicy{
+					Inline:       policy.Inline,
+					ConfigMapRef: policy.ConfigMapRef.DeepCopy(),
+				}
+			}
+			dst.Spec.PolicyARNs = append([]string(nil), policy.ARNs...)
+		}
+
+		for _, tag := range session.Tags {
+			dst.Spec.SessionTags = append(dst.Spec.SessionTags, v1.SessionTag(tag))
+		}
+	}
+
+	if secret := r.Spec.Secret; secret != nil {
+		dst.Spec.SecretFormats = append([]string(nil), secret.Formats...)
+		if secret.Name != "" || len(secret.Labels) > 0 || len(secret.Annotations) > 0 || secret.Type != "" {
+			dst.Spec.SecretTemplate = &v1.SecretTemplate{
+				Name:        secret.Name,
+				Labels:      copyMap(secret.Labels),
+				Annotations: copyMap(secret.Annotations),
+				Type:        secret.Type,
+			}
+		}
+	}
+
+	status := r.Status.DeepCopy()
+	dst.Status = v1.AWSIAMRoleStatus{
+		ObservedGeneration:  status.ObservedGeneration,
+		RoleARN:             status.RoleARN,
+		Expiration:          status.Expiration,
+		RoleSessionDuration: status.RoleSessionDuration,
+		STSEndpoint:         status.STSEndpoint,
+		LastRefreshTime:     status.LastRefreshTime,
+		NextRefreshTime:     status.NextRefreshTime,
+		Conditions:          status.Conditions,
+	}
+	for _, tag := range status.SessionTags {
+		dst.Status.SessionTags = append(dst.Status.SessionTags, v1.SessionTag(tag))
+	}
+	if status.CircuitBreaker != nil {
+		circuitBreaker := v1.CircuitBreakerStatus(*status.CircuitBreaker)
+		dst.Status.CircuitBreaker = &circuitBreaker
+	}
+	if status.LastFailure != nil {
+		lastFailure := v1.FailureStatus(*status.LastFailure)
+		dst.Status.LastFailure = &lastFailure
+	}
+
+	return nil
+}
+
+// ConvertFrom converts the v1 AWSIAMRole to the AWSIAMRole.
+func (r *AWSIAMRole) ConvertFrom(src *v1.AWSIAMRole) error {
+	r.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	r.Spec = AWSIAMRoleSpec{
+		Role: convertFromRoleReference(src.Spec.RoleReference),
+	}
+
+	for _, roleReference := range src.Spec.AssumeRoleChain {
+		r.Spec.AssumeRoleChain = append(r.Spec.AssumeRoleChain, convertFromRoleReference(roleReference))
+	}
+
+	session := &SessionSpec{
+		Name: src.Spec.SessionName,
+	}
+	if src.Spec.RoleSessionDuration != 0 {
+		session.Duration = &metav1.Duration{Duration: time.Duration(src.Spec.RoleSessionDuration) * time.Second}
+	}
+	if src.Spec.ExternalID != "" || src.Spec.ExternalIDSecretRef != nil {
+		session.ExternalID = &ExternalID{
+			Value:        src.Spec.ExternalID,
+			SecretKeyRef: src.Spec.ExternalIDSecretRef.DeepCopy(),
+		}
+	}
+	if src.Spec.SessionPolicy != nil || len(src.Spec.PolicyARNs) > 0 {
+		session.Policy = &SessionPolicy{
+			ARNs: append([]string(nil), src.Spec.PolicyARNs...),
+		}
+		if policy := src.Spec.SessionPolicy; policy != nil {
+			session.Policy.Inline = policy.Inline
+			session.Policy.ConfigMapRef = policy.ConfigMapRef.DeepCopy()
+		}
+	}
+	for _, tag := range src.Spec.SessionTags {
+		session.Tags = append(session.Tags, SessionTag(tag))
+	}
+	if session.Duration != nil || session.Name != "" || session.ExternalID != nil || session.Policy != nil || len(session.Tags) > 0 {
+		r.Spec.Session = session
+	}
+
+	if len(src.Spec.SecretFormats) > 0 || src.Spec.SecretTemplate != nil {
+		r.Spec.Secret = &SecretSpec{
+			Formats: append([]string(nil), src.Spec.SecretFormats...),
+		}
+		if template := src.Spec.SecretTemplate; template != nil {
+			r.Spec.Secret.Name = template.Name
+			r.Spec.Secret.Labels = copyMap(template.Labels)
+			r.Spec.Secret.Annotations = copyMap(template.Annotations)
+			r.Spec.Secret.Type = template.Type
+		}
+	}
+
+	status := src.Status.DeepCopy()
+	r.Status = AWSIAMRoleStatus{
+		ObservedGeneration:  status.ObservedGeneration,
+		RoleARN:             status.RoleARN,
+		Expiration:          status.Expiration,
+		RoleSessionDuration: status.RoleSessionDuration,
+		STSEndpoint:         status.STSEndpoint,
+		LastRefreshTime:     status.LastRefreshTime,
+		NextRefreshTime:     status.NextRefreshTime,
+		Conditions:          status.Conditions,
+	}
+	for _, tag := range status.SessionTags {
+		r.Status.SessionTags = append(r.Status.SessionTags, SessionTag(tag))
+	}
+	if status.CircuitBreaker != nil {
+		circuitBreaker := CircuitBreakerStatus(*status.CircuitBreaker)
+		r.Status.CircuitBreaker = &circuitBreaker
+	}
+	if status.LastFailure != nil {
+		lastFailure := FailureStatus(*status.LastFailure)
+		r.Status.LastFailure = &lastFailure
+	}
+
+	return nil
+}
+
+// convertTo converts the role reference to a v1 role reference, which is
+// either a role name or an ARN.
+func (r RoleReference) convertTo() (string, error) {
+	switch {
+	case r.Name != "" && r.ARN != "":
+		return "", errors.New("only one of name and arn can be set")
+	case r.ARN != "":
+		if !strings.HasPrefix(r.ARN, "arn:") {
+			return "", fmt.Errorf("'%s' is not an ARN", r.ARN)
+		}
+		return r.ARN, nil
+	case r.Name != "":
+		return r.Name, nil
+	}
+	return "", errors.New("one of name and arn must be set")
+}
+
+// convertFromRoleReference converts a v1 role reference to a role
+// reference.
+func convertFromRoleReference(roleReference string) RoleReference {
+	if strings.HasPrefix(roleReference, "arn:") {
+		return RoleReference{ARN: roleReference}
+	}
+	return RoleReference{Name: roleReference}
+}
+
+// copyMap returns a copy of the map. Empty maps are returned as nil.
+func copyMap(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
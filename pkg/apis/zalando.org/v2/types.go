@@ -0,0 +1,211 @@
+package v2
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// AWSIAMRole describes an AWS IAM Role for which credentials can be
+// provisioned in a cluster.
+// +k8s:deepcopy-gen=true
+type AWSIAMRole struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AWSIAMRoleSpec   `json:"spec"`
+	Status AWSIAMRoleStatus `json:"status"`
+}
+
+// AWSIAMRoleSpec is the spec part of the AWSIAMRole resource.
+// +k8s:deepcopy-gen=true
+type AWSIAMRoleSpec struct {
+	// role is the role credentials are provisioned for.
+	Role RoleReference `json:"role"`
+	// assumeRoleChain is an ordered list of intermediate roles which are
+	// assumed before assuming the role. The session duration of chained
+	// roles is limited to one hour.
+	// +optional
+	AssumeRoleChain []RoleReference `json:"assumeRoleChain,omitempty"`
+	// session defines the parameters of the session used when assuming
+	// the role.
+	// +optional
+	Session *SessionSpec `json:"session,omitempty"`
+	// secret defines the secret the credentials are stored in.
+	// +optional
+	Secret *SecretSpec `json:"secret,omitempty"`
+}
+
+// RoleReference references an AWS IAM role either by name or by ARN.
+// +k8s:deepcopy-gen=true
+type RoleReference struct {
+	// name is the name of a role, optionally with path, in the account of
+	// the controller.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// arn is the full ARN of a role.
+	// +optional
+	ARN string `json:"arn,omitempty"`
+}
+
+// SessionSpec defines the parameters of the session used when assuming a
+// role.
+// +k8s:deepcopy-gen=true
+type SessionSpec struct {
+	// duration is the session duration, e.g. 2h. Defaults to 1h. It must
+	// be a whole number of seconds.
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+	// name is appended as the last level of the RoleSessionName.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// externalID is passed as sts:ExternalId when assuming the role.
+	// +optional
+	ExternalID *ExternalID `json:"externalID,omitempty"`
+	// policy scopes down the permissions of the role.
+	// +optional
+	Policy *SessionPolicy `json:"policy,omitempty"`
+	// tags are session tags applied when assuming the role. Tags
+	// configured for the controller take precedence.
+	// +optional
+	Tags []SessionTag `json:"tags,omitempty"`
+}
+
+// ExternalID defines the ExternalID either directly or via a reference to a
+// Secret.
+// +k8s:deepcopy-gen=true
+type ExternalID struct {
+	// value is the ExternalID.
+	// +optional
+	Value string `json:"value,omitempty"`
+	// secretKeyRef references a key in a Secret in the same namespace
+	// holding the ExternalID. If set it takes precedence over value.
+	// +optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// SessionPolicy defines the session policies of a session.
+// +k8s:deepcopy-gen=true
+type SessionPolicy struct {
+	// inline is the JSON policy document.
+	// +optional
+	Inline string `json:"inline,omitempty"`
+	// configMapRef references a key in a ConfigMap in the same namespace
+	// holding the JSON policy document. If set it takes precedence over
+	// inline.
+	// +optional
+	ConfigMapRef *corev1.ConfigMapKeySelector `json:"configMapRef,omitempty"`
+	// arns are the ARNs of managed policies used as session policies.
+	// +optional
+	ARNs []string `json:"arns,omitempty"`
+}
+
+// SessionTag is a session tag applied when assuming an AWS IAM role.
+// +k8s:deepcopy-gen=true
+type SessionTag struct {
+	Key        string `json:"key"`
+	Value      string `json:"value"`
+	Transitive bool   `json:"transitive,omitempty"`
+}
+
+// SecretSpec defines the secret the credentials are stored in.
+// +k8s:deepcopy-gen=true
+type SecretSpec struct {
+	// name is the name of the secret. Defaults to the name of the
+	// AWSIAMRole.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// labels are added to the labels copied from the AWSIAMRole.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// annotations are added to the annotations of the secret.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// type is the type of the secret. Defaults to Opaque. Types defined by
+	// Kubernetes other than Opaque are not supported.
+	// +optional
+	Type corev1.SecretType `json:"type,omitempty"`
+	// formats limits the credentials formats stored in the secret to any
+	// of credentials, credentials.process and credentials.json. All
+	// formats are stored by default.
+	// +optional
+	Formats []string `json:"formats,omitempty"`
+}
+
+// AWSIAMRoleStatus is the status section of the AWSIAMRole resource.
+// +k8s:deepcopy-gen=true
+type AWSIAMRoleStatus struct {
+	// observedGeneration is the most recent generation observed for this
+	// AWSIAMRole.
+	// +optional
+	ObservedGeneration *int64 `json:"observedGeneration,omitempty"`
+	// roleARN is the ARN of the role of the current credentials.
+	// +optional
+	RoleARN string `json:"roleARN,omitempty"`
+	// expiration is the expiry time of the current credentials.
+	// +optional
+	Expiration *metav1.Time `json:"expiration,omitempty"`
+	// roleSessionDuration is the effective session duration in seconds of
+	// the current credentials.
+	// +optional
+	RoleSessionDuration int64 `json:"roleSessionDuration,omitempty"`
+	// sessionTags are the session tags applied when the current
+	// credentials were issued.
+	// +optional
+	SessionTags []SessionTag `json:"sessionTags,omitempty"`
+	// stsEndpoint is the URL of the STS endpoint which issued the current
+	// credentials.
+	// +optional
+	STSEndpoint string `json:"stsEndpoint,omitempty"`
+	// circuitBreaker describes the consecutive permanent failures to get
+	// credentials for the role.
+	// +optional
+	CircuitBreaker *CircuitBreakerStatus `json:"circuitBreaker,omitempty"`
+	// lastFailure describes the last failure to get credentials for the
+	// role.
+	// +optional
+	LastFailure *FailureStatus `json:"lastFailure,omitempty"`
+	// lastRefreshTime is the time the current credentials were issued and
+	// stored in the secret.
+	// +optional
+	LastRefreshTime *metav1.Time `json:"lastRefreshTime,omitempty"`
+	// nextRefreshTime is the time the credentials are refreshed next.
+	// +optional
+	NextRefreshTime *metav1.Time `json:"nextRefreshTime,omitempty"`
+	// conditions describe the state of the AWSIAMRole.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// CircuitBreakerStatus describes the state of the circuit breaker which stops
+// requesting credentials after consecutive permanent failures.
+// +k8s:deepcopy-gen=true
+type CircuitBreakerStatus struct {
+	State               string       `json:"state"`
+	ConsecutiveFailures int32        `json:"consecutiveFailures"`
+	LastError           string       `json:"lastError,omitempty"`
+	RetryAfter          *metav1.Time `json:"retryAfter,omitempty"`
+}
+
+// FailureStatus describes a failure to get credentials.
+// +k8s:deepcopy-gen=true
+type FailureStatus struct {
+	Reason  string      `json:"reason"`
+	Message string      `json:"message,omitempty"`
+	Time    metav1.Time `json:"time"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// AWSIAMRoleList is a list of AWSIAMRoles.
+// +k8s:deepcopy-gen=true
+type AWSIAMRoleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []AWSIAMRole `json:"items"`
+}
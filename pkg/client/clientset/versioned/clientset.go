@@ -23,6 +23,7 @@ import (
 	http "net/http"
 
 	zalandov1 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/client/clientset/versioned/typed/zalando.org/v1"
+	zalandov2 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/client/clientset/versioned/typed/zalando.org/v2"
 	discovery "k8s.io/client-go/discovery"
 	rest "k8s.io/client-go/rest"
 	flowcontrol "k8s.io/client-go/util/flowcontrol"
@@ -31,12 +32,14 @@ import (
 type Interface interface {
 	Discovery() discovery.DiscoveryInterface
 	ZalandoV1() zalandov1.ZalandoV1Interface
+	ZalandoV2() zalandov2.ZalandoV2Interface
 }
 
 // Clientset contains the clients for groups.
 type Clientset struct {
 	*discovery.DiscoveryClient
 	zalandoV1 *zalandov1.ZalandoV1Client
+	zalandoV2 *zalandov2.ZalandoV2Client
 }
 
 // ZalandoV1 retrieves the ZalandoV1Client
@@ -44,6 +47,11 @@ func (c *Clientset) ZalandoV1() zalandov1.ZalandoV1Interface {
 	return c.zalandoV1
 }
 
+// ZalandoV2 retrieves the ZalandoV2Client
+func (c *Clientset) ZalandoV2() zalandov2.ZalandoV2Interface {
+	return c.zalandoV2
+}
+
 // Discovery retrieves the DiscoveryClient
 func (c *Clientset) Discovery() discovery.DiscoveryInterface {
 	if c == nil {
@@ -92,6 +100,10 @@ func NewForConfigAndClient(c *rest.Config, httpClient *http.Client) (*Clientset,
 	if err != nil {
 		return nil, err
 	}
+	cs.zalandoV2, err = zalandov2.NewForConfigAndClient(&configShallowCopy, httpClient)
+	if err != nil {
+		return nil, err
+	}
 
 	cs.DiscoveryClient, err = discovery.NewDiscoveryClientForConfigAndClient(&configShallowCopy, httpClient)
 	if err != nil {
@@ -114,6 +126,7 @@ func NewForConfigOrDie(c *rest.Config) *Clientset {
 func New(c rest.Interface) *Clientset {
 	var cs Clientset
 	cs.zalandoV1 = zalandov1.New(c)
+	cs.zalandoV2 = zalandov2.New(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClient(c)
 	return &cs
@@ -20,6 +20,7 @@ package scheme
 
 import (
 	zalandov1 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v1"
+	zalandov2 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v2"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
@@ -32,6 +33,7 @@ var Codecs = serializer.NewCodecFactory(Scheme)
 var ParameterCodec = runtime.NewParameterCodec(Scheme)
 var localSchemeBuilder = runtime.SchemeBuilder{
 	zalandov1.AddToScheme,
+	zalandov2.AddToScheme,
 }
 
 // AddToScheme adds all types of this clientset into the given scheme. This allows composition
@@ -0,0 +1,70 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	context "context"
+
+	zalandoorgv1 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v1"
+	scheme "github.com/zalando-incubator/kube-aws-iam-controller/pkg/client/clientset/versioned/scheme"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// ClusterAWSIAMRolesGetter has a method to return a ClusterAWSIAMRoleInterface.
+// A group's client should implement this interface.
+type ClusterAWSIAMRolesGetter interface {
+	ClusterAWSIAMRoles() ClusterAWSIAMRoleInterface
+}
+
+// ClusterAWSIAMRoleInterface has methods to work with ClusterAWSIAMRole resources.
+type ClusterAWSIAMRoleInterface interface {
+	Create(ctx context.Context, clusterAWSIAMRole *zalandoorgv1.ClusterAWSIAMRole, opts metav1.CreateOptions) (*zalandoorgv1.ClusterAWSIAMRole, error)
+	Update(ctx context.Context, clusterAWSIAMRole *zalandoorgv1.ClusterAWSIAMRole, opts metav1.UpdateOptions) (*zalandoorgv1.ClusterAWSIAMRole, error)
+	// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+	UpdateStatus(ctx context.Context, clusterAWSIAMRole *zalandoorgv1.ClusterAWSIAMRole, opts metav1.UpdateOptions) (*zalandoorgv1.ClusterAWSIAMRole, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*zalandoorgv1.ClusterAWSIAMRole, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*zalandoorgv1.ClusterAWSIAMRoleList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (result *zalandoorgv1.ClusterAWSIAMRole, err error)
+	ClusterAWSIAMRoleExpansion
+}
+
+// clusterAWSIAMRoles implements ClusterAWSIAMRoleInterface
+type clusterAWSIAMRoles struct {
+	*gentype.ClientWithList[*zalandoorgv1.ClusterAWSIAMRole, *zalandoorgv1.ClusterAWSIAMRoleList]
+}
+
+// newClusterAWSIAMRoles returns a ClusterAWSIAMRoles
+func newClusterAWSIAMRoles(c *ZalandoV1Client) *clusterAWSIAMRoles {
+	return &clusterAWSIAMRoles{
+		gentype.NewClientWithList[*zalandoorgv1.ClusterAWSIAMRole, *zalandoorgv1.ClusterAWSIAMRoleList](
+			"clusterawsiamroles",
+			c.RESTClient(),
+			scheme.ParameterCodec,
+			"",
+			func() *zalandoorgv1.ClusterAWSIAMRole { return &zalandoorgv1.ClusterAWSIAMRole{} },
+			func() *zalandoorgv1.ClusterAWSIAMRoleList { return &zalandoorgv1.ClusterAWSIAMRoleList{} },
+		),
+	}
+}
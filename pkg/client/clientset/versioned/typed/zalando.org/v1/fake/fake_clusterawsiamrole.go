@@ -0,0 +1,52 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v1"
+	zalandoorgv1 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/client/clientset/versioned/typed/zalando.org/v1"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// fakeClusterAWSIAMRoles implements ClusterAWSIAMRoleInterface
+type fakeClusterAWSIAMRoles struct {
+	*gentype.FakeClientWithList[*v1.ClusterAWSIAMRole, *v1.ClusterAWSIAMRoleList]
+	Fake *FakeZalandoV1
+}
+
+func newFakeClusterAWSIAMRoles(fake *FakeZalandoV1) zalandoorgv1.ClusterAWSIAMRoleInterface {
+	return &fakeClusterAWSIAMRoles{
+		gentype.NewFakeClientWithList[*v1.ClusterAWSIAMRole, *v1.ClusterAWSIAMRoleList](
+			fake.Fake,
+			"",
+			v1.SchemeGroupVersion.WithResource("clusterawsiamroles"),
+			v1.SchemeGroupVersion.WithKind("ClusterAWSIAMRole"),
+			func() *v1.ClusterAWSIAMRole { return &v1.ClusterAWSIAMRole{} },
+			func() *v1.ClusterAWSIAMRoleList { return &v1.ClusterAWSIAMRoleList{} },
+			func(dst, src *v1.ClusterAWSIAMRoleList) { dst.ListMeta = src.ListMeta },
+			func(list *v1.ClusterAWSIAMRoleList) []*v1.ClusterAWSIAMRole {
+				return gentype.ToPointerSlice(list.Items)
+			},
+			func(list *v1.ClusterAWSIAMRoleList, items []*v1.ClusterAWSIAMRole) {
+				list.Items = gentype.FromPointerSlice(items)
+			},
+		),
+		fake,
+	}
+}
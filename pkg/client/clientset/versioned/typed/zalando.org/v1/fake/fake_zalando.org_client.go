@@ -32,6 +32,10 @@ func (c *FakeZalandoV1) AWSIAMRoles(namespace string) v1.AWSIAMRoleInterface {
 	return newFakeAWSIAMRoles(c, namespace)
 }
 
+func (c *FakeZalandoV1) ClusterAWSIAMRoles() v1.ClusterAWSIAMRoleInterface {
+	return newFakeClusterAWSIAMRoles(c)
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeZalandoV1) RESTClient() rest.Interface {
@@ -29,6 +29,7 @@ import (
 type ZalandoV1Interface interface {
 	RESTClient() rest.Interface
 	AWSIAMRolesGetter
+	ClusterAWSIAMRolesGetter
 }
 
 // ZalandoV1Client is used to interact with features provided by the zalando.org group.
@@ -40,6 +41,10 @@ func (c *ZalandoV1Client) AWSIAMRoles(namespace string) AWSIAMRoleInterface {
 	return newAWSIAMRoles(c, namespace)
 }
 
+func (c *ZalandoV1Client) ClusterAWSIAMRoles() ClusterAWSIAMRoleInterface {
+	return newClusterAWSIAMRoles(c)
+}
+
 // NewForConfig creates a new ZalandoV1Client for the given config.
 // NewForConfig is equivalent to NewForConfigAndClient(c, httpClient),
 // where httpClient was generated with rest.HTTPClientFor(c).
@@ -0,0 +1,48 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v2 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v2"
+	zalandoorgv2 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/client/clientset/versioned/typed/zalando.org/v2"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// fakeAWSIAMRoles implements AWSIAMRoleInterface
+type fakeAWSIAMRoles struct {
+	*gentype.FakeClientWithList[*v2.AWSIAMRole, *v2.AWSIAMRoleList]
+	Fake *FakeZalandoV2
+}
+
+func newFakeAWSIAMRoles(fake *FakeZalandoV2, namespace string) zalandoorgv2.AWSIAMRoleInterface {
+	return &fakeAWSIAMRoles{
+		gentype.NewFakeClientWithList[*v2.AWSIAMRole, *v2.AWSIAMRoleList](
+			fake.Fake,
+			namespace,
+			v2.SchemeGroupVersion.WithResource("awsiamroles"),
+			v2.SchemeGroupVersion.WithKind("AWSIAMRole"),
+			func() *v2.AWSIAMRole { return &v2.AWSIAMRole{} },
+			func() *v2.AWSIAMRoleList { return &v2.AWSIAMRoleList{} },
+			func(dst, src *v2.AWSIAMRoleList) { dst.ListMeta = src.ListMeta },
+			func(list *v2.AWSIAMRoleList) []*v2.AWSIAMRole { return gentype.ToPointerSlice(list.Items) },
+			func(list *v2.AWSIAMRoleList, items []*v2.AWSIAMRole) { list.Items = gentype.FromPointerSlice(items) },
+		),
+		fake,
+	}
+}
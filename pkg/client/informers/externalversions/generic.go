@@ -22,6 +22,7 @@ import (
 	fmt "fmt"
 
 	v1 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v1"
+	v2 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v2"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
 	cache "k8s.io/client-go/tools/cache"
 )
@@ -55,6 +56,12 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 	// Group=zalando.org, Version=v1
 	case v1.SchemeGroupVersion.WithResource("awsiamroles"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Zalando().V1().AWSIAMRoles().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("clusterawsiamroles"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Zalando().V1().ClusterAWSIAMRoles().Informer()}, nil
+
+		// Group=zalando.org, Version=v2
+	case v2.SchemeGroupVersion.WithResource("awsiamroles"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Zalando().V2().AWSIAMRoles().Informer()}, nil
 
 	}
 
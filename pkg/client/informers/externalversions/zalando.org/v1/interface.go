@@ -26,6 +26,8 @@ import (
 type Interface interface {
 	// AWSIAMRoles returns a AWSIAMRoleInformer.
 	AWSIAMRoles() AWSIAMRoleInformer
+	// ClusterAWSIAMRoles returns a ClusterAWSIAMRoleInformer.
+	ClusterAWSIAMRoles() ClusterAWSIAMRoleInformer
 }
 
 type version struct {
@@ -43,3 +45,8 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 func (v *version) AWSIAMRoles() AWSIAMRoleInformer {
 	return &aWSIAMRoleInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// ClusterAWSIAMRoles returns a ClusterAWSIAMRoleInformer.
+func (v *version) ClusterAWSIAMRoles() ClusterAWSIAMRoleInformer {
+	return &clusterAWSIAMRoleInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	zalandoorgv1 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	listers "k8s.io/client-go/listers"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// ClusterAWSIAMRoleLister helps list ClusterAWSIAMRoles.
+// All objects returned here must be treated as read-only.
+type ClusterAWSIAMRoleLister interface {
+	// List lists all ClusterAWSIAMRoles in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*zalandoorgv1.ClusterAWSIAMRole, err error)
+	// Get retrieves the ClusterAWSIAMRole from the index for a given name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*zalandoorgv1.ClusterAWSIAMRole, error)
+	ClusterAWSIAMRoleListerExpansion
+}
+
+// clusterAWSIAMRoleLister implements the ClusterAWSIAMRoleLister interface.
+type clusterAWSIAMRoleLister struct {
+	listers.ResourceIndexer[*zalandoorgv1.ClusterAWSIAMRole]
+}
+
+// NewClusterAWSIAMRoleLister returns a new ClusterAWSIAMRoleLister.
+func NewClusterAWSIAMRoleLister(indexer cache.Indexer) ClusterAWSIAMRoleLister {
+	return &clusterAWSIAMRoleLister{listers.New[*zalandoorgv1.ClusterAWSIAMRole](indexer, zalandoorgv1.Resource("clusterawsiamrole"))}
+}
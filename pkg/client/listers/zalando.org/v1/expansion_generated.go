@@ -25,3 +25,7 @@ type AWSIAMRoleListerExpansion interface{}
 // AWSIAMRoleNamespaceListerExpansion allows custom methods to be added to
 // AWSIAMRoleNamespaceLister.
 type AWSIAMRoleNamespaceListerExpansion interface{}
+
+// ClusterAWSIAMRoleListerExpansion allows custom methods to be added to
+// ClusterAWSIAMRoleLister.
+type ClusterAWSIAMRoleListerExpansion interface{}
@@ -8,6 +8,7 @@ import (
 
 	awsiamrole "github.com/zalando-incubator/kube-aws-iam-controller/pkg/client/clientset/versioned"
 	zalandov1 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/client/clientset/versioned/typed/zalando.org/v1"
+	zalandov2 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/client/clientset/versioned/typed/zalando.org/v2"
 	"k8s.io/client-go/kubernetes"
 	rest "k8s.io/client-go/rest"
 	"k8s.io/client-go/transport"
@@ -16,6 +17,7 @@ import (
 type Interface interface {
 	kubernetes.Interface
 	ZalandoV1() zalandov1.ZalandoV1Interface
+	ZalandoV2() zalandov2.ZalandoV2Interface
 }
 
 type Clientset struct {
@@ -48,6 +50,10 @@ func (c *Clientset) ZalandoV1() zalandov1.ZalandoV1Interface {
 	return c.awsiamrole.ZalandoV1()
 }
 
+func (c *Clientset) ZalandoV2() zalandov2.ZalandoV2Interface {
+	return c.awsiamrole.ZalandoV2()
+}
+
 // ConfigureKubeConfig configures a kubeconfig.
 func ConfigureKubeConfig(apiServerURL *url.URL, timeout time.Duration, stopCh <-chan struct{}) (*rest.Config, error) {
 	tr := &http.Transport{
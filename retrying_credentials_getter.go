@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultTransientRetryDelay  = 200 * time.Millisecond
+	defaultThrottlingRetryDelay = time.Second
+	defaultMaxRetryDelay        = 20 * time.Second
+)
+
+// RetryingCredentialsGetter is a credentials getter which retries throttling
+// and transient errors of another credentials getter with exponential backoff
+// and jitter until a deadline is reached. Permanent errors are never retried.
+type RetryingCredentialsGetter struct {
+	getter         CredentialsGetter
+	deadline       time.Duration
+	transientDelay time.Duration
+	throttleDelay  time.Duration
+	maxDelay       time.Duration
+}
+
+// NewRetryingCredentialsGetter initializes a new retrying credentials getter.
+// Requests are retried for at most the deadline.
+func NewRetryingCredentialsGetter(getter CredentialsGetter, deadline time.Duration) *RetryingCredentialsGetter {
+	return &RetryingCredentialsGetter{
+		getter:         getter,
+		deadline:       deadline,
+		transientDelay: defaultTransientRetryDelay,
+		throttleDelay:  defaultThrottlingRetryDelay,
+		maxDelay:       defaultMaxRetryDelay,
+	}
+}
+
+// Get gets credentials from the underlying credentials getter and retries
+// throttling and transient errors.
+func (g *RetryingCredentialsGetter) Get(ctx context.Context, role string, sessionDuration time.Duration, opts CredentialsOptions) (*Credentials, error) {
+	deadline := time.Now().Add(g.deadline)
+
+	for attempt := 0; ; attempt++ {
+		creds, err := g.getter.Get(ctx, role, sessionDuration, opts)
+		if err == nil {
+			return creds, nil
+		}
+
+		class := classifyError(err)
+		if class == errorClassPermanent {
+			return nil, err
+		}
+
+		delay := g.backoff(class, attempt)
+		if !time.Now().Add(delay).Before(deadline) {
+			return nil, err
+		}
+
+		log.Debugf("Retrying %s error for role '%s' in %s: %v", class, role, delay, err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, err
+		}
+	}
+}
+
+// backoff returns the delay before the next attempt using exponential
+// backoff with full jitter. Throttling errors start with a higher delay than
+// transient errors.
+func (g *RetryingCredentialsGetter) backoff(class errorClass, attempt int) time.Duration {
+	base := g.transientDelay
+	if class == errorClassThrottling {
+		base = g.throttleDelay
+	}
+
+	delay := g.maxDelay
+	if attempt < 32 && base<<attempt < g.maxDelay {
+		delay = base << attempt
+	}
+
+	return time.Duration(rand.Int64N(int64(delay) + 1))
+}
+
+// withoutSDKRetries returns a copy of the AWS config with the retries of the
+// SDK disabled. It's used for the clients of credentials getters wrapped by a
+// RetryingCredentialsGetter, so the wrapper is the only retry layer and the
+// retries of the SDK don't multiply its attempts.
+func withoutSDKRetries(cfg aws.Config) aws.Config {
+	cfg = cfg.Copy()
+	cfg.Retryer = func() aws.Retryer {
+		return aws.NopRetryer{}
+	}
+	return cfg
+}
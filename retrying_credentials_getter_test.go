@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/require"
+)
+
+type flakyCredsGetter struct {
+	calls    int
+	failures int
+	err      error
+}
+
+func (g *flakyCredsGetter) Get(ctx context.Context, role string, sessionDuration time.Duration, opts CredentialsOptions) (*Credentials, error) {
+	g.calls++
+	if g.calls <= g.failures {
+		return nil, g.err
+	}
+	return &Credentials{RoleARN: role}, nil
+}
+
+func newTestRetryingCredentialsGetter(getter CredentialsGetter, deadline time.Duration) *RetryingCredentialsGetter {
+	retrying := NewRetryingCredentialsGetter(getter, deadline)
+	retrying.transientDelay = time.Millisecond
+	retrying.throttleDelay = time.Millisecond
+	retrying.maxDelay = 5 * time.Millisecond
+	return retrying
+}
+
+func TestRetryingCredentialsGetter(tt *testing.T) {
+	for _, tc := range []struct {
+		msg      string
+		failures int
+		err      error
+		deadline time.Duration
+		calls    int
+		success  bool
+	}{
+		{
+			msg:     "no error",
+			calls:   1,
+			success: true,
+		},
+		{
+			msg:      "retry throttling errors",
+			failures: 3,
+			err:      &smithy.GenericAPIError{Code: "Throttling"},
+			deadline: time.Minute,
+			calls:    4,
+			success:  true,
+		},
+		{
+			msg:      "retry transient errors",
+			failures: 2,
+			err:      responseError(500, errors.New("internal error")),
+			deadline: time.Minute,
+			calls:    3,
+			success:  true,
+		},
+		{
+			msg:      "don't retry permanent errors",
+			failures: 3,
+			err:      &smithy.GenericAPIError{Code: "AccessDenied"},
+			deadline: time.Minute,
+			calls:    1,
+		},
+		{
+			msg:      "don't retry without deadline",
+			failures: 3,
+			err:      &smithy.GenericAPIError{Code: "Throttling"},
+			calls:    1,
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			getter := &flakyCredsGetter{failures: tc.failures, err: tc.err}
+			retrying := newTestRetryingCredentialsGetter(getter, tc.deadline)
+
+			creds, err := retrying.Get(context.Background(), "role", time.Hour, CredentialsOptions{})
+			if tc.success {
+				require.NoError(t, err)
+				require.Equal(t, "role", creds.RoleARN)
+			} else {
+				require.Equal(t, tc.err, err)
+			}
+			require.Equal(t, tc.calls, getter.calls)
+		})
+	}
+}
+
+func TestRetryingCredentialsGetterDeadline(t *testing.T) {
+	getter := &flakyCredsGetter{failures: 1000, err: &smithy.GenericAPIError{Code: "Throttling"}}
+	retrying := newTestRetryingCredentialsGetter(getter, 50*time.Millisecond)
+
+	start := time.Now()
+	_, err := retrying.Get(context.Background(), "role", time.Hour, CredentialsOptions{})
+	require.Error(t, err)
+	require.Less(t, time.Since(start), time.Second)
+	require.Greater(t, getter.calls, 1)
+}
+
+func TestRetryingCredentialsGetterContextCanceled(t *testing.T) {
+	getter := &flakyCredsGetter{failures: 1000, err: &smithy.GenericAPIError{Code: "Throttling"}}
+	retrying := NewRetryingCredentialsGetter(getter, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := retrying.Get(ctx, "role", time.Hour, CredentialsOptions{})
+	require.Error(t, err)
+	require.Equal(t, 1, getter.calls)
+}
+
+func TestBackoff(t *testing.T) {
+	retrying := NewRetryingCredentialsGetter(nil, time.Minute)
+	for attempt := 0; attempt < 100; attempt++ {
+		require.LessOrEqual(t, retrying.backoff(errorClassTransient, attempt), defaultMaxRetryDelay)
+		require.LessOrEqual(t, retrying.backoff(errorClassThrottling, attempt), defaultMaxRetryDelay)
+	}
+	require.LessOrEqual(t, retrying.backoff(errorClassTransient, 0), defaultTransientRetryDelay)
+}
+
+func TestWithoutSDKRetries(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := aws.Config{
+		Region:      "eu-central-1",
+		Credentials: aws.AnonymousCredentials{},
+	}
+	svc := sts.NewFromConfig(withoutSDKRetries(cfg), func(o *sts.Options) {
+		o.BaseEndpoint = aws.String(server.URL)
+	})
+
+	_, err := svc.GetCallerIdentity(context.Background(), &sts.GetCallerIdentityInput{})
+	require.Error(t, err)
+	require.Equal(t, 1, requests)
+	require.Nil(t, cfg.Retryer)
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	av1 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	conditionRoleValidated = "RoleValidated"
+
+	reasonRoleValid                  = "RoleValid"
+	reasonRoleNotFound               = "RoleNotFound"
+	reasonRoleNotTrusted             = "RoleNotTrusted"
+	reasonMaxSessionDurationExceeded = "MaxSessionDurationExceeded"
+	reasonValidationSkipped          = "ValidationSkipped"
+	reasonValidationFailed           = "ValidationFailed"
+)
+
+type iamAPI interface {
+	GetRole(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error)
+}
+
+// RoleValidator validates the role of an AWSIAMRole with IAM GetRole before
+// credentials are requested. It checks that the role exists, that its trust
+// policy allows one of the principals of the controller to assume it and
+// that its MaxSessionDuration allows the requested session duration.
+type RoleValidator struct {
+	iam         iamAPI
+	baseRoleARN string
+	accountID   string
+	principals  []string
+}
+
+// NewRoleValidator initializes a new role validator. Roles referenced by
+// name are resolved relative to the base role ARN and only roles in the
+// account of the base role ARN can be validated. Principals are the ARNs of
+// the identities assuming roles.
+func NewRoleValidator(cfg aws.Config, baseRoleARN string, principals []string) (*RoleValidator, error) {
+	return newRoleValidator(iam.NewFromConfig(cfg), baseRoleARN, principals)
+}
+
+func newRoleValidator(svc iamAPI, baseRoleARN string, principals []string) (*RoleValidator, error) {
+	accountID, err := roleAccountID(baseRoleARN, "")
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := make([]string, 0, len(principals))
+	for _, principal := range principals {
+		principal, err := principalARN(principal)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, principal)
+	}
+
+	return &RoleValidator{
+		iam:         svc,
+		baseRoleARN: baseRoleARN,
+		accountID:   accountID,
+		principals:  normalized,
+	}, nil
+}
+
+// Validate validates the role of the AWSIAMRole and returns the
+// RoleValidated condition describing the result. Transient errors are
+// returned as error so the validation can be retried.
+func (v *RoleValidator) Validate(ctx context.Context, awsIAMRole *av1.AWSIAMRole) (metav1.Condition, error) {
+	condition := metav1.Condition{
+		Type:               conditionRoleValidated,
+		ObservedGeneration: awsIAMRole.Generation,
+	}
+
+	roleARN := v.resolve(awsIAMRole.Spec.RoleReference)
+	accountID, err := roleAccountID(roleARN, v.accountID)
+	if err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reasonValidationFailed
+		condition.Message = err.Error()
+		return condition, nil
+	}
+
+	if accountID != v.accountID {
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = reasonValidationSkipped
+		condition.Message = fmt.Sprintf("Role '%s' is not in account %s and can't be validated", roleARN, v.accountID)
+		return condition, nil
+	}
+
+	roleName := roleARN[strings.LastIndex(roleARN, "/")+1:]
+	resp, err := v.iam.GetRole(ctx, &iam.GetRoleInput{RoleName: aws.String(roleName)})
+	if err != nil {
+		var notFound *iamtypes.NoSuchEntityException
+		switch {
+		case errors.As(err, &notFound):
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = reasonRoleNotFound
+			condition.Message = fmt.Sprintf("Role '%s' does not exist", roleARN)
+			return condition, nil
+		case classifyError(err) != errorClassPermanent:
+			return condition, err
+		}
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = reasonValidationFailed
+		condition.Message = fmt.Sprintf("Failed to get role '%s': %v", roleARN, err)
+		return condition, nil
+	}
+
+	principals := v.principals
+	if chain := awsIAMRole.Spec.AssumeRoleChain; len(chain) > 0 {
+		// the role is assumed by the last role of the chain.
+		principals = []string{v.resolve(chain[len(chain)-1])}
+	}
+
+	var reasons, messages []string
+	trusted, err := trustsPrincipal(aws.ToString(resp.Role.AssumeRolePolicyDocument), principals)
+	if err != nil {
+		reasons = append(reasons, reasonRoleNotTrusted)
+		messages = append(messages, fmt.Sprintf("failed to parse trust policy: %v", err))
+	} else if !trusted {
+		reasons = append(reasons, reasonRoleNotTrusted)
+		messages = append(messages, fmt.Sprintf("the trust policy doesn't allow %s to assume the role", strings.Join(principals, ", ")))
+	}
+
+	requested := getRoleSessionDuration(awsIAMRole)
+	if resp.Role.MaxSessionDuration != nil {
+		maxSessionDuration := time.Duration(*resp.Role.MaxSessionDuration) * time.Second
+		if requested > maxSessionDuration {
+			reasons = append(reasons, reasonMaxSessionDurationExceeded)
+			messages = append(messages, fmt.Sprintf("the roleSessionDuration %s exceeds the MaxSessionDuration %s of the role", requested, maxSessionDuration))
+		}
+	}
+
+	if len(reasons) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reasons[0]
+		condition.Message = fmt.Sprintf("Role '%s' is invalid: %s", roleARN, strings.Join(messages, "; "))
+		return condition, nil
+	}
+
+	condition.Status = metav1.ConditionTrue
+	condition.Reason = reasonRoleValid
+	condition.Message = fmt.Sprintf("Role '%s' exists and can be assumed", roleARN)
+	return condition, nil
+}
+
+// resolve returns the full ARN of a role reference.
+func (v *RoleValidator) resolve(role string) string {
+	if strings.HasPrefix(role, arnPrefix) {
+		return role
+	}
+	return v.baseRoleARN + role
+}
+
+// policyDocument is an IAM policy document. Only the fields needed to
+// evaluate trust policies are parsed.
+type policyDocument struct {
+	Statement policyStatements `json:"Statement"`
+}
+
+type policyStatement struct {
+	Effect    string          `json:"Effect"`
+	Principal json.RawMessage `json:"Principal"`
+	Action    stringList      `json:"Action"`
+	Condition json.RawMessage `json:"Condition"`
+}
+
+// policyStatements is a list of statements which can also be a single
+// statement in a policy document.
+type policyStatements []policyStatement
+
+func (s *policyStatements) UnmarshalJSON(data []byte) error {
+	var statement policyStatement
+	if json.Unmarshal(data, &statement) == nil {
+		*s = policyStatements{statement}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]policyStatement)(s))
+}
+
+// stringList is a list of strings which can also be a single string in a
+// policy document.
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var value string
+	if json.Unmarshal(data, &value) == nil {
+		*l = stringList{value}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+// trustsPrincipal returns true if the URL encoded trust policy allows one of
+// the principals to call sts:AssumeRole and doesn't deny it explicitly.
+// Conditions can't be evaluated, so conditional allow statements are assumed
+// to apply and conditional deny statements are ignored.
+func trustsPrincipal(encodedPolicy string, principals []string) (bool, error) {
+	policy, err := url.PathUnescape(encodedPolicy)
+	if err != nil {
+		return false, err
+	}
+
+	var document policyDocument
+	err = json.Unmarshal([]byte(policy), &document)
+	if err != nil {
+		return false, err
+	}
+
+	allowed := false
+	for _, statement := range document.Statement {
+		if !matchesAction(statement.Action, "sts:AssumeRole") {
+			continue
+		}
+
+		matches, err := matchesPrincipal(statement.Principal, principals)
+		if err != nil {
+			return false, err
+		}
+		if !matches {
+			continue
+		}
+
+		if strings.EqualFold(statement.Effect, "Deny") {
+			if len(statement.Condition) == 0 {
+				return false, nil
+			}
+			continue
+		}
+		allowed = true
+	}
+	return allowed, nil
+}
+
+// matchesAction returns true if one of the action patterns matches the
+// action.
+func matchesAction(patterns []string, action string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(action)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPrincipal returns true if the principal element of a statement
+// matches one of the principals.
+func matchesPrincipal(element json.RawMessage, principals []string) (bool, error) {
+	if len(element) == 0 {
+		return false, nil
+	}
+
+	var wildcard string
+	if json.Unmarshal(element, &wildcard) == nil {
+		return wildcard == "*", nil
+	}
+
+	var principal map[string]stringList
+	err := json.Unmarshal(element, &principal)
+	if err != nil {
+		return false, fmt.Errorf("invalid principal: %w", err)
+	}
+
+	for _, value := range principal["AWS"] {
+		for _, p := range principals {
+			if principalMatches(value, p) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// principalMatches returns true if the principal of a trust policy matches
+// the principal ARN. Account IDs and account root ARNs match all principals
+// of the account. Role ARNs are compared by name as the ARN of an assumed
+// role doesn't include the path of the role.
+func principalMatches(value, principal string) bool {
+	if value == "*" || value == principal {
+		return true
+	}
+
+	principalARN, err := arn.Parse(principal)
+	if err != nil {
+		return false
+	}
+
+	if value == principalARN.AccountID {
+		return true
+	}
+
+	valueARN, err := arn.Parse(value)
+	if err != nil || valueARN.Partition != principalARN.Partition || valueARN.AccountID != principalARN.AccountID {
+		return false
+	}
+
+	if valueARN.Resource == "root" {
+		return true
+	}
+
+	return strings.HasPrefix(valueARN.Resource, "role/") && strings.HasPrefix(principalARN.Resource, "role/") &&
+		path.Base(valueARN.Resource) == path.Base(principalARN.Resource)
+}
+
+// principalARN returns the ARN used in trust policies for an identity ARN as
+// returned by sts:GetCallerIdentity. Assumed role sessions are converted to
+// the ARN of the role.
+func principalARN(identity string) (string, error) {
+	identityARN, err := arn.Parse(identity)
+	if err != nil {
+		return "", fmt.Errorf("invalid principal ARN '%s': %w", identity, err)
+	}
+
+	if identityARN.Service == "sts" && strings.HasPrefix(identityARN.Resource, "assumed-role/") {
+		parts := strings.Split(identityARN.Resource, "/")
+		return fmt.Sprintf("arn:%s:iam::%s:role/%s", identityARN.Partition, identityARN.AccountID, parts[1]), nil
+	}
+	return identity, nil
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/require"
+	av1 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	testControllerIdentity = "arn:aws:sts::123456789012:assumed-role/kube-aws-iam-controller/session"
+	testTrustPolicy        = `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:role/system/kube-aws-iam-controller"},"Action":"sts:AssumeRole"}]}`
+)
+
+type mockIAMAPI struct {
+	roles map[string]*iamtypes.Role
+	err   error
+	calls int
+}
+
+func (m *mockIAMAPI) GetRole(_ context.Context, params *iam.GetRoleInput, _ ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	role, ok := m.roles[aws.ToString(params.RoleName)]
+	if !ok {
+		return nil, &iamtypes.NoSuchEntityException{Message: aws.String("role not found")}
+	}
+	return &iam.GetRoleOutput{Role: role}, nil
+}
+
+func testIAMRole(trustPolicy string, maxSessionDuration int32) *iamtypes.Role {
+	return &iamtypes.Role{
+		AssumeRolePolicyDocument: aws.String(url.PathEscape(trustPolicy)),
+		MaxSessionDuration:       aws.Int32(maxSessionDuration),
+	}
+}
+
+func TestRoleValidator(tt *testing.T) {
+	iamAPI := &mockIAMAPI{
+		roles: map[string]*iamtypes.Role{
+			"app":         testIAMRole(testTrustPolicy, 3600),
+			"untrusted":   testIAMRole(`{"Statement":{"Effect":"Allow","Principal":{"Service":"ec2.amazonaws.com"},"Action":"sts:AssumeRole"}}`, 3600),
+			"long":        testIAMRole(testTrustPolicy, 43200),
+			"chained":     testIAMRole(`{"Statement":{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:role/intermediate"},"Action":"sts:AssumeRole"}}`, 3600),
+			"source-role": testIAMRole(`{"Statement":{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:role/source"},"Action":"sts:*"}}`, 3600),
+		},
+	}
+
+	validator, err := newRoleValidator(iamAPI, "arn:aws:iam::123456789012:role/", []string{testControllerIdentity, "arn:aws:iam::123456789012:role/source"})
+	require.NoError(tt, err)
+
+	for _, tc := range []struct {
+		msg    string
+		spec   av1.AWSIAMRoleSpec
+		status metav1.ConditionStatus
+		reason string
+	}{
+		{
+			msg:    "role name trusting the controller",
+			spec:   av1.AWSIAMRoleSpec{RoleReference: "app"},
+			status: metav1.ConditionTrue,
+			reason: reasonRoleValid,
+		},
+		{
+			msg:    "role ARN trusting the controller",
+			spec:   av1.AWSIAMRoleSpec{RoleReference: "arn:aws:iam::123456789012:role/path/app"},
+			status: metav1.ConditionTrue,
+			reason: reasonRoleValid,
+		},
+		{
+			msg:    "role trusting a source role",
+			spec:   av1.AWSIAMRoleSpec{RoleReference: "source-role"},
+			status: metav1.ConditionTrue,
+			reason: reasonRoleValid,
+		},
+		{
+			msg:    "missing role",
+			spec:   av1.AWSIAMRoleSpec{RoleReference: "missing"},
+			status: metav1.ConditionFalse,
+			reason: reasonRoleNotFound,
+		},
+		{
+			msg:    "role not trusting the controller",
+			spec:   av1.AWSIAMRoleSpec{RoleReference: "untrusted"},
+			status: metav1.ConditionFalse,
+			reason: reasonRoleNotTrusted,
+		},
+		{
+			msg:    "session duration exceeding the MaxSessionDuration",
+			spec:   av1.AWSIAMRoleSpec{RoleReference: "app", RoleSessionDuration: 7200},
+			status: metav1.ConditionFalse,
+			reason: reasonMaxSessionDurationExceeded,
+		},
+		{
+			msg:    "session duration within the MaxSessionDuration",
+			spec:   av1.AWSIAMRoleSpec{RoleReference: "long", RoleSessionDuration: 7200},
+			status: metav1.ConditionTrue,
+			reason: reasonRoleValid,
+		},
+		{
+			msg:    "role trusting the last role of the chain",
+			spec:   av1.AWSIAMRoleSpec{RoleReference: "chained", AssumeRoleChain: []string{"first", "intermediate"}},
+			status: metav1.ConditionTrue,
+			reason: reasonRoleValid,
+		},
+		{
+			msg:    "role not trusting the last role of the chain",
+			spec:   av1.AWSIAMRoleSpec{RoleReference: "app", AssumeRoleChain: []string{"intermediate"}},
+			status: metav1.ConditionFalse,
+			reason: reasonRoleNotTrusted,
+		},
+		{
+			msg:    "role in another account",
+			spec:   av1.AWSIAMRoleSpec{RoleReference: "arn:aws:iam::210987654321:role/app"},
+			status: metav1.ConditionUnknown,
+			reason: reasonValidationSkipped,
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			condition, err := validator.Validate(context.Background(), &av1.AWSIAMRole{
+				ObjectMeta: metav1.ObjectMeta{Generation: 3},
+				Spec:       tc.spec,
+			})
+			require.NoError(t, err)
+			require.Equal(t, conditionRoleValidated, condition.Type)
+			require.Equal(t, tc.status, condition.Status, condition.Message)
+			require.Equal(t, tc.reason, condition.Reason)
+			require.EqualValues(t, 3, condition.ObservedGeneration)
+		})
+	}
+}
+
+func TestRoleValidatorErrors(t *testing.T) {
+	iamAPI := &mockIAMAPI{err: &smithy.GenericAPIError{Code: "AccessDenied"}}
+	validator, err := newRoleValidator(iamAPI, "arn:aws:iam::123456789012:role/", []string{testControllerIdentity})
+	require.NoError(t, err)
+
+	awsIAMRole := &av1.AWSIAMRole{Spec: av1.AWSIAMRoleSpec{RoleReference: "app"}}
+	condition, err := validator.Validate(context.Background(), awsIAMRole)
+	require.NoError(t, err)
+	require.Equal(t, metav1.ConditionUnknown, condition.Status)
+	require.Equal(t, reasonValidationFailed, condition.Reason)
+
+	// transient errors are returned to retry the validation.
+	iamAPI.err = &smithy.GenericAPIError{Code: "Throttling"}
+	_, err = validator.Validate(context.Background(), awsIAMRole)
+	require.Error(t, err)
+
+	_, err = newRoleValidator(iamAPI, "arn:aws:iam::123456789012:role/", []string{"invalid"})
+	require.Error(t, err)
+}
+
+func TestTrustsPrincipal(tt *testing.T) {
+	principals := []string{"arn:aws:iam::123456789012:role/kube-aws-iam-controller"}
+
+	for _, tc := range []struct {
+		msg     string
+		policy  string
+		trusted bool
+	}{
+		{
+			msg:     "role ARN",
+			policy:  testTrustPolicy,
+			trusted: true,
+		},
+		{
+			msg:     "list of principals and actions",
+			policy:  `{"Statement":[{"Effect":"Allow","Principal":{"AWS":["arn:aws:iam::123456789012:role/other","arn:aws:iam::123456789012:role/kube-aws-iam-controller"]},"Action":["sts:TagSession","sts:AssumeRole"]}]}`,
+			trusted: true,
+		},
+		{
+			msg:     "account root",
+			policy:  `{"Statement":{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:root"},"Action":"sts:AssumeRole"}}`,
+			trusted: true,
+		},
+		{
+			msg:     "account ID",
+			policy:  `{"Statement":{"Effect":"Allow","Principal":{"AWS":"123456789012"},"Action":"sts:AssumeRole"}}`,
+			trusted: true,
+		},
+		{
+			msg:     "wildcard principal with condition",
+			policy:  `{"Statement":{"Effect":"Allow","Principal":"*","Action":"sts:AssumeRole","Condition":{"StringEquals":{"aws:PrincipalOrgID":"o-123"}}}}`,
+			trusted: true,
+		},
+		{
+			msg:     "other account",
+			policy:  `{"Statement":{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::210987654321:root"},"Action":"sts:AssumeRole"}}`,
+			trusted: false,
+		},
+		{
+			msg:     "other role",
+			policy:  `{"Statement":{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:role/other"},"Action":"sts:AssumeRole"}}`,
+			trusted: false,
+		},
+		{
+			msg:     "other action",
+			policy:  `{"Statement":{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:root"},"Action":"sts:AssumeRoleWithWebIdentity"}}`,
+			trusted: false,
+		},
+		{
+			msg:     "explicit deny",
+			policy:  `{"Statement":[{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:root"},"Action":"sts:AssumeRole"},{"Effect":"Deny","Principal":{"AWS":"arn:aws:iam::123456789012:role/kube-aws-iam-controller"},"Action":"sts:AssumeRole"}]}`,
+			trusted: false,
+		},
+		{
+			msg:     "conditional deny",
+			policy:  `{"Statement":[{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:root"},"Action":"sts:AssumeRole"},{"Effect":"Deny","Principal":"*","Action":"sts:*","Condition":{"Bool":{"aws:SecureTransport":"false"}}}]}`,
+			trusted: true,
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			trusted, err := trustsPrincipal(url.PathEscape(tc.policy), principals)
+			require.NoError(t, err)
+			require.Equal(t, tc.trusted, trusted)
+		})
+	}
+
+	_, err := trustsPrincipal("not json", principals)
+	require.Error(tt, err)
+}
+
+func TestPrincipalARN(t *testing.T) {
+	principal, err := principalARN(testControllerIdentity)
+	require.NoError(t, err)
+	require.Equal(t, "arn:aws:iam::123456789012:role/kube-aws-iam-controller", principal)
+
+	principal, err = principalARN("arn:aws:iam::123456789012:user/admin")
+	require.NoError(t, err)
+	require.Equal(t, "arn:aws:iam::123456789012:user/admin", principal)
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+const (
+	rolesAnywhereService         = "rolesanywhere"
+	rolesAnywhereSessionsPath    = "/sessions"
+	rolesAnywhereRSAAlgorithm    = "AWS4-X509-RSA-SHA256"
+	rolesAnywhereECDSAAlgorithm  = "AWS4-X509-ECDSA-SHA256"
+	rolesAnywhereTimeFormat      = "20060102T150405Z"
+	rolesAnywhereDateFormat      = "20060102"
+	rolesAnywhereSessionDuration = time.Hour
+	rolesAnywhereCredentialsName = "RolesAnywhereCredentials"
+)
+
+// RolesAnywhereCredentialsProvider gets credentials from IAM Roles Anywhere
+// by calling CreateSession signed with an X.509 certificate and private key.
+// The certificate and key are read from files on every retrieval, so
+// rotated certificates e.g. mounted from a Secret are picked up on the next
+// refresh.
+type RolesAnywhereCredentialsProvider struct {
+	client          *http.Client
+	endpoint        string
+	region          string
+	trustAnchorARN  string
+	profileARN      string
+	roleARN         string
+	certificateFile string
+	privateKeyFile  string
+	now             func() time.Time
+}
+
+// rolesAnywhereSessionResponse is the response of CreateSession.
+type rolesAnywhereSessionResponse struct {
+	CredentialSet []struct {
+		Credentials struct {
+			AccessKeyID     string    `json:"accessKeyId"`
+			SecretAccessKey string    `json:"secretAccessKey"`
+			SessionToken    string    `json:"sessionToken"`
+			Expiration      time.Time `json:"expiration"`
+		} `json:"credentials"`
+	} `json:"credentialSet"`
+}
+
+// NewRolesAnywhereCredentialsProvider initializes a new IAM Roles Anywhere
+// credentials provider. The region is taken from the trust anchor ARN. If
+// endpoint is empty the regional endpoint is used.
+func NewRolesAnywhereCredentialsProvider(trustAnchorARN, profileARN, roleARN, certificateFile, privateKeyFile, endpoint string) (*RolesAnywhereCredentialsProvider, error) {
+	trustAnchor, err := arn.Parse(trustAnchorARN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trust anchor ARN '%s': %w", trustAnchorARN, err)
+	}
+
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com", rolesAnywhereService, trustAnchor.Region)
+	}
+
+	return &RolesAnywhereCredentialsProvider{
+		client:          &http.Client{Timeout: 30 * time.Second},
+		endpoint:        strings.TrimSuffix(endpoint, "/"),
+		region:          trustAnchor.Region,
+		trustAnchorARN:  trustAnchorARN,
+		profileARN:      profileARN,
+		roleARN:         roleARN,
+		certificateFile: certificateFile,
+		privateKeyFile:  privateKeyFile,
+		now:             time.Now,
+	}, nil
+}
+
+// Retrieve gets new credentials from IAM Roles Anywhere.
+func (p *RolesAnywhereCredentialsProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	chain, err := loadCertificateChain(p.certificateFile)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+
+	signer, err := loadPrivateKey(p.privateKeyFile)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"durationSeconds": int(rolesAnywhereSessionDuration.Seconds()),
+		"profileArn":      p.profileARN,
+		"roleArn":         p.roleARN,
+		"trustAnchorArn":  p.trustAnchorARN,
+	})
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+rolesAnywhereSessionsPath, bytes.NewReader(body))
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	err = signRolesAnywhereRequest(req, body, signer, chain, p.region, p.now())
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return aws.Credentials{}, fmt.Errorf("IAM Roles Anywhere CreateSession failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var session rolesAnywhereSessionResponse
+	err = json.Unmarshal(respBody, &session)
+	if err != nil {
+		return aws.Credentials{}, fmt.Errorf("failed to parse IAM Roles Anywhere CreateSession response: %w", err)
+	}
+
+	if len(session.CredentialSet) == 0 {
+		return aws.Credentials{}, errors.New("IAM Roles Anywhere CreateSession returned no credentials")
+	}
+
+	creds := session.CredentialSet[0].Credentials
+	return aws.Credentials{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		Source:          rolesAnywhereCredentialsName,
+		CanExpire:       true,
+		Expires:         creds.Expiration,
+	}, nil
+}
+
+// signRolesAnywhereRequest signs a request with the X.509 variant of
+// Signature Version 4 used by IAM Roles Anywhere:
+// https://docs.aws.amazon.com/rolesanywhere/latest/userguide/authentication-sign-process.html
+func signRolesAnywhereRequest(req *http.Request, body []byte, signer crypto.Signer, chain []*x509.Certificate, region string, now time.Time) error {
+	var algorithm string
+	switch signer.Public().(type) {
+	case *rsa.PublicKey:
+		algorithm = rolesAnywhereRSAAlgorithm
+	case *ecdsa.PublicKey:
+		algorithm = rolesAnywhereECDSAAlgorithm
+	default:
+		return fmt.Errorf("unsupported private key type %T", signer.Public())
+	}
+
+	now = now.UTC()
+	req.Header.Set("X-Amz-Date", now.Format(rolesAnywhereTimeFormat))
+	req.Header.Set("X-Amz-X509", base64.StdEncoding.EncodeToString(chain[0].Raw))
+	signedHeaders := []string{"content-type", "host", "x-amz-date", "x-amz-x509"}
+	if len(chain) > 1 {
+		intermediates := make([]string, 0, len(chain)-1)
+		for _, cert := range chain[1:] {
+			intermediates = append(intermediates, base64.StdEncoding.EncodeToString(cert.Raw))
+		}
+		req.Header.Set("X-Amz-X509-Chain", strings.Join(intermediates, ","))
+		signedHeaders = append(signedHeaders, "x-amz-x509-chain")
+	}
+
+	scope := strings.Join([]string{now.Format(rolesAnywhereDateFormat), region, rolesAnywhereService, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		algorithm,
+		now.Format(rolesAnywhereTimeFormat),
+		scope,
+		sha256Hex([]byte(canonicalRolesAnywhereRequest(req, body, signedHeaders))),
+	}, "\n")
+
+	digest := sha256.Sum256([]byte(stringToSign))
+	signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm,
+		chain[0].SerialNumber.String(),
+		scope,
+		strings.Join(signedHeaders, ";"),
+		hex.EncodeToString(signature),
+	))
+	return nil
+}
+
+// canonicalRolesAnywhereRequest returns the canonical request of a signed
+// IAM Roles Anywhere request. The signed headers must be lower case and
+// sorted.
+func canonicalRolesAnywhereRequest(req *http.Request, body []byte, signedHeaders []string) string {
+	var headers strings.Builder
+	for _, header := range signedHeaders {
+		value := req.Header.Get(header)
+		if header == "host" {
+			value = req.Host
+			if value == "" {
+				value = req.URL.Host
+			}
+		}
+		headers.WriteString(header + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	return strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		headers.String(),
+		strings.Join(signedHeaders, ";"),
+		sha256Hex(body),
+	}, "\n")
+}
+
+func sha256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// loadCertificateChain loads PEM encoded certificates from a file. The first
+// certificate is the end-entity certificate, the others are intermediates.
+func loadCertificateChain(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var chain []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate from %s: %w", path, err)
+		}
+		chain = append(chain, cert)
+	}
+
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no certificate found in %s", path)
+	}
+	return chain, nil
+}
+
+// loadPrivateKey loads a PEM encoded PKCS#8, PKCS#1 or SEC 1 private key from
+// a file.
+func loadPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no private key found in %s", path)
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T in %s", key, path)
+		}
+		return signer, nil
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("failed to parse private key from %s", path)
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testTrustAnchorARN = "arn:aws:rolesanywhere:eu-central-1:123456789012:trust-anchor/anchor"
+	testProfileARN     = "arn:aws:rolesanywhere:eu-central-1:123456789012:profile/profile"
+	testRoleARN        = "arn:aws:iam::123456789012:role/on-prem"
+)
+
+// writeTestCertificate writes a self-signed certificate and its private key
+// to files in dir.
+func writeTestCertificate(t *testing.T, dir string, key crypto.Signer, serial int64) (string, string) {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "kube-aws-iam-controller"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+// rolesAnywhereStandIn is a local stand-in for the IAM Roles Anywhere
+// CreateSession API which verifies the request signature.
+type rolesAnywhereStandIn struct {
+	serials []string
+	chains  int
+}
+
+func (s *rolesAnywhereStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	err := s.verify(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprintf(w, `{"credentialSet":[{"credentials":{"accessKeyId":"AKID","secretAccessKey":"SECRET","sessionToken":"TOKEN","expiration":"2030-01-01T00:00:00Z"}}]}`)
+}
+
+func (s *rolesAnywhereStandIn) verify(r *http.Request) error {
+	if r.Method != http.MethodPost || r.URL.Path != "/sessions" {
+		return fmt.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+
+	var input map[string]interface{}
+	err = json.Unmarshal(body, &input)
+	if err != nil {
+		return err
+	}
+	if input["trustAnchorArn"] != testTrustAnchorARN || input["profileArn"] != testProfileARN || input["roleArn"] != testRoleARN {
+		return fmt.Errorf("unexpected input %v", input)
+	}
+
+	der, err := base64.StdEncoding.DecodeString(r.Header.Get("X-Amz-X509"))
+	if err != nil {
+		return err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return err
+	}
+
+	// Authorization: <algorithm> Credential=<serial>/<scope>, SignedHeaders=<headers>, Signature=<signature>
+	algorithm, params, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	fields := map[string]string{}
+	for _, param := range strings.Split(params, ", ") {
+		key, value, _ := strings.Cut(param, "=")
+		fields[key] = value
+	}
+
+	serial, scope, _ := strings.Cut(fields["Credential"], "/")
+	if serial != cert.SerialNumber.String() {
+		return fmt.Errorf("credential %s doesn't match certificate serial %s", serial, cert.SerialNumber)
+	}
+	date := r.Header.Get("X-Amz-Date")
+	if scope != date[:8]+"/eu-central-1/rolesanywhere/aws4_request" {
+		return fmt.Errorf("unexpected scope %s", scope)
+	}
+
+	// the certificate chain must be signed if it's sent.
+	signedHeaders := "content-type;host;x-amz-date;x-amz-x509"
+	canonicalHeaders := "content-type:" + r.Header.Get("Content-Type") + "\n" +
+		"host:" + r.Host + "\n" +
+		"x-amz-date:" + date + "\n" +
+		"x-amz-x509:" + r.Header.Get("X-Amz-X509") + "\n"
+	if chain := r.Header.Get("X-Amz-X509-Chain"); chain != "" {
+		signedHeaders += ";x-amz-x509-chain"
+		canonicalHeaders += "x-amz-x509-chain:" + chain + "\n"
+		s.chains++
+	}
+	if fields["SignedHeaders"] != signedHeaders {
+		return fmt.Errorf("unexpected signed headers %s", fields["SignedHeaders"])
+	}
+
+	bodyHash := sha256.Sum256(body)
+	canonicalRequest := "POST\n/sessions\n\n" +
+		canonicalHeaders + "\n" +
+		signedHeaders + "\n" +
+		hex.EncodeToString(bodyHash[:])
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := algorithm + "\n" + date + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+	digest := sha256.Sum256([]byte(stringToSign))
+
+	signature, err := hex.DecodeString(fields["Signature"])
+	if err != nil {
+		return err
+	}
+
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if algorithm != "AWS4-X509-RSA-SHA256" {
+			return fmt.Errorf("unexpected algorithm %s", algorithm)
+		}
+		err = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+		if err != nil {
+			return err
+		}
+	case *ecdsa.PublicKey:
+		if algorithm != "AWS4-X509-ECDSA-SHA256" {
+			return fmt.Errorf("unexpected algorithm %s", algorithm)
+		}
+		if !ecdsa.VerifyASN1(key, digest[:], signature) {
+			return fmt.Errorf("invalid signature")
+		}
+	}
+
+	s.serials = append(s.serials, serial)
+	return nil
+}
+
+func TestRolesAnywhereCredentialsProvider(tt *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(tt, err)
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(tt, err)
+
+	for _, tc := range []struct {
+		msg   string
+		key   crypto.Signer
+		chain bool
+	}{
+		{
+			msg: "RSA key",
+			key: rsaKey,
+		},
+		{
+			msg: "ECDSA key",
+			key: ecdsaKey,
+		},
+		{
+			msg:   "certificate chain",
+			key:   ecdsaKey,
+			chain: true,
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			standIn := &rolesAnywhereStandIn{}
+			server := httptest.NewServer(standIn)
+			defer server.Close()
+
+			dir := t.TempDir()
+			certFile, keyFile := writeTestCertificate(t, dir, tc.key, 1)
+			if tc.chain {
+				// the self-signed certificate stands in for an
+				// intermediate certificate.
+				intermediate, err := os.ReadFile(certFile)
+				require.NoError(t, err)
+				f, err := os.OpenFile(certFile, os.O_APPEND|os.O_WRONLY, 0o600)
+				require.NoError(t, err)
+				_, err = f.Write(intermediate)
+				require.NoError(t, err)
+				require.NoError(t, f.Close())
+			}
+
+			provider, err := NewRolesAnywhereCredentialsProvider(testTrustAnchorARN, testProfileARN, testRoleARN, certFile, keyFile, server.URL)
+			require.NoError(t, err)
+
+			creds, err := provider.Retrieve(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, "AKID", creds.AccessKeyID)
+			require.Equal(t, "SECRET", creds.SecretAccessKey)
+			require.Equal(t, "TOKEN", creds.SessionToken)
+			require.True(t, creds.CanExpire)
+			require.Equal(t, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), creds.Expires)
+
+			// a rotated certificate is used on the next retrieval.
+			writeTestCertificate(t, dir, tc.key, 2)
+			_, err = provider.Retrieve(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, []string{"1", "2"}, standIn.serials)
+			if tc.chain {
+				require.Equal(t, 1, standIn.chains)
+			} else {
+				require.Zero(t, standIn.chains)
+			}
+		})
+	}
+}
+
+func TestCanonicalRolesAnywhereRequest(t *testing.T) {
+	body := []byte(`{"durationSeconds":3600}`)
+	req := httptest.NewRequest(http.MethodPost, "https://rolesanywhere.eu-central-1.amazonaws.com/sessions", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Amz-Date", "20240101T000000Z")
+	req.Header.Set("X-Amz-X509", "Y2VydA==")
+	req.Header.Set("X-Amz-X509-Chain", "aW50ZXJtZWRpYXRl")
+
+	expected := "POST\n" +
+		"/sessions\n" +
+		"\n" +
+		"content-type:application/json\n" +
+		"host:rolesanywhere.eu-central-1.amazonaws.com\n" +
+		"x-amz-date:20240101T000000Z\n" +
+		"x-amz-x509:Y2VydA==\n" +
+		"x-amz-x509-chain:aW50ZXJtZWRpYXRl\n" +
+		"\n" +
+		"content-type;host;x-amz-date;x-amz-x509;x-amz-x509-chain\n" +
+		"1a15f67f6619aa540b13e9a4c37d149fb2c9bdea25d82b73a3878826694dfe27"
+	actual := canonicalRolesAnywhereRequest(req, body, []string{"content-type", "host", "x-amz-date", "x-amz-x509", "x-amz-x509-chain"})
+	require.Equal(t, expected, actual)
+}
+
+func TestRolesAnywhereCredentialsProviderErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Untrusted certificate"}`, http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	certFile, keyFile := writeTestCertificate(t, t.TempDir(), key, 1)
+
+	provider, err := NewRolesAnywhereCredentialsProvider(testTrustAnchorARN, testProfileARN, testRoleARN, certFile, keyFile, server.URL)
+	require.NoError(t, err)
+	_, err = provider.Retrieve(context.Background())
+	require.ErrorContains(t, err, "Untrusted certificate")
+
+	provider.certificateFile = filepath.Join(t.TempDir(), "missing.crt")
+	_, err = provider.Retrieve(context.Background())
+	require.Error(t, err)
+
+	_, err = NewRolesAnywhereCredentialsProvider("invalid", testProfileARN, testRoleARN, certFile, keyFile, "")
+	require.Error(t, err)
+
+	provider, err = NewRolesAnywhereCredentialsProvider(testTrustAnchorARN, testProfileARN, testRoleARN, certFile, keyFile, "")
+	require.NoError(t, err)
+	require.Equal(t, "https://rolesanywhere.eu-central-1.amazonaws.com", provider.endpoint)
+}
@@ -76,10 +76,11 @@ func NewSecretsController(client kubernetes.Interface, namespace string, interva
 	}
 }
 
-// getCreds gets new credentials from the CredentialsGetter and converts them
-// to a secret data map.
-func (c *SecretsController) getCreds(ctx context.Context, role string) (map[string][]byte, error) {
-	creds, err := c.creds.Get(ctx, role, 3600*time.Second)
+// getCreds gets new credentials for a role used in the namespace from the
+// CredentialsGetter and converts them to a secret data map. The namespace
+// selects the base role mapping.
+func (c *SecretsController) getCreds(ctx context.Context, role, namespace string) (map[string][]byte, error) {
+	creds, err := c.creds.Get(ctx, role, 3600*time.Second, CredentialsOptions{Namespace: namespace})
 	if err != nil {
 		return nil, err
 	}
@@ -113,6 +114,12 @@ func (c *SecretsController) getCreds(ctx context.Context, role string) (map[stri
 	}, nil
 }
 
+// AddReadinessCheck adds a check which must pass for the controller to be
+// ready.
+func (c *SecretsController) AddReadinessCheck(name string, check healthcheck.Check) {
+	c.healthReporter.AddReadinessCheck(name, check)
+}
+
 // Run runs the secret controller loop. This will refresh secrets with AWS IAM
 // roles.
 func (c *SecretsController) Run(ctx context.Context) {
@@ -131,6 +138,8 @@ func (c *SecretsController) Run(ctx context.Context) {
 
 	// Add the liveness endpoint at /healthz
 	http.HandleFunc("/healthz", c.healthReporter.LiveEndpoint)
+	// Add the readiness endpoint at /ready
+	http.HandleFunc("/ready", c.healthReporter.ReadyEndpoint)
 
 	// Start the HTTP server
 	http.ListenAndServe(healthEndpointAddress, nil)
@@ -163,8 +172,6 @@ func (c *SecretsController) refresh(ctx context.Context) error {
 		return err
 	}
 
-	credsCache := map[string]map[string][]byte{}
-
 	tmpSecretStore := NewRoleStore()
 
 	for _, secret := range secrets.Items {
@@ -213,7 +220,7 @@ func (c *SecretsController) refresh(ctx context.Context) error {
 		}
 
 		if refreshCreds {
-			secret.Data, err = c.getCreds(ctx, role)
+			secret.Data, err = c.getCreds(ctx, role, secret.Namespace)
 			if err != nil {
 				log.Errorf("Failed to get credentials for role %s: %v", role, err)
 				continue
@@ -234,26 +241,20 @@ func (c *SecretsController) refresh(ctx context.Context) error {
 				"expire":    string(secret.Data[expireKey]),
 			}).Info()
 		}
-
-		if secret.Data != nil {
-			credsCache[role] = secret.Data
-		}
 	}
 
-	// create missing secrets
+	// create missing secrets. Credentials are requested per namespace as
+	// the base role mapping depends on it.
 	c.roleStore.RLock()
 	for role, namespaces := range c.roleStore.Store {
-		creds, ok := credsCache[role]
-		if !ok {
-			creds, err = c.getCreds(ctx, role)
-			if err != nil {
-				log.Errorf("Failed to get credentials for role %s: %v", role, err)
-				continue
-			}
-		}
-
 		for ns := range namespaces {
 			if !tmpSecretStore.Exists(role, ns) {
+				creds, err := c.getCreds(ctx, role, ns)
+				if err != nil {
+					log.Errorf("Failed to get credentials for role %s in namespace %s: %v", role, ns, err)
+					continue
+				}
+
 				// create secret
 				name := secretPrefix + role
 				secret := &v1.Secret{
@@ -265,7 +266,7 @@ func (c *SecretsController) refresh(ctx context.Context) error {
 					Data: creds,
 				}
 
-				_, err := c.client.CoreV1().Secrets(ns).Create(ctx, secret, metav1.CreateOptions{})
+				_, err = c.client.CoreV1().Secrets(ns).Create(ctx, secret, metav1.CreateOptions{})
 				if err != nil {
 					log.Errorf("Failed to create secret %s/%s: %v", ns, name, err)
 					continue
@@ -16,7 +16,7 @@ type mockCredsGetter struct {
 	creds *Credentials
 }
 
-func (g *mockCredsGetter) Get(ctx context.Context, role string, sessionDuration time.Duration) (*Credentials, error) {
+func (g *mockCredsGetter) Get(ctx context.Context, role string, sessionDuration time.Duration, opts CredentialsOptions) (*Credentials, error) {
 	if g.err != nil {
 		return nil, g.err
 	}
@@ -185,3 +185,42 @@ func TestRefresh(tt *testing.T) {
 		})
 	}
 }
+
+func TestRefreshBaseRoleMapping(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&v1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "finance",
+				Labels: map[string]string{"business-unit": "finance"},
+			},
+		},
+		&v1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "default",
+			},
+		},
+	)
+
+	credsGetter := func(accessKeyID string) CredentialsGetter {
+		return &mockCredsGetter{creds: &Credentials{AccessKeyID: accessKeyID, Expiration: time.Now().Add(time.Hour)}}
+	}
+	getter := NewMappingCredentialsGetter(client, "000000000000", credsGetter("default"))
+	require.NoError(t, getter.Add(BaseRoleMapping{
+		Name: "finance",
+		NamespaceSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"business-unit": "finance"},
+		},
+	}, credsGetter("finance")))
+
+	controller := NewSecretsController(client, v1.NamespaceAll, time.Second, time.Second, getter)
+	controller.roleStore.Add("role1", "finance", "pod1")
+	controller.roleStore.Add("role1", "default", "pod2")
+	require.NoError(t, controller.refresh(context.TODO()))
+
+	// the source role of the namespace of the pod is used.
+	for namespace, accessKeyID := range map[string]string{"finance": "finance", "default": "default"} {
+		secret, err := client.CoreV1().Secrets(namespace).Get(context.TODO(), secretPrefix+"role1", metav1.GetOptions{})
+		require.NoError(t, err)
+		require.Contains(t, string(secret.Data[credentialsFileKey]), "aws_access_key_id = "+accessKeyID+"\n")
+	}
+}
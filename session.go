@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	log "github.com/sirupsen/logrus"
+	av1 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v1"
+)
+
+const (
+	sessionTagKeyMaxSize   = 128
+	sessionTagValueMaxSize = 256
+	sessionTagsMaxCount    = 50
+	sessionTagReservedKey  = "aws:"
+)
+
+var (
+	// invalidSessionTagChars matches characters not allowed in session tag
+	// keys and values according to:
+	// https://docs.aws.amazon.com/STS/latest/APIReference/API_Tag.html
+	invalidSessionTagChars = regexp.MustCompile(`[^\p{L}\p{Z}\p{N}_.:/=+\-@]`)
+)
+
+// SessionTag is a session tag passed to STS when assuming a role.
+type SessionTag struct {
+	Key        string
+	Value      string
+	Transitive bool
+}
+
+// SessionConfig defines how the session parameters used when assuming a role
+// are derived from an AWSIAMRole and its namespace.
+type SessionConfig struct {
+	// Tags is a fixed set of session tags applied to every session.
+	Tags map[string]string
+	// NamespaceLabelTags maps namespace labels to session tag keys.
+	NamespaceLabelTags map[string]string
+	// AWSIAMRoleLabelTags maps AWSIAMRole labels to session tag keys.
+	AWSIAMRoleLabelTags map[string]string
+	// TransitiveTags is the list of session tag keys which should be
+	// marked as transitive.
+	TransitiveTags []string
+	// AllowedSpecTags is the list of session tag keys which can be defined
+	// in the spec of an AWSIAMRole.
+	AllowedSpecTags []string
+	// SourceIdentityTemplate is the template used to render the source
+	// identity of a session.
+	SourceIdentityTemplate *template.Template
+	// SessionNameTemplate is the template used to render the
+	// RoleSessionName of a session.
+	SessionNameTemplate *template.Template
+	// ClusterID identifies the cluster in session templates.
+	ClusterID string
+}
+
+// sessionTemplateData is the data available to session templates.
+type sessionTemplateData struct {
+	// Cluster is the ID of the cluster.
+	Cluster string
+	// Namespace is the namespace of the AWSIAMRole.
+	Namespace string
+	// Name is the name of the AWSIAMRole.
+	Name string
+	// RoleReference is the role reference of the AWSIAMRole.
+	RoleReference string
+	// RoleName is the name of the referenced role without path.
+	RoleName string
+}
+
+// templateData returns the data available to session templates for an
+// AWSIAMRole.
+func (c *SessionConfig) templateData(awsIAMRole *av1.AWSIAMRole) sessionTemplateData {
+	roleReference := awsIAMRole.Spec.RoleReference
+	return sessionTemplateData{
+		Cluster:       c.ClusterID,
+		Namespace:     awsIAMRole.Namespace,
+		Name:          awsIAMRole.Name,
+		RoleReference: roleReference,
+		RoleName:      roleReference[strings.LastIndex(roleReference, "/")+1:],
+	}
+}
+
+// SourceIdentity renders the source identity for an AWSIAMRole. It returns an
+// empty string if no template is configured.
+func (c *SessionConfig) SourceIdentity(awsIAMRole *av1.AWSIAMRole) (string, error) {
+	if c == nil || c.SourceIdentityTemplate == nil {
+		return "", nil
+	}
+
+	var buf bytes.Buffer
+	err := c.SourceIdentityTemplate.Execute(&buf, c.templateData(awsIAMRole))
+	if err != nil {
+		return "", fmt.Errorf("failed to render source identity: %w", err)
+	}
+
+	sourceIdentity, err := normalizeSessionName(buf.String())
+	if err != nil {
+		return "", fmt.Errorf("invalid source identity: %w", err)
+	}
+	return sourceIdentity, nil
+}
+
+// SessionName renders the '/' separated session name for an AWSIAMRole. It
+// returns an empty string if no template is configured. The session name is
+// normalized when assuming the role.
+func (c *SessionConfig) SessionName(awsIAMRole *av1.AWSIAMRole) (string, error) {
+	if c == nil || c.SessionNameTemplate == nil {
+		return "", nil
+	}
+
+	var buf bytes.Buffer
+	err := c.SessionNameTemplate.Execute(&buf, c.templateData(awsIAMRole))
+	if err != nil {
+		return "", fmt.Errorf("failed to render session name: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// SessionTags returns the session tags for an AWSIAMRole with the specified
+// labels and session tags in a namespace with the specified labels. Fixed
+// tags take precedence over tags copied from the namespace, which in turn
+// take precedence over tags copied from the AWSIAMRole. Tag keys are
+// case-insensitive, so a tag replaces tags of lower precedence regardless of
+// the case of their keys. Session tags defined in the AWSIAMRole spec are
+// only applied if their key is allowed and isn't configured for any of the
+// other tags, regardless of case and whether the label it's copied from is
+// present. The keys of the ignored spec tags are returned along with the
+// tags. The tags are sanitized and truncated according to the STS limits and
+// sorted by key.
+func (c *SessionConfig) SessionTags(namespaceLabels, awsIAMRoleLabels map[string]string, specTags []av1.SessionTag) ([]SessionTag, []string) {
+	var ignored []string
+	if c == nil {
+		for _, tag := range specTags {
+			ignored = append(ignored, tag.Key)
+		}
+		return nil, ignored
+	}
+
+	tags := make(map[string]SessionTag)
+	copyLabelTags(tags, c.AWSIAMRoleLabelTags, awsIAMRoleLabels)
+	copyLabelTags(tags, c.NamespaceLabelTags, namespaceLabels)
+	for _, key := range sortedKeys(c.Tags) {
+		setSessionTag(tags, key, c.Tags[key])
+	}
+
+	transitive := sessionTagKeySet(c.TransitiveTags)
+	allowed := sessionTagKeySet(c.AllowedSpecTags)
+	reserved := c.reservedSessionTagKeys()
+	for _, tag := range specTags {
+		key := normalizeSessionTag(tag.Key, sessionTagKeyMaxSize)
+		lowerKey := strings.ToLower(key)
+		_, isReserved := reserved[lowerKey]
+		_, isAllowed := allowed[lowerKey]
+		if isReserved || !isAllowed || key == "" || strings.HasPrefix(lowerKey, sessionTagReservedKey) {
+			ignored = append(ignored, tag.Key)
+			continue
+		}
+		setSessionTag(tags, key, tag.Value)
+		if tag.Transitive {
+			transitive[lowerKey] = struct{}{}
+		}
+	}
+
+	sessionTags := make([]SessionTag, 0, len(tags))
+	for lowerKey, tag := range tags {
+		if tag.Key == "" || strings.HasPrefix(lowerKey, sessionTagReservedKey) {
+			log.Warnf("Ignoring invalid session tag key '%s'", tag.Key)
+			continue
+		}
+
+		_, tag.Transitive = transitive[lowerKey]
+		sessionTags = append(sessionTags, tag)
+	}
+
+	sort.Slice(sessionTags, func(i, j int) bool {
+		return sessionTags[i].Key < sessionTags[j].Key
+	})
+
+	if len(sessionTags) > sessionTagsMaxCount {
+		log.Warnf("Dropping %d session tags exceeding the limit of %d tags", len(sessionTags)-sessionTagsMaxCount, sessionTagsMaxCount)
+		sessionTags = sessionTags[:sessionTagsMaxCount]
+	}
+
+	return sessionTags, ignored
+}
+
+// reservedSessionTagKeys returns the lower case keys of all session tags
+// configured for the controller, whether or not they are set for a session.
+func (c *SessionConfig) reservedSessionTagKeys() map[string]struct{} {
+	keys := make([]string, 0, len(c.Tags)+len(c.NamespaceLabelTags)+len(c.AWSIAMRoleLabelTags))
+	for key := range c.Tags {
+		keys = append(keys, key)
+	}
+	for _, key := range c.NamespaceLabelTags {
+		keys = append(keys, key)
+	}
+	for _, key := range c.AWSIAMRoleLabelTags {
+		keys = append(keys, key)
+	}
+	return sessionTagKeySet(keys)
+}
+
+// sessionTagKeySet returns the set of normalized lower case session tag
+// keys.
+func sessionTagKeySet(keys []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		set[strings.ToLower(normalizeSessionTag(key, sessionTagKeyMaxSize))] = struct{}{}
+	}
+	return set
+}
+
+// copyLabelTags copies the labels defined in the mapping to the tags map.
+// Labels are copied in order, so the last of several labels mapped to the
+// same key wins.
+func copyLabelTags(tags map[string]SessionTag, mapping, labels map[string]string) {
+	for _, label := range sortedKeys(mapping) {
+		if value, ok := labels[label]; ok {
+			setSessionTag(tags, mapping[label], value)
+		}
+	}
+}
+
+// setSessionTag sets the normalized session tag in the tags map. Tags are
+// keyed by their lower case key as tag keys are case-insensitive in STS, so
+// the tag replaces any tag with the same key regardless of case.
+func setSessionTag(tags map[string]SessionTag, key, value string) {
+	key = normalizeSessionTag(key, sessionTagKeyMaxSize)
+	tags[strings.ToLower(key)] = SessionTag{
+		Key:   key,
+		Value: normalizeSessionTag(value, sessionTagValueMaxSize),
+	}
+}
+
+// normalizeSessionTag substitutes characters not allowed in session tag keys
+// and values with '_' and truncates the result to maxSize characters.
+func normalizeSessionTag(s string, maxSize int) string {
+	s = invalidSessionTagChars.ReplaceAllString(s, "_")
+	runes := []rune(s)
+	if len(runes) > maxSize {
+		return string(runes[:maxSize])
+	}
+	return s
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/require"
+	av1 "github.com/zalando-incubator/kube-aws-iam-controller/pkg/apis/zalando.org/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSessionTags(tt *testing.T) {
+	for _, tc := range []struct {
+		msg              string
+		config           *SessionConfig
+		namespaceLabels  map[string]string
+		awsIAMRoleLabels map[string]string
+		specTags         []av1.SessionTag
+		expectedTags     []SessionTag
+		expectedIgnored  []string
+	}{
+		{
+			msg:          "no config",
+			config:       nil,
+			expectedTags: nil,
+		},
+		{
+			msg: "fixed, namespace and awsiamrole tags",
+			config: &SessionConfig{
+				Tags: map[string]string{
+					"cluster": "production",
+				},
+				NamespaceLabelTags: map[string]string{
+					"team": "team",
+				},
+				AWSIAMRoleLabelTags: map[string]string{
+					"application": "app",
+				},
+				TransitiveTags: []string{"team"},
+			},
+			namespaceLabels: map[string]string{
+				"team":  "teapot",
+				"other": "ignored",
+			},
+			awsIAMRoleLabels: map[string]string{
+				"application": "my-app",
+			},
+			expectedTags: []SessionTag{
+				{Key: "app", Value: "my-app"},
+				{Key: "cluster", Value: "production"},
+				{Key: "team", Value: "teapot", Transitive: true},
+			},
+		},
+		{
+			msg: "fixed tags take precedence",
+			config: &SessionConfig{
+				Tags: map[string]string{
+					"namespace": "fixed",
+				},
+				NamespaceLabelTags: map[string]string{
+					"name": "namespace",
+				},
+				AWSIAMRoleLabelTags: map[string]string{
+					"namespace": "namespace",
+				},
+			},
+			namespaceLabels: map[string]string{
+				"name": "from-namespace",
+			},
+			awsIAMRoleLabels: map[string]string{
+				"namespace": "from-awsiamrole",
+			},
+			expectedTags: []SessionTag{
+				{Key: "namespace", Value: "fixed"},
+			},
+		},
+		{
+			msg: "namespace tags take precedence over awsiamrole tags",
+			config: &SessionConfig{
+				NamespaceLabelTags: map[string]string{
+					"name": "namespace",
+				},
+				AWSIAMRoleLabelTags: map[string]string{
+					"namespace": "namespace",
+				},
+			},
+			namespaceLabels: map[string]string{
+				"name": "from-namespace",
+			},
+			awsIAMRoleLabels: map[string]string{
+				"namespace": "from-awsiamrole",
+			},
+			expectedTags: []SessionTag{
+				{Key: "namespace", Value: "from-namespace"},
+			},
+		},
+		{
+			msg: "tag keys are case-insensitive across sources",
+			config: &SessionConfig{
+				Tags: map[string]string{
+					"Cluster": "fixed",
+				},
+				NamespaceLabelTags: map[string]string{
+					"cluster": "cluster",
+					"team":    "Team",
+				},
+				AWSIAMRoleLabelTags: map[string]string{
+					"cluster": "CLUSTER",
+					"team":    "team",
+				},
+				TransitiveTags: []string{"TEAM"},
+			},
+			namespaceLabels: map[string]string{
+				"cluster": "from-namespace",
+				"team":    "from-namespace",
+			},
+			awsIAMRoleLabels: map[string]string{
+				"cluster": "from-awsiamrole",
+				"team":    "from-awsiamrole",
+			},
+			expectedTags: []SessionTag{
+				{Key: "Cluster", Value: "fixed"},
+				{Key: "Team", Value: "from-namespace", Transitive: true},
+			},
+		},
+		{
+			msg: "tag keys are case-insensitive within a source",
+			config: &SessionConfig{
+				Tags: map[string]string{
+					"Env": "upper",
+					"env": "lower",
+				},
+				NamespaceLabelTags: map[string]string{
+					"a": "Team",
+					"b": "team",
+				},
+			},
+			namespaceLabels: map[string]string{
+				"a": "from-a",
+				"b": "from-b",
+			},
+			expectedTags: []SessionTag{
+				{Key: "env", Value: "lower"},
+				{Key: "team", Value: "from-b"},
+			},
+		},
+		{
+			msg: "spec tags without config are ignored",
+			specTags: []av1.SessionTag{
+				{Key: "project", Value: "billing", Transitive: true},
+			},
+			expectedTags:    nil,
+			expectedIgnored: []string{"project"},
+		},
+		{
+			msg: "spec tags can't override tags of the controller",
+			config: &SessionConfig{
+				Tags: map[string]string{
+					"namespace": "fixed",
+				},
+				AWSIAMRoleLabelTags: map[string]string{
+					"application": "app",
+				},
+				TransitiveTags:  []string{"cost-center"},
+				AllowedSpecTags: []string{"namespace", "app", "cost-center", "aws:reserved"},
+			},
+			awsIAMRoleLabels: map[string]string{
+				"application": "my-app",
+			},
+			specTags: []av1.SessionTag{
+				{Key: "Namespace", Value: "spoofed"},
+				{Key: "app", Value: "spoofed"},
+				{Key: "cost-center", Value: "1234"},
+				{Key: "aws:reserved", Value: "value"},
+			},
+			expectedTags: []SessionTag{
+				{Key: "app", Value: "my-app"},
+				{Key: "cost-center", Value: "1234", Transitive: true},
+				{Key: "namespace", Value: "fixed"},
+			},
+			expectedIgnored: []string{"Namespace", "app", "aws:reserved"},
+		},
+		{
+			msg: "spec tags can't set tags of the controller with missing labels",
+			config: &SessionConfig{
+				NamespaceLabelTags: map[string]string{
+					"team": "Team",
+				},
+				AWSIAMRoleLabelTags: map[string]string{
+					"application": "app",
+				},
+				AllowedSpecTags: []string{"team", "app"},
+			},
+			specTags: []av1.SessionTag{
+				{Key: "team", Value: "spoofed"},
+				{Key: "APP", Value: "spoofed"},
+			},
+			expectedTags:    []SessionTag{},
+			expectedIgnored: []string{"team", "APP"},
+		},
+		{
+			msg: "only allowed spec tags are applied",
+			config: &SessionConfig{
+				AllowedSpecTags: []string{"Project"},
+			},
+			specTags: []av1.SessionTag{
+				{Key: "project", Value: "billing", Transitive: true},
+				{Key: "cost-center", Value: "1234"},
+			},
+			expectedTags: []SessionTag{
+				{Key: "project", Value: "billing", Transitive: true},
+			},
+			expectedIgnored: []string{"cost-center"},
+		},
+		{
+			msg: "sanitize, truncate and drop reserved tags",
+			config: &SessionConfig{
+				Tags: map[string]string{
+					"aws:reserved":              "value",
+					"invalid*key":               "invalid|value",
+					strings.Repeat("k", 200):    strings.Repeat("v", 300),
+					"":                          "empty",
+					"AWS:ReservedCaseSensitive": "value",
+				},
+			},
+			expectedTags: []SessionTag{
+				{Key: "invalid_key", Value: "invalid_value"},
+				{Key: strings.Repeat("k", sessionTagKeyMaxSize), Value: strings.Repeat("v", sessionTagValueMaxSize)},
+			},
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			// map iteration order must not affect the tags.
+			for i := 0; i < 10; i++ {
+				tags, ignored := tc.config.SessionTags(tc.namespaceLabels, tc.awsIAMRoleLabels, tc.specTags)
+				require.Equal(t, tc.expectedTags, tags)
+				require.Equal(t, tc.expectedIgnored, ignored)
+			}
+		})
+	}
+}
+
+func TestSessionTagsLimit(t *testing.T) {
+	config := &SessionConfig{
+		Tags: make(map[string]string),
+	}
+	for i := 0; i < sessionTagsMaxCount+10; i++ {
+		config.Tags[strings.Repeat("k", i+1)] = "value"
+	}
+
+	tags, _ := config.SessionTags(nil, nil, nil)
+	require.Len(t, tags, sessionTagsMaxCount)
+}
+
+func TestSourceIdentity(t *testing.T) {
+	awsIAMRole := &av1.AWSIAMRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "my-app",
+			Namespace: "default",
+		},
+		Spec: av1.AWSIAMRoleSpec{
+			RoleReference: "my-role",
+		},
+	}
+
+	var config *SessionConfig
+	sourceIdentity, err := config.SourceIdentity(awsIAMRole)
+	require.NoError(t, err)
+	require.Empty(t, sourceIdentity)
+
+	config = &SessionConfig{
+		SourceIdentityTemplate: template.Must(template.New("").Parse("{{.Namespace}}/{{.Name}}:{{.RoleReference}}")),
+	}
+	sourceIdentity, err = config.SourceIdentity(awsIAMRole)
+	require.NoError(t, err)
+	require.Equal(t, "default.my-app_my-role", sourceIdentity)
+
+	config.SourceIdentityTemplate = template.Must(template.New("").Parse("{{.Missing}}"))
+	_, err = config.SourceIdentity(awsIAMRole)
+	require.Error(t, err)
+}
+
+func TestSessionName(t *testing.T) {
+	awsIAMRole := &av1.AWSIAMRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "my-app",
+			Namespace: "default",
+		},
+		Spec: av1.AWSIAMRoleSpec{
+			RoleReference: "arn:aws:iam::012345678910:role/path/my-role",
+		},
+	}
+
+	var config *SessionConfig
+	sessionName, err := config.SessionName(awsIAMRole)
+	require.NoError(t, err)
+	require.Empty(t, sessionName)
+
+	config = &SessionConfig{
+		SessionNameTemplate: template.Must(template.New("").Parse("{{.Cluster}}/{{.Namespace}}/{{.Name}}/{{.RoleName}}")),
+		ClusterID:           "cluster",
+	}
+	sessionName, err = config.SessionName(awsIAMRole)
+	require.NoError(t, err)
+	require.Equal(t, "cluster/default/my-app/my-role", sessionName)
+
+	config.SessionNameTemplate = template.Must(template.New("").Option("missingkey=error").Parse("{{.Missing}}"))
+	_, err = config.SessionName(awsIAMRole)
+	require.Error(t, err)
+}
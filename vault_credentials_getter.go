@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	vaultTokenHeader = "X-Vault-Token"
+)
+
+// errVaultUnsupportedOptions is returned when credentials are requested with
+// options which can't be passed to the Vault AWS secrets engine.
+var errVaultUnsupportedOptions = errors.New("ExternalID, session policies, session names and role chaining are not supported with the Vault credentials backend")
+
+// VaultConfig configures the Vault credentials backend.
+type VaultConfig struct {
+	// Address is the address of the Vault server e.g.
+	// https://vault.example.org:8200.
+	Address string
+	// AuthMount is the mount path of the Kubernetes auth method.
+	AuthMount string
+	// AuthRole is the role used to log in with the Kubernetes auth method.
+	AuthRole string
+	// TokenFile is the path of the service account token used to log in.
+	TokenFile string
+	// AWSMount is the mount path of the AWS secrets engine.
+	AWSMount string
+	// CACert is the path of a PEM encoded CA certificate used to verify the
+	// Vault server certificate. If empty the system roots are used.
+	CACert string
+}
+
+// vaultError is an error response of the Vault API.
+type vaultError struct {
+	statusCode int
+	errors     []string
+}
+
+func (e *vaultError) Error() string {
+	return fmt.Sprintf("vault request failed with status %d: %s", e.statusCode, strings.Join(e.errors, ", "))
+}
+
+// HTTPStatusCode returns the HTTP status code of the response.
+func (e *vaultError) HTTPStatusCode() int {
+	return e.statusCode
+}
+
+// vaultResponse is a response of the Vault API.
+type vaultResponse struct {
+	LeaseDuration int `json:"lease_duration"`
+	Data          struct {
+		AccessKey     string `json:"access_key"`
+		SecretKey     string `json:"secret_key"`
+		SessionToken  string `json:"session_token"`
+		SecurityToken string `json:"security_token"`
+		ARN           string `json:"arn"`
+	} `json:"data"`
+	Auth *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+	Errors []string `json:"errors"`
+}
+
+// VaultCredentialsGetter is a credentials getter which gets credentials from
+// the AWS secrets engine of HashiCorp Vault using roles of the
+// `assumed_role` credential type. The role reference is used as the name of
+// the Vault role. It logs in with the Kubernetes auth method and renews its
+// Vault token before it expires.
+type VaultCredentialsGetter struct {
+	client    *http.Client
+	config    VaultConfig
+	token     string
+	renewable bool
+	renewAt   time.Time
+	mu        sync.Mutex
+	now       func() time.Time
+}
+
+// NewVaultCredentialsGetter initializes a new Vault credentials getter.
+func NewVaultCredentialsGetter(config VaultConfig) (*VaultCredentialsGetter, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.CACert != "" {
+		data, err := os.ReadFile(config.CACert)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", config.CACert)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	config.Address = strings.TrimSuffix(config.Address, "/")
+	config.AuthMount = strings.Trim(config.AuthMount, "/")
+	config.AWSMount = strings.Trim(config.AWSMount, "/")
+
+	return &VaultCredentialsGetter{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   30 * time.Second,
+		},
+		config: config,
+		now:    time.Now,
+	}, nil
+}
+
+// Get gets new credentials for the specified Vault role. Session tags,
+// source identities and session names configured for the controller are
+// rejected at startup, so only the options of the AWSIAMRole are checked.
+func (g *VaultCredentialsGetter) Get(ctx context.Context, role string, sessionDuration time.Duration, opts CredentialsOptions) (*Credentials, error) {
+	if opts.ExternalID != "" || opts.Policy != "" || len(opts.PolicyARNs) > 0 || len(opts.AssumeRoleChain) > 0 || opts.SessionNameSuffix != "" {
+		return nil, errVaultUnsupportedOptions
+	}
+
+	body := map[string]string{
+		"ttl": fmt.Sprintf("%ds", int(sessionDuration.Seconds())),
+	}
+
+	for attempt := 0; ; attempt++ {
+		token, err := g.clientToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var resp vaultResponse
+		now := g.now()
+		err = g.do(ctx, "/v1/"+g.config.AWSMount+"/sts/"+role, token, body, &resp)
+		if err == nil {
+			// the response doesn't always include the ARN of the role,
+			// the requested role is reported instead.
+			return &Credentials{
+				RoleARN:         firstNonEmpty(resp.Data.ARN, role),
+				AccessKeyID:     resp.Data.AccessKey,
+				SecretAccessKey: resp.Data.SecretKey,
+				SessionToken:    firstNonEmpty(resp.Data.SessionToken, resp.Data.SecurityToken),
+				Expiration:      now.Add(time.Duration(resp.LeaseDuration) * time.Second),
+				SessionDuration: time.Duration(resp.LeaseDuration) * time.Second,
+				Endpoint:        g.config.Address,
+			}, nil
+		}
+
+		// the token may have been revoked, log in again once.
+		var vaultErr *vaultError
+		if attempt > 0 || !errors.As(err, &vaultErr) || vaultErr.statusCode != http.StatusForbidden {
+			return nil, fmt.Errorf("failed to get credentials for Vault role '%s': %w", role, err)
+		}
+		g.resetToken(token)
+	}
+}
+
+// clientToken returns a valid Vault token. The current token is renewed
+// after two thirds of its lease and a new token is requested if it can't be
+// renewed.
+func (g *VaultCredentialsGetter) clientToken(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.token != "" && g.now().Before(g.renewAt) {
+		return g.token, nil
+	}
+
+	if g.token != "" && g.renewable {
+		err := g.authenticate(ctx, "/v1/auth/token/renew-self", g.token, map[string]string{})
+		if err == nil {
+			return g.token, nil
+		}
+		log.Debugf("Failed to renew Vault token, logging in again: %v", err)
+	}
+
+	jwt, err := os.ReadFile(g.config.TokenFile)
+	if err != nil {
+		return "", err
+	}
+
+	err = g.authenticate(ctx, "/v1/auth/"+g.config.AuthMount+"/login", "", map[string]string{
+		"role": g.config.AuthRole,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to log in to Vault: %w", err)
+	}
+	return g.token, nil
+}
+
+// authenticate calls a Vault auth endpoint and stores the returned token.
+// Must be called with the lock held.
+func (g *VaultCredentialsGetter) authenticate(ctx context.Context, path, token string, body interface{}) error {
+	now := g.now()
+
+	var resp vaultResponse
+	err := g.do(ctx, path, token, body, &resp)
+	if err != nil {
+		return err
+	}
+
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return errors.New("vault response contains no token")
+	}
+
+	lease := time.Duration(resp.Auth.LeaseDuration) * time.Second
+	g.token = resp.Auth.ClientToken
+	g.renewable = resp.Auth.Renewable
+	g.renewAt = now.Add(lease * 2 / 3)
+	if resp.Auth.LeaseDuration == 0 {
+		// tokens without lease never expire.
+		g.renewAt = now.Add(100 * 365 * 24 * time.Hour)
+	}
+	return nil
+}
+
+// resetToken drops the token if it's still the current token.
+func (g *VaultCredentialsGetter) resetToken(token string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.token == token {
+		g.token = ""
+	}
+}
+
+// do sends a POST request to the Vault API and decodes the response.
+func (g *VaultCredentialsGetter) do(ctx context.Context, path, token string, body interface{}, resp *vaultResponse) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.config.Address+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set(vaultTokenHeader, token)
+	}
+
+	httpResp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	respData, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return err
+	}
+
+	if httpResp.StatusCode >= http.StatusBadRequest {
+		var errResp vaultResponse
+		_ = json.Unmarshal(respData, &errResp)
+		return &vaultError{statusCode: httpResp.StatusCode, errors: errResp.Errors}
+	}
+
+	return json.Unmarshal(respData, resp)
+}
+
+// firstNonEmpty returns the first non-empty string.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
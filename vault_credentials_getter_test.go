@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// vaultStandIn is a local stand-in for the Vault API serving the Kubernetes
+// auth method, token renewal and the AWS secrets engine.
+type vaultStandIn struct {
+	mu       sync.Mutex
+	logins   int
+	renewals int
+	tokens   map[string]bool
+	ttls     []string
+	failSTS  int
+	omitARN  bool
+}
+
+func (v *vaultStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	var body map[string]string
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	token := r.Header.Get(vaultTokenHeader)
+
+	switch r.URL.Path {
+	case "/v1/auth/kubernetes/login":
+		if body["role"] != "controller" || body["jwt"] != "service-account-token" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		v.logins++
+		token := fmt.Sprintf("token-%d", v.logins)
+		v.tokens[token] = true
+		fmt.Fprintf(w, `{"auth":{"client_token":"%s","lease_duration":3600,"renewable":true}}`, token)
+	case "/v1/auth/token/renew-self":
+		if !v.tokens[token] {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		v.renewals++
+		fmt.Fprintf(w, `{"auth":{"client_token":"%s","lease_duration":3600,"renewable":true}}`, token)
+	case "/v1/aws/sts/my-role":
+		if !v.tokens[token] {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		if v.failSTS > 0 {
+			v.failSTS--
+			http.Error(w, `{"errors":["internal error"]}`, http.StatusInternalServerError)
+			return
+		}
+		v.ttls = append(v.ttls, body["ttl"])
+		if v.omitARN {
+			fmt.Fprint(w, `{"lease_duration":900,"data":{"access_key":"AKID","secret_key":"SECRET","security_token":"TOKEN"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"lease_duration":900,"data":{"access_key":"AKID","secret_key":"SECRET","security_token":"TOKEN","arn":"arn:aws:iam::123456789012:role/my-role"}}`)
+	default:
+		http.Error(w, `{"errors":["unsupported path"]}`, http.StatusNotFound)
+	}
+}
+
+func newTestVaultCredentialsGetter(t *testing.T, address string) *VaultCredentialsGetter {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("service-account-token\n"), 0o600))
+
+	getter, err := NewVaultCredentialsGetter(VaultConfig{
+		Address:   address + "/",
+		AuthMount: "kubernetes",
+		AuthRole:  "controller",
+		TokenFile: tokenFile,
+		AWSMount:  "aws",
+	})
+	require.NoError(t, err)
+	return getter
+}
+
+func TestVaultCredentialsGetter(t *testing.T) {
+	vault := &vaultStandIn{tokens: map[string]bool{}}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	now := time.Now()
+	getter := newTestVaultCredentialsGetter(t, server.URL)
+	getter.now = func() time.Time { return now }
+
+	creds, err := getter.Get(context.Background(), "my-role", time.Hour, CredentialsOptions{})
+	require.NoError(t, err)
+	require.Equal(t, &Credentials{
+		RoleARN:         "arn:aws:iam::123456789012:role/my-role",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "SECRET",
+		SessionToken:    "TOKEN",
+		Expiration:      now.Add(15 * time.Minute),
+		SessionDuration: 15 * time.Minute,
+		Endpoint:        server.URL,
+	}, creds)
+	require.Equal(t, []string{"3600s"}, vault.ttls)
+	require.Equal(t, 1, vault.logins)
+
+	// the requested role is reported if the response has no ARN.
+	vault.omitARN = true
+	creds, err = getter.Get(context.Background(), "my-role", time.Hour, CredentialsOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "my-role", creds.RoleARN)
+	vault.omitARN = false
+
+	// the token is reused.
+	require.Equal(t, 1, vault.logins)
+	require.Equal(t, 0, vault.renewals)
+
+	// the token is renewed after two thirds of its lease.
+	now = now.Add(41 * time.Minute)
+	_, err = getter.Get(context.Background(), "my-role", time.Hour, CredentialsOptions{})
+	require.NoError(t, err)
+	require.Equal(t, 1, vault.logins)
+	require.Equal(t, 1, vault.renewals)
+
+	// a revoked token is replaced by logging in again.
+	vault.tokens = map[string]bool{}
+	_, err = getter.Get(context.Background(), "my-role", time.Hour, CredentialsOptions{})
+	require.NoError(t, err)
+	require.Equal(t, 2, vault.logins)
+
+	// a token which can't be renewed is replaced by logging in again.
+	vault.tokens = map[string]bool{}
+	now = now.Add(time.Hour)
+	_, err = getter.Get(context.Background(), "my-role", time.Hour, CredentialsOptions{})
+	require.NoError(t, err)
+	require.Equal(t, 3, vault.logins)
+}
+
+func TestVaultCredentialsGetterErrors(t *testing.T) {
+	vault := &vaultStandIn{tokens: map[string]bool{}}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	getter := newTestVaultCredentialsGetter(t, server.URL)
+
+	for _, opts := range []CredentialsOptions{
+		{Policy: "{}"},
+		{AssumeRoleChain: []string{"intermediate"}},
+		{SessionNameSuffix: "worker"},
+	} {
+		_, err := getter.Get(context.Background(), "my-role", time.Hour, opts)
+		require.ErrorIs(t, err, errVaultUnsupportedOptions)
+	}
+
+	_, err := getter.Get(context.Background(), "missing-role", time.Hour, CredentialsOptions{})
+	_, err = getter.Get(context.Background(), "missing-role", time.Hour, CredentialsOptions{})
+	require.Error(t, err)
+	require.Equal(t, errorClassPermanent, classifyError(err))
+
+	vault.failSTS = 1
+	_, err = getter.Get(context.Background(), "my-role", time.Hour, CredentialsOptions{})
+	require.Error(t, err)
+	require.Equal(t, errorClassTransient, classifyError(err))
+
+	getter.config.AuthRole = "unknown"
+	getter.token = ""
+	_, err = getter.Get(context.Background(), "my-role", time.Hour, CredentialsOptions{})
+	require.ErrorContains(t, err, "failed to log in to Vault")
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultVerificationDelay = 250 * time.Millisecond
+)
+
+// errCredentialsVerificationFailed is returned when issued credentials
+// could not be verified.
+var errCredentialsVerificationFailed = errors.New("failed to verify issued credentials")
+
+// VerifyingCredentialsGetter is a credentials getter which verifies the
+// credentials returned by another credentials getter with
+// sts:GetCallerIdentity before returning them. Newly issued credentials may
+// not be valid immediately, so failed calls are retried with exponential
+// backoff until the timeout. The caller identity must be a session of the
+// role of the credentials.
+type VerifyingCredentialsGetter struct {
+	getter  CredentialsGetter
+	sts     callerIdentityAPI
+	timeout time.Duration
+	delay   time.Duration
+}
+
+// NewVerifyingCredentialsGetter initializes a new verifying credentials
+// getter. The AWS config is only used to configure the STS client, the
+// calls are signed with the credentials being verified.
+func NewVerifyingCredentialsGetter(getter CredentialsGetter, cfg aws.Config, timeout time.Duration, optFns ...func(*sts.Options)) *VerifyingCredentialsGetter {
+	return &VerifyingCredentialsGetter{
+		getter:  getter,
+		sts:     sts.NewFromConfig(cfg, optFns...),
+		timeout: timeout,
+		delay:   defaultVerificationDelay,
+	}
+}
+
+// Get gets credentials from the underlying credentials getter and verifies
+// them.
+func (g *VerifyingCredentialsGetter) Get(ctx context.Context, role string, sessionDuration time.Duration, opts CredentialsOptions) (*Credentials, error) {
+	creds, err := g.getter.Get(ctx, role, sessionDuration, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = g.verify(ctx, creds)
+	if err != nil {
+		return nil, fmt.Errorf("%w for role '%s': %w", errCredentialsVerificationFailed, creds.RoleARN, err)
+	}
+	return creds, nil
+}
+
+// verify calls sts:GetCallerIdentity with the credentials until it succeeds
+// or the timeout is reached and checks the returned identity.
+func (g *VerifyingCredentialsGetter) verify(ctx context.Context, creds *Credentials) error {
+	deadline := time.Now().Add(g.timeout)
+	delay := g.delay
+
+	for {
+		resp, err := g.sts.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{}, withCredentials(creds))
+		if err == nil {
+			return checkAssumedRoleARN(aws.ToString(resp.Arn), creds.RoleARN)
+		}
+
+		if !time.Now().Add(delay).Before(deadline) {
+			return err
+		}
+
+		log.Debugf("Credentials for role '%s' are not valid yet, verifying again in %s: %v", creds.RoleARN, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+	}
+}
+
+// checkAssumedRoleARN checks that the caller identity is a session of the
+// role, i.e. arn:<partition>:sts::<account>:assumed-role/<role-name>/<session>.
+// Roles which are not referenced by ARN are not checked.
+func checkAssumedRoleARN(identity, roleARN string) error {
+	role, err := arn.Parse(roleARN)
+	if err != nil {
+		return nil
+	}
+
+	expected := fmt.Sprintf("arn:%s:sts::%s:assumed-role/%s/", role.Partition, role.AccountID, role.Resource[strings.LastIndex(role.Resource, "/")+1:])
+	if !strings.HasPrefix(identity, expected) {
+		return fmt.Errorf("caller identity '%s' is not a session of role '%s'", identity, roleARN)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/require"
+)
+
+type flakyCallerIdentityAPI struct {
+	calls    int
+	failures int
+	arn      string
+}
+
+func (m *flakyCallerIdentityAPI) GetCallerIdentity(_ context.Context, _ *sts.GetCallerIdentityInput, _ ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	m.calls++
+	if m.calls <= m.failures {
+		return nil, &smithy.GenericAPIError{Code: "InvalidClientTokenId", Message: "The security token included in the request is invalid."}
+	}
+	return &sts.GetCallerIdentityOutput{Arn: aws.String(m.arn)}, nil
+}
+
+func TestVerifyingCredentialsGetter(tt *testing.T) {
+	for _, tc := range []struct {
+		msg      string
+		roleARN  string
+		arn      string
+		failures int
+		timeout  time.Duration
+		calls    int
+		success  bool
+	}{
+		{
+			msg:     "verified",
+			roleARN: "arn:aws:iam::123456789012:role/app",
+			arn:     "arn:aws:sts::123456789012:assumed-role/app/session",
+			timeout: time.Minute,
+			calls:   1,
+			success: true,
+		},
+		{
+			msg:     "verified role with path",
+			roleARN: "arn:aws:iam::123456789012:role/team/app",
+			arn:     "arn:aws:sts::123456789012:assumed-role/app/session",
+			timeout: time.Minute,
+			calls:   1,
+			success: true,
+		},
+		{
+			msg:      "retry until credentials are valid",
+			roleARN:  "arn:aws:iam::123456789012:role/app",
+			arn:      "arn:aws:sts::123456789012:assumed-role/app/session",
+			failures: 2,
+			timeout:  time.Minute,
+			calls:    3,
+			success:  true,
+		},
+		{
+			msg:      "give up after the timeout",
+			roleARN:  "arn:aws:iam::123456789012:role/app",
+			arn:      "arn:aws:sts::123456789012:assumed-role/app/session",
+			failures: 10,
+			timeout:  100 * time.Millisecond,
+			calls:    3,
+		},
+		{
+			msg:     "identity of another role",
+			roleARN: "arn:aws:iam::123456789012:role/app",
+			arn:     "arn:aws:sts::123456789012:assumed-role/application/session",
+			timeout: time.Minute,
+			calls:   1,
+		},
+		{
+			msg:     "identity in another account",
+			roleARN: "arn:aws:iam::123456789012:role/app",
+			arn:     "arn:aws:sts::210987654321:assumed-role/app/session",
+			timeout: time.Minute,
+			calls:   1,
+		},
+		{
+			msg:     "role without ARN is not checked",
+			roleARN: "app",
+			arn:     "arn:aws:sts::123456789012:assumed-role/app/session",
+			timeout: time.Minute,
+			calls:   1,
+			success: true,
+		},
+	} {
+		tt.Run(tc.msg, func(t *testing.T) {
+			svc := &flakyCallerIdentityAPI{failures: tc.failures, arn: tc.arn}
+			getter := &VerifyingCredentialsGetter{
+				getter:  &mockCredsGetter{creds: &Credentials{RoleARN: tc.roleARN}},
+				sts:     svc,
+				timeout: tc.timeout,
+				delay:   20 * time.Millisecond,
+			}
+
+			creds, err := getter.Get(context.Background(), "app", time.Hour, CredentialsOptions{})
+			require.Equal(t, tc.calls, svc.calls)
+			if !tc.success {
+				require.ErrorIs(t, err, errCredentialsVerificationFailed)
+				require.Nil(t, creds)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.roleARN, creds.RoleARN)
+		})
+	}
+}
+
+func TestVerifyingCredentialsGetterError(t *testing.T) {
+	svc := &flakyCallerIdentityAPI{}
+	getter := &VerifyingCredentialsGetter{
+		getter:  &mockCredsGetter{err: errors.New("AccessDenied")},
+		sts:     svc,
+		timeout: time.Minute,
+		delay:   time.Millisecond,
+	}
+
+	_, err := getter.Get(context.Background(), "app", time.Hour, CredentialsOptions{})
+	require.ErrorContains(t, err, "AccessDenied")
+	require.NotErrorIs(t, err, errCredentialsVerificationFailed)
+	require.Equal(t, 0, svc.calls)
+}